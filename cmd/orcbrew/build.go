@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/yamlsource"
+)
+
+var buildCommand = &command{
+	name:    "build",
+	usage:   "[OPTIONS] sourceDir",
+	summary: "Build an .orcbrew file from a directory of YAML files",
+	run:     runBuild,
+}
+
+var unbuildCommand = &command{
+	name:    "unbuild",
+	usage:   "[OPTIONS] inputFile",
+	summary: "Write an .orcbrew file as a directory of YAML files",
+	run:     runUnbuild,
+}
+
+func runBuild(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	output := flags.String("o", "", "The file to write the result to (default stdout)")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	all, err := yamlsource.Build(filenames[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	writeFile(&orcbrew.File{Packs: all, ExportAll: len(all) > 1}, *output)
+}
+
+func runUnbuild(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	dir := flags.String("dir", "src", "The directory to write the YAML files to")
+	layout := flags.String("layout", "entity", "Write one file per entity, or per kind: entity or kind")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var l yamlsource.Layout
+	switch *layout {
+	case "entity":
+		l = yamlsource.PerEntity
+	case "kind":
+		l = yamlsource.PerKind
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown layout %s\n", *layout)
+		os.Exit(2)
+	}
+
+	f := readFileToWrite(filenames[0])
+	if err := yamlsource.Unbuild(f.Packs, *dir, l); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *dir, err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var diffCommand = &command{
+	name:    "diff",
+	usage:   "[OPTIONS] oldFile newFile",
+	summary: "Show the entities that differ between two .orcbrew files",
+	run:     runDiff,
+}
+
+var diffFormats = map[string]func(w io.Writer, diffs []schema.EntityDiff) error{
+	"text":     writeDiffText,
+	"json":     writeDiffJSON,
+	"markdown": writeDiffMarkdown,
+}
+
+var diffHeadings = map[string]string{
+	"added":   "Added",
+	"removed": "Removed",
+	"changed": "Changed",
+}
+
+// runDiff exits with status 1 when the files differ, like diff(1)
+func runDiff(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	format := flags.String("format", "text", "The output format: text, json or markdown")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	write, ok := diffFormats[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown format %s\n", *format)
+		os.Exit(2)
+	}
+
+	oldFile, newFile := readFile(filenames[0]), readFile(filenames[1])
+	diffs, err := schema.Diff(oldFile.Packs, newFile.Packs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(2)
+	}
+
+	err = write(os.Stdout, diffs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(2)
+	}
+
+	if len(diffs) > 0 {
+		os.Exit(1)
+	}
+}
+
+func writeDiffText(w io.Writer, diffs []schema.EntityDiff) error {
+	var buf bytes.Buffer
+	for idx, diff := range diffs {
+		if idx == 0 || diffs[idx-1].Pack != diff.Pack {
+			fmt.Fprintf(&buf, "%s:\n", diff.Pack)
+		}
+
+		entity := diff.Kind + "/" + diff.Key
+		switch diff.Status {
+		case "added":
+			fmt.Fprintf(&buf, "  + %s\n", entity)
+		case "removed":
+			fmt.Fprintf(&buf, "  - %s\n", entity)
+		default:
+			for _, change := range diff.Changes {
+				switch change.Op {
+				case "added":
+					fmt.Fprintf(&buf, "  %s/%s: +%s\n", entity, change.Field, schema.FormatValue(change.New))
+				case "removed":
+					fmt.Fprintf(&buf, "  %s/%s: -%s\n", entity, change.Field, schema.FormatValue(change.Old))
+				default:
+					fmt.Fprintf(&buf, "  %s: %s %s → %s\n", entity, change.Field, schema.FormatValue(change.Old), schema.FormatValue(change.New))
+				}
+			}
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeDiffJSON(w io.Writer, diffs []schema.EntityDiff) error {
+	if diffs == nil {
+		diffs = []schema.EntityDiff{}
+	}
+
+	jsonBytes, err := json.MarshalIndent(diffs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(jsonBytes, '\n'))
+	return err
+}
+
+// writeDiffMarkdown writes a changelog with a section per option pack
+func writeDiffMarkdown(w io.Writer, diffs []schema.EntityDiff) error {
+	var buf bytes.Buffer
+
+	for start := 0; start < len(diffs); {
+		end := start
+		for end < len(diffs) && diffs[end].Pack == diffs[start].Pack {
+			end++
+		}
+		packDiffs := diffs[start:end]
+		start = end
+
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "## %s\n", packDiffs[0].Pack)
+
+		for _, status := range []string{"added", "removed", "changed"} {
+			var section []schema.EntityDiff
+			for _, diff := range packDiffs {
+				if diff.Status == status {
+					section = append(section, diff)
+				}
+			}
+			if len(section) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(&buf, "\n### %s\n\n", diffHeadings[status])
+			for _, diff := range section {
+				name := diff.Name
+				if name == "" {
+					name = diff.Key
+				}
+				fmt.Fprintf(&buf, "- **%s** (`%s/%s`)\n", name, diff.Kind, diff.Key)
+				for _, change := range diff.Changes {
+					switch change.Op {
+					case "added":
+						fmt.Fprintf(&buf, "  - `%s`: added %s\n", change.Field, schema.FormatValue(change.New))
+					case "removed":
+						fmt.Fprintf(&buf, "  - `%s`: removed %s\n", change.Field, schema.FormatValue(change.Old))
+					default:
+						fmt.Fprintf(&buf, "  - `%s`: %s → %s\n", change.Field, schema.FormatValue(change.Old), schema.FormatValue(change.New))
+					}
+				}
+			}
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/fc5"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/foundry"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/render"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/sqlite"
+)
+
+var exportCommand = &command{
+	name:    "export",
+	usage:   "-format FORMAT [OPTIONS] inputFile",
+	summary: "Export a file for use in another tool",
+	run:     runExport,
+}
+
+// exportFormat is a format a file can be exported to, which is written either
+// to a single file, to a file that must be named, such as a database, or to
+// a directory
+type exportFormat struct {
+	name      string
+	summary   string
+	write     func(all schema.OrcbrewExportAll, w io.Writer) error
+	writeFile func(all schema.OrcbrewExportAll, filename string) error
+	writeDir  func(all schema.OrcbrewExportAll, dir string) error
+}
+
+var exportFormats = []*exportFormat{
+	{
+		name:    "fc5",
+		summary: "a compendium XML file for the Fight Club 5 and Game Master 5 apps",
+		write: func(all schema.OrcbrewExportAll, w io.Writer) error {
+			return fc5.Export(combinePacks(all)).Write(w)
+		},
+	},
+	{
+		name:     "foundry",
+		summary:  "compendium packs for the dnd5e system of Foundry VTT (-dir)",
+		writeDir: foundry.WritePacks,
+	},
+	{
+		name:    "obsidian",
+		summary: "an Obsidian vault with a note for each entity (-dir)",
+		writeDir: func(all schema.OrcbrewExportAll, dir string) error {
+			return render.NewBook(packsTitle(all), combinePacks(all)).WriteVault(dir)
+		},
+	},
+	{
+		name:      "sqlite",
+		summary:   "a SQLite database with a table for each kind (-o)",
+		writeFile: sqlite.WriteFile,
+	},
+}
+
+// packsTitle returns a title for a document made from the option packs,
+// e.g. "Test & More Tests"
+func packsTitle(all schema.OrcbrewExportAll) string {
+	var packs []string
+	for pack := range all {
+		packs = append(packs, pack)
+	}
+	sort.Strings(packs)
+	return strings.Join(packs, " & ")
+}
+
+func runExport(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	var names []string
+	for _, format := range exportFormats {
+		names = append(names, format.name)
+	}
+	formatName := flags.String("format", "", "The format to export to: "+strings.Join(names, ", "))
+	output := flags.String("o", "", "The file to write the export to, for formats written to a file (default stdout, except for databases)")
+	dir := flags.String("dir", "", "The directory to write the export to, for formats written to a directory")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) != 1 || *formatName == "" {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "\nFormats:\n")
+		for _, format := range exportFormats {
+			fmt.Fprintf(os.Stderr, "  %-12s %s\n", format.name, format.summary)
+		}
+		os.Exit(2)
+	}
+
+	var format *exportFormat
+	for _, f := range exportFormats {
+		if f.name == *formatName {
+			format = f
+		}
+	}
+	if format == nil {
+		if _, ok := renderTemplates[*formatName]; ok {
+			// Books are only rendered by render, so that there's one set of
+			// flags for them
+			fmt.Fprintf(os.Stderr, "Use orcbrew render -format %s\n", *formatName)
+		} else {
+			fmt.Fprintf(os.Stderr, "Unknown format %s\n", *formatName)
+		}
+		os.Exit(2)
+	}
+
+	f := readFile(filenames[0])
+
+	if format.writeDir != nil {
+		if *dir == "" {
+			fmt.Fprintf(os.Stderr, "The %s format is written to a directory, use -dir\n", format.name)
+			os.Exit(2)
+		}
+		if err := format.writeDir(f.Packs, *dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *dir, err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	if format.writeFile != nil {
+		if *output == "" {
+			fmt.Fprintf(os.Stderr, "The %s format is written to a file, use -o\n", format.name)
+			os.Exit(2)
+		}
+		if err := format.writeFile(f.Packs, *output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *output, err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		out, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *output, err)
+			os.Exit(2)
+		}
+		defer out.Close()
+		w = out
+	}
+	if err := format.write(f.Packs, w); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing export: %s\n", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var extractCommand = &command{
+	name:    "extract",
+	usage:   "[OPTIONS] inputFile",
+	summary: "Extract the entities matching a filter into a new .orcbrew file",
+	run:     runExtract,
+}
+
+// stringList is a flag that may be given several times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func runExtract(cmd *command, args []string) {
+	var keys, packs, where stringList
+
+	flags := newFlagSet(cmd)
+	kinds := flags.String("kind", "", "A comma-separated list of the kinds of entity to extract, e.g. spells,monsters")
+	flags.Var(&keys, "key", "A glob pattern matching the keys to extract (may be repeated)")
+	flags.Var(&packs, "pack", "The name of an option pack to extract from (may be repeated)")
+	flags.Var(&where, "where", "A condition on entity fields, e.g. level<=3 (may be repeated)")
+	withReferences := flags.Bool("with-references", false, "Also extract the entities referenced by the extracted entities")
+	output := flags.String("o", "", "The file to write the extracted entities to (default stdout)")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	filter := schema.Filter{
+		Keys:              keys,
+		Packs:             packs,
+		IncludeReferences: *withReferences,
+	}
+
+	if *kinds != "" {
+		for _, kind := range strings.Split(*kinds, ",") {
+			if !schema.IsKind(kind) {
+				fmt.Fprintf(os.Stderr, "Unknown kind %s, expected one of %s\n", kind, strings.Join(schema.Kinds, ", "))
+				os.Exit(2)
+			}
+			filter.Kinds = append(filter.Kinds, kind)
+		}
+	}
+
+	for _, condition := range where {
+		predicate, err := schema.ParsePredicate(condition)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(2)
+		}
+		filter.Predicates = append(filter.Predicates, predicate)
+	}
+
+	f := readFileToWrite(filenames[0])
+
+	extracted, err := filter.ExportAll(f.Packs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(2)
+	}
+
+	writeFile(&orcbrew.File{Packs: extracted, ExportAll: f.ExportAll}, *output)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var graphCommand = &command{
+	name:    "graph",
+	usage:   "[OPTIONS] inputFile",
+	summary: "Export the references between entities as a DOT or JSON graph",
+	run:     runGraph,
+}
+
+func runGraph(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	format := flags.String("format", "dot", "The output format: dot or json")
+	output := flags.String("o", "", "The file to write the graph to (default stdout)")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *format != "dot" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown format %s\n", *format)
+		os.Exit(2)
+	}
+
+	g := schema.NewGraph(readFile(filenames[0]).Packs)
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		out, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *output, err)
+			os.Exit(2)
+		}
+		defer out.Close()
+		w = out
+	}
+
+	var err error
+	if *format == "dot" {
+		err = g.WriteDOT(w)
+	} else {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(g)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing graph: %s\n", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/fivetools"
+)
+
+var import5etoolsCommand = &command{
+	name:    "import-5etools",
+	usage:   "[OPTIONS] inputFile.json",
+	summary: "Convert homebrew in 5etools JSON to an .orcbrew file",
+	run:     runImport5etools,
+}
+
+func runImport5etools(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	pack := flags.String("pack", "", "The name of the option pack (default the source named in the file)")
+	output := flags.String("o", "", "The file to write the result to (default stdout)")
+	report := flags.String("report", "", "The file to write the report of fields that weren't imported to (default stderr)")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	in, err := os.Open(filenames[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(2)
+	}
+	defer in.Close()
+
+	all, notes, err := fivetools.Import(in, *pack)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %s\n", filenames[0], err)
+		os.Exit(2)
+	}
+
+	var w io.Writer = os.Stderr
+	if *report != "" {
+		f, err := os.Create(*report)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *report, err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		w = f
+	}
+	for _, note := range notes {
+		fmt.Fprintf(w, "%s\n", note)
+	}
+
+	writeFile(&orcbrew.File{Packs: all}, *output)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/jsonschema"
+)
+
+var jsonSchemaCommand = &command{
+	name:    "jsonschema",
+	usage:   "[OPTIONS]",
+	summary: "Print the JSON Schema for orcbrew2json output",
+	run:     runJSONSchema,
+}
+
+func runJSONSchema(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	root := flags.String("root", "source", "The document to describe, either source or export-all")
+	output := flags.String("o", "", "The file to write the schema to (default stdout)")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var s *jsonschema.Schema
+	switch *root {
+	case "source":
+		s = jsonschema.ForSource()
+	case "export-all":
+		s = jsonschema.ForExportAll()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown root %s, expected source or export-all\n", *root)
+		os.Exit(2)
+	}
+
+	schemaJSON, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding schema: %s\n", err)
+		os.Exit(2)
+	}
+	schemaJSON = append(schemaJSON, '\n')
+
+	if *output == "" {
+		os.Stdout.Write(schemaJSON)
+		return
+	}
+
+	err = ioutil.WriteFile(*output, schemaJSON, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *output, err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// command is a single subcommand of the orcbrew tool
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(cmd *command, args []string)
+}
+
+var commands = []*command{
+	buildCommand,
+	diffCommand,
+	exportCommand,
+	extractCommand,
+	graphCommand,
+	import5etoolsCommand,
+	jsonSchemaCommand,
+	mergeCommand,
+	merge3Command,
+	renderCommand,
+	renameKeyCommand,
+	renamePackCommand,
+	siteCommand,
+	splitCommand,
+	tableCommand,
+	unbuildCommand,
+}
+
+func main() {
+	flag.Usage = printUsage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			cmd.run(cmd, args[1:])
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command %s\n\n", args[0])
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s COMMAND [OPTIONS] [ARGS]\n\nCommands:\n", os.Args[0])
+	width := 0
+	for _, cmd := range commands {
+		if len(cmd.name) > width {
+			width = len(cmd.name)
+		}
+	}
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-*s %s\n", width, cmd.name, cmd.summary)
+	}
+}
+
+// newFlagSet returns a flag set for the given command which prints the
+// command usage on error
+func newFlagSet(cmd *command) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s %s\n", os.Args[0], cmd.name, cmd.usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseArgs parses flags which may be interspersed with the positional
+// arguments, returning the positional arguments
+func parseArgs(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		flags.Parse(args)
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var mergeCommand = &command{
+	name:    "merge",
+	usage:   "[OPTIONS] inputFile...",
+	summary: "Merge several .orcbrew files into one Export All",
+	run:     runMerge,
+}
+
+var conflictPolicies = map[string]schema.ConflictPolicy{
+	"error":  schema.ConflictError,
+	"first":  schema.ConflictFirstWins,
+	"last":   schema.ConflictLastWins,
+	"rename": schema.ConflictRename,
+}
+
+func runMerge(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	output := flags.String("o", "", "The file to write the merged Export All to (default stdout)")
+	policy := flags.String("policy", "error", "How to resolve conflicting entities: error, first, last or rename")
+	suffix := flags.String("suffix", "-", "The separator used when renaming conflicting keys, e.g. myspell-2")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	options := schema.MergeOptions{RenameSuffix: *suffix}
+	var ok bool
+	if options.Policy, ok = conflictPolicies[*policy]; !ok {
+		fmt.Fprintf(os.Stderr, "Unknown conflict policy %s\n", *policy)
+		os.Exit(2)
+	}
+
+	var inputs []schema.OrcbrewExportAll
+	for _, filename := range filenames {
+		inputs = append(inputs, readFileToWrite(filename).Packs)
+	}
+
+	merged, conflicts, err := schema.Merge(options, inputs...)
+	for _, conflict := range conflicts {
+		fmt.Fprintf(os.Stderr, "%s (redefined in %s)\n", conflict, filenames[conflict.Input])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Merge failed: %s\n", err)
+		os.Exit(1)
+	}
+
+	writeFile(&orcbrew.File{Packs: merged, ExportAll: true}, *output)
+}
+
+// writeFile writes an .orcbrew file to filename, or stdout if it is empty
+func writeFile(f *orcbrew.File, filename string) {
+	var err error
+	if filename == "" {
+		err = f.Write(os.Stdout)
+	} else {
+		err = f.WriteFile(filename)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", filename, err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var merge3Command = &command{
+	name:    "merge3",
+	usage:   "[OPTIONS] baseFile oursFile theirsFile",
+	summary: "Three-way merge of .orcbrew files, usable as a git merge driver",
+	run:     runMerge3,
+}
+
+// runMerge3 exits with status 1 when there are conflicts, which are written
+// between conflict markers, as git expects of a merge driver
+func runMerge3(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	output := flags.String("o", "", "The file to write the merged result to (default stdout)")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) != 3 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	base, ours, theirs := readFileToWrite(filenames[0]), readFileToWrite(filenames[1]), readFileToWrite(filenames[2])
+
+	// Single sources without an option pack are keyed by their file name,
+	// which differs between the temporary files git merges
+	if !base.ExportAll && !ours.ExportAll && !theirs.ExportAll {
+		for pack := range ours.Packs {
+			rekeySource(base, pack)
+			rekeySource(theirs, pack)
+		}
+	}
+
+	merged, conflicts, err := schema.Merge3(base.Packs, ours.Packs, theirs.Packs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Merge failed: %s\n", err)
+		os.Exit(2)
+	}
+
+	for _, conflict := range conflicts {
+		fmt.Fprintf(os.Stderr, "CONFLICT %s\n", conflict)
+	}
+
+	f := &orcbrew.File{Packs: merged, ExportAll: ours.ExportAll}
+	if *output == "" {
+		err = f.WriteConflicts(os.Stdout, conflicts)
+	} else {
+		out, createErr := os.Create(*output)
+		if createErr != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *output, createErr)
+			os.Exit(2)
+		}
+		err = f.WriteConflicts(out, conflicts)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *output, err)
+		os.Exit(2)
+	}
+
+	if len(conflicts) > 0 {
+		os.Exit(1)
+	}
+}
+
+// rekeySource keys the only source in a file by pack
+func rekeySource(f *orcbrew.File, pack string) {
+	source, err := f.Source()
+	if err == nil {
+		f.Packs = schema.OrcbrewExportAll{pack: source}
+	}
+}
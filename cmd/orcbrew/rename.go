@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var renameKeyCommand = &command{
+	name:    "rename-key",
+	usage:   "[OPTIONS] inputFile kind oldKey newKey",
+	summary: "Change the key of an entity and every reference to it",
+	run:     runRenameKey,
+}
+
+var renamePackCommand = &command{
+	name:    "rename-pack",
+	usage:   "[OPTIONS] inputFile oldName newName",
+	summary: "Rename an option pack",
+	run:     runRenamePack,
+}
+
+func runRenameKey(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	output := flags.String("o", "", "The file to write the result to (default stdout)")
+	args = parseArgs(flags, args)
+
+	if len(args) != 4 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	f := readFileToWrite(args[0])
+	rewrites, err := schema.RenameKey(f.Packs, args[1], args[2], args[3])
+	finishRename(f, rewrites, err, *output)
+}
+
+func runRenamePack(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	output := flags.String("o", "", "The file to write the result to (default stdout)")
+	args = parseArgs(flags, args)
+
+	if len(args) != 3 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	f := readFileToWrite(args[0])
+	rewrites, err := schema.RenamePack(f.Packs, args[1], args[2])
+	finishRename(f, rewrites, err, *output)
+}
+
+// finishRename reports each rewritten location on stderr and writes the file
+func finishRename(f *orcbrew.File, rewrites []schema.Rewrite, err error, output string) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	for _, rewrite := range rewrites {
+		fmt.Fprintf(os.Stderr, "%s\n", rewrite)
+	}
+
+	writeFile(f, output)
+}
+
+// readFile reads an .orcbrew file, exiting on error
+func readFile(filename string) *orcbrew.File {
+	f, err := orcbrew.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(2)
+	}
+	return f
+}
+
+// readFileToWrite reads an .orcbrew file like readFile, for a command that
+// writes its entities out again, warning about each key that will be lost
+// because the schema doesn't have it
+func readFileToWrite(filename string) *orcbrew.File {
+	f := readFile(filename)
+	for _, path := range f.Unknown {
+		fmt.Fprintf(os.Stderr, "%s: dropping %s, which isn't in the schema\n", filename, path)
+	}
+	return f
+}
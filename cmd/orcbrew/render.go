@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/render"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var renderCommand = &command{
+	name:    "render",
+	usage:   "[OPTIONS] inputFile",
+	summary: "Render a file as a Markdown book or printable HTML",
+	run:     runRender,
+}
+
+// renderTemplates are the default templates for each output format
+var renderTemplates = map[string]string{
+	"markdown":    render.MarkdownTemplate,
+	"html":        render.HTMLTemplate,
+	"homebrewery": render.HomebreweryTemplate,
+}
+
+func runRender(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	format := flags.String("format", "markdown", "The output format: markdown, homebrewery or html")
+	templateFile := flags.String("template", "", "A template to use instead of the default for the format")
+	printTemplate := flags.Bool("print-template", false, "Print the default template for the format and exit")
+	title := flags.String("title", "", "The title of the book (default the option pack or file name)")
+	output := flags.String("o", "", "The file to write the book to (default stdout)")
+	filenames := parseArgs(flags, args)
+
+	text, ok := renderTemplates[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown format %s\n", *format)
+		os.Exit(2)
+	}
+
+	if *printTemplate {
+		fmt.Print(text)
+		return
+	}
+
+	if len(filenames) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *templateFile != "" {
+		data, err := ioutil.ReadFile(*templateFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading template: %s\n", err)
+			os.Exit(2)
+		}
+		text = string(data)
+	}
+
+	book := readBook(filenames[0], *title)
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		out, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *output, err)
+			os.Exit(2)
+		}
+		defer out.Close()
+		w = out
+	}
+
+	var err error
+	if *format == "html" {
+		err = book.ExecuteHTML(w, text)
+	} else {
+		err = book.Execute(w, text)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering %s: %s\n", filenames[0], err)
+		os.Exit(2)
+	}
+}
+
+// readBook reads an .orcbrew file as a book, combining the option packs of an
+// Export All. Without a title the book is named after its only option pack,
+// or the file when it has several.
+func readBook(filename string, title string) *render.Book {
+	f := readFile(filename)
+
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		if len(f.Packs) == 1 {
+			for pack := range f.Packs {
+				title = pack
+			}
+		}
+	}
+
+	return render.NewBook(title, combinePacks(f.Packs))
+}
+
+// combinePacks combines the option packs of a file into one source, keeping
+// the entity from the pack that sorts first when a key is defined twice
+func combinePacks(all schema.OrcbrewExportAll) schema.OrcbrewSource {
+	var packs []string
+	for pack := range all {
+		packs = append(packs, pack)
+	}
+	sort.Strings(packs)
+
+	var sources []schema.OrcbrewSource
+	for _, pack := range packs {
+		sources = append(sources, all[pack])
+	}
+
+	source, _, err := schema.MergeSources(schema.MergeOptions{Policy: schema.ConflictFirstWins}, sources...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error combining option packs: %s\n", err)
+		os.Exit(2)
+	}
+	return source
+}
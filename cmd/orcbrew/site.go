@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+var siteCommand = &command{
+	name:    "site",
+	usage:   "[OPTIONS] inputFile",
+	summary: "Generate a static website for browsing a file",
+	run:     runSite,
+}
+
+func runSite(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	dir := flags.String("out", "site", "The directory to write the site to")
+	title := flags.String("title", "", "The title of the site (default the option pack or file name)")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	book := readBook(filenames[0], *title)
+	if err := book.WriteSite(*dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing site: %s\n", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var splitCommand = &command{
+	name:    "split",
+	usage:   "[OPTIONS] inputFile",
+	summary: "Split an Export All into one .orcbrew file per option pack",
+	run:     runSplit,
+}
+
+func runSplit(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	dir := flags.String("dir", ".", "The directory to write the option packs to")
+	writeJSON := flags.Bool("json", false, "Also write a .json file for each option pack")
+	filenames := parseArgs(flags, args)
+
+	if len(filenames) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	f := readFileToWrite(filenames[0])
+
+	err := os.MkdirAll(*dir, 0755)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %s\n", *dir, err)
+		os.Exit(2)
+	}
+
+	for _, pack := range f.Split() {
+		filename := filepath.Join(*dir, pack.Filename+".orcbrew")
+		packFile := &orcbrew.File{Packs: schema.OrcbrewExportAll{pack.Name: pack.Source}}
+		writeFile(packFile, filename)
+		fmt.Fprintf(os.Stdout, "Saved %s to %s\n", pack.Name, filename)
+
+		if *writeJSON {
+			filename = filepath.Join(*dir, pack.Filename+".json")
+			writeSourceJSON(pack.Source, filename)
+			fmt.Fprintf(os.Stdout, "Saved %s to %s\n", pack.Name, filename)
+		}
+	}
+}
+
+// writeSourceJSON writes a source as pretty-printed JSON to filename
+func writeSourceJSON(source schema.OrcbrewSource, filename string) {
+	jsonBytes, err := json.MarshalIndent(source, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(filename, append(jsonBytes, '\n'), 0644)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", filename, err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var tableCommand = &command{
+	name:    "table",
+	usage:   "[OPTIONS] -kind KIND inputFile",
+	summary: "Export entities as a CSV or TSV spreadsheet, or import an edited one",
+	run:     runTable,
+}
+
+func runTable(cmd *command, args []string) {
+	flags := newFlagSet(cmd)
+	kind := flags.String("kind", "", "The kind of entity, e.g. spells")
+	columnList := flags.String("columns", "", "Comma separated columns to export (default depends on the kind)")
+	listColumns := flags.Bool("list-columns", false, "List the columns available for the kind and exit")
+	format := flags.String("format", "", "The table format: csv or tsv (default from the file extension, or csv)")
+	importFile := flags.String("import", "", "A table to update the input file from, instead of exporting")
+	output := flags.String("o", "", "The file to write the table, or the updated file when importing, to (default stdout)")
+	filenames := parseArgs(flags, args)
+
+	if *kind == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *listColumns {
+		columns, err := schema.TableColumnNames(*kind)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(2)
+		}
+		fmt.Println(strings.Join(columns, "\n"))
+		return
+	}
+
+	if len(filenames) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *importFile != "" {
+		f := readFileToWrite(filenames[0])
+
+		in, err := os.Open(*importFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(2)
+		}
+		defer in.Close()
+
+		r := csv.NewReader(in)
+		r.Comma = tableSeparator(*format, *importFile)
+		rewrites, err := schema.ReadTable(r, f.Packs, *kind)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing %s: %s\n", *importFile, err)
+			os.Exit(1)
+		}
+
+		for _, rewrite := range rewrites {
+			fmt.Fprintf(os.Stderr, "%s\n", rewrite)
+		}
+		writeFile(f, *output)
+		return
+	}
+
+	columns := schema.TableColumns[*kind]
+	if columns == nil {
+		columns = []string{"key", "name"}
+	}
+	if *columnList != "" {
+		columns = strings.Split(*columnList, ",")
+		for idx := range columns {
+			columns[idx] = strings.TrimSpace(columns[idx])
+		}
+	}
+
+	f := readFile(filenames[0])
+
+	out := os.Stdout
+	if *output != "" {
+		var err error
+		out, err = os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", *output, err)
+			os.Exit(2)
+		}
+		defer out.Close()
+	}
+
+	w := csv.NewWriter(out)
+	w.Comma = tableSeparator(*format, *output)
+	if err := schema.WriteTable(w, f.Packs, *kind, columns); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing table: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+// tableSeparator returns the field separator for a table format, using the
+// extension of the file when no format is given
+func tableSeparator(format string, filename string) rune {
+	if format == "" {
+		if strings.EqualFold(filepath.Ext(filename), ".tsv") {
+			return '\t'
+		}
+		return ','
+	}
+
+	switch format {
+	case "csv":
+		return ','
+	case "tsv":
+		return '\t'
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown format %s\n", format)
+	os.Exit(2)
+	return 0
+}
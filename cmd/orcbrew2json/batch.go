@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/jsonschema"
+)
+
+// sumsFile is the file -skip keeps a record of the files converted in, in
+// -outdir or the current directory. Each line is the SHA-256 of a file, or -
+// with -skip mtime, the options it was converted with and its name.
+const sumsFile = ".orcbrew2json.sha256"
+
+// A job is a file to convert and where to save its output
+type job struct {
+	input  string
+	output string
+}
+
+// A result is what came of converting a file
+type result struct {
+	job
+	skipped bool
+	invalid []*jsonschema.ValidationError
+	err     error
+}
+
+// findInputs returns the files named by the arguments, each of which is a
+// file, a directory, which is searched for files recursively, or a glob
+// pattern. The output of a file found in a directory or by a pattern keeps
+// its path from there under -outdir.
+func findInputs(args []string) ([]job, error) {
+	inputExt, outputExt := ".orcbrew", ".json"
+	if *decode {
+		inputExt, outputExt = ".json", ".orcbrew"
+	} else if *format == "ndjson" {
+		outputExt = ".ndjson"
+	}
+
+	var todo []job
+	inputs := make(map[string]bool)
+	outputs := make(map[string]string)
+	add := func(input string, root string) error {
+		input = filepath.Clean(input)
+		if inputs[input] {
+			return nil
+		}
+		inputs[input] = true
+
+		output, err := outputPath(input, root, outputExt)
+		if err != nil {
+			return err
+		}
+		if other, ok := outputs[output]; ok {
+			return fmt.Errorf("%s and %s would both be saved to %s", other, input, output)
+		}
+		outputs[output] = input
+
+		todo = append(todo, job{input, output})
+		return nil
+	}
+	addDir := func(dir string, root string) error {
+		return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !strings.EqualFold(filepath.Ext(path), inputExt) {
+				return err
+			}
+			return add(path, root)
+		})
+	}
+
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			info, err := os.Stat(arg)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				err = addDir(arg, arg)
+			} else {
+				err = add(arg, filepath.Dir(arg))
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("Bad pattern %s: %s", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("No files match %s", arg)
+		}
+		root := globRoot(arg)
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				err = addDir(match, root)
+			} else {
+				err = add(match, root)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(todo) == 0 {
+		return nil, fmt.Errorf("No %s files found in %s", inputExt, strings.Join(args, ", "))
+	}
+	return todo, nil
+}
+
+// outputPath returns where the output of a file found under root is saved,
+// which is next to it unless -outdir is given
+func outputPath(input string, root string, ext string) (string, error) {
+	if *outDir == "" {
+		return strings.TrimSuffix(input, filepath.Ext(input)) + ext, nil
+	}
+	rel, err := filepath.Rel(root, input)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(*outDir, strings.TrimSuffix(rel, filepath.Ext(rel))+ext), nil
+}
+
+// globRoot returns the directory a glob pattern matches files under, which
+// is the part of it before the first wildcard
+func globRoot(pattern string) string {
+	root := filepath.Dir(pattern)
+	for strings.ContainsAny(root, "*?[") {
+		root = filepath.Dir(root)
+	}
+	return root
+}
+
+// runBatch converts the files with -jobs workers, printing each result as
+// it comes and a summary at the end, and returns the exit status: 2 if a
+// file couldn't be converted, 1 if one wasn't valid, otherwise 0
+func runBatch(todo []job) int {
+	sums, err := readSums()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %s\n", sumsFile, err)
+		return 2
+	}
+
+	queue := make(chan job)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < *parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				results <- sums.convert(j)
+			}
+		}()
+	}
+	go func() {
+		for _, j := range todo {
+			queue <- j
+		}
+		close(queue)
+		wg.Wait()
+		close(results)
+	}()
+
+	status := 0
+	done, skipped := 0, 0
+	var failed []string
+	for r := range results {
+		switch {
+		case r.err != nil:
+			fmt.Fprintf(os.Stderr, "%s\n", r.err)
+			failed = append(failed, r.input)
+			status = 2
+		case len(r.invalid) > 0:
+			for _, err := range r.invalid {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", r.input, err)
+			}
+			failed = append(failed, r.input)
+			if status == 0 {
+				status = 1
+			}
+		case r.skipped:
+			skipped++
+			fmt.Fprintf(os.Stdout, "Skipped %s, unchanged since it was converted\n", r.input)
+		case *validate:
+			done++
+			fmt.Fprintf(os.Stdout, "%s is valid\n", r.input)
+		default:
+			done++
+			if *noSave == false {
+				fmt.Fprintf(os.Stdout, "Saved to %s\n", r.output)
+			}
+		}
+	}
+
+	if err := sums.write(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", sumsFile, err)
+		status = 2
+	}
+
+	if len(todo) > 1 {
+		verb := "converted"
+		if *validate {
+			verb = "valid"
+		}
+		fmt.Fprintf(os.Stdout, "%d %s, %d skipped, %d failed\n", done, verb, skipped, len(failed))
+		sort.Strings(failed)
+		for _, input := range failed {
+			fmt.Fprintf(os.Stderr, "Failed: %s\n", input)
+		}
+	}
+	return status
+}
+
+// A sum is the record of a file that has been converted
+type sum struct {
+	hash    string
+	options string
+}
+
+// sums are the records of the files that have been converted, for -skip
+type sums struct {
+	filename string
+	mu       sync.Mutex
+	hashes   map[string]sum
+}
+
+// outputOptions returns the options that change the output of a file, so
+// that a file converted with others isn't skipped
+func outputOptions() string {
+	options := []string{*format}
+	if *typed {
+		options = append(options, "typed")
+	}
+	if *decode {
+		options = append(options, "decode")
+	}
+	if *rawOutput {
+		options = append(options, "raw")
+	}
+	return strings.Join(options, ",")
+}
+
+// readSums reads the records of the files converted before, when -skip is
+// used
+func readSums() (*sums, error) {
+	s := &sums{hashes: make(map[string]sum)}
+	if *skip == "" {
+		return s, nil
+	}
+
+	dir := *outDir
+	if dir == "" {
+		dir = "."
+	}
+	s.filename = filepath.Join(dir, sumsFile)
+
+	file, err := os.Open(s.filename)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "  ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		// Records from before options were kept have none, so those files
+		// are converted again
+		var record sum
+		if parts := strings.Fields(fields[0]); len(parts) == 2 {
+			record = sum{parts[0], parts[1]}
+		}
+		s.hashes[fields[1]] = record
+	}
+	return s, scanner.Err()
+}
+
+// write writes the records of the files converted so far
+func (s *sums) write() error {
+	if s.filename == "" {
+		return nil
+	}
+
+	var inputs []string
+	for input := range s.hashes {
+		inputs = append(inputs, input)
+	}
+	sort.Strings(inputs)
+
+	var buf strings.Builder
+	for _, input := range inputs {
+		record := s.hashes[input]
+		fmt.Fprintf(&buf, "%s %s  %s\n", record.hash, record.options, input)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.filename), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.filename, []byte(buf.String()), 0644)
+}
+
+// convert converts a file, unless -skip finds it hasn't changed since it was
+// converted with the same options
+func (s *sums) convert(j job) result {
+	r := result{job: j}
+	if *skip == "" {
+		r.invalid, r.err = convertFile(j.input, j.output)
+		return r
+	}
+
+	record := sum{hash: "-", options: outputOptions()}
+	s.mu.Lock()
+	last, converted := s.hashes[j.input]
+	s.mu.Unlock()
+
+	output, err := os.Stat(j.output)
+	unchanged := err == nil && converted && last.options == record.options
+	switch *skip {
+	case "mtime":
+		input, err := os.Stat(j.input)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		unchanged = unchanged && !output.ModTime().Before(input.ModTime())
+	case "hash":
+		if record.hash, r.err = hashFile(j.input); r.err != nil {
+			return r
+		}
+		unchanged = unchanged && last.hash == record.hash
+	}
+	if unchanged {
+		r.skipped = true
+		return r
+	}
+
+	r.invalid, r.err = convertFile(j.input, j.output)
+	if r.err == nil {
+		s.mu.Lock()
+		s.hashes[j.input] = record
+		s.mu.Unlock()
+	}
+	return r
+}
+
+// hashFile returns the SHA-256 of a file, in hex
+func hashFile(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+const exampleFile = "../../orcbrew/schema/example.orcbrew"
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "orcbrew2json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// setFlag sets a string flag, returning a function that restores it
+func setFlag(flag *string, value string) func() {
+	old := *flag
+	*flag = value
+	return func() { *flag = old }
+}
+
+func TestGlobRoot(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"*.orcbrew", "."},
+		{"packs/*.orcbrew", "packs"},
+		{"packs/*/x.orcbrew", "packs"},
+		{"packs/a?/b/*.orcbrew", "packs"},
+		{"a/b/[cd]/*/*.orcbrew", "a/b"},
+		{"/srv/packs/**/*.orcbrew", "/srv/packs"},
+	}
+	for _, test := range tests {
+		if root := globRoot(filepath.FromSlash(test.pattern)); root != filepath.FromSlash(test.expected) {
+			t.Errorf("%s: expected %s, got %s", test.pattern, test.expected, root)
+		}
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	tests := []struct {
+		outDir   string
+		input    string
+		root     string
+		expected string
+	}{
+		{"", "a.orcbrew", ".", "a.json"},
+		{"", "packs/x/a.orcbrew", "packs", "packs/x/a.json"},
+		{"out", "a.orcbrew", ".", "out/a.json"},
+		{"out", "packs/x/a.orcbrew", "packs", "out/x/a.json"},
+		{"out", "packs/x/y/a.ORCBREW", "packs/x", "out/y/a.json"},
+		{"/tmp/out", "packs/a.orcbrew", "packs", "/tmp/out/a.json"},
+	}
+	for _, test := range tests {
+		restore := setFlag(outDir, filepath.FromSlash(test.outDir))
+		output, err := outputPath(filepath.FromSlash(test.input), filepath.FromSlash(test.root), ".json")
+		restore()
+		if err != nil {
+			t.Errorf("%s: %s", test.input, err)
+		} else if output != filepath.FromSlash(test.expected) {
+			t.Errorf("%s under %s with -outdir %q: expected %s, got %s", test.input, test.root, test.outDir, test.expected, output)
+		}
+	}
+}
+
+func TestFindInputs(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.orcbrew", "x/b.orcbrew", "x/y/c.orcbrew", "x/notes.txt", "z/a.orcbrew"} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer setFlag(outDir, filepath.Join(dir, "out"))()
+
+	path := func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+	tests := []struct {
+		args     []string
+		expected []job
+	}{
+		{
+			[]string{path("x")},
+			[]job{{path("x/b.orcbrew"), path("out/b.json")}, {path("x/y/c.orcbrew"), path("out/y/c.json")}},
+		},
+		{
+			// Files named twice are converted once
+			[]string{path("a.orcbrew"), path("*.orcbrew"), path("x/y")},
+			[]job{{path("a.orcbrew"), path("out/a.json")}, {path("x/y/c.orcbrew"), path("out/c.json")}},
+		},
+		{
+			[]string{path("*/*.orcbrew")},
+			[]job{{path("x/b.orcbrew"), path("out/x/b.json")}, {path("z/a.orcbrew"), path("out/z/a.json")}},
+		},
+	}
+	for _, test := range tests {
+		todo, err := findInputs(test.args)
+		if err != nil {
+			t.Errorf("%v: %s", test.args, err)
+			continue
+		}
+		if diff := deep.Equal(todo, test.expected); diff != nil {
+			t.Errorf("%v: %v", test.args, diff)
+		}
+	}
+
+	errors := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{path("a.orcbrew"), path("z")}, "would both be saved to"},
+		{[]string{path("*.json")}, "No files match"},
+		{[]string{path("missing.orcbrew")}, "no such file"},
+		{[]string{path("x/y/c.orcbrew"), path("x/notes.txt")}, ""},
+	}
+	for _, test := range errors {
+		_, err := findInputs(test.args)
+		if test.expected == "" {
+			if err != nil {
+				t.Errorf("%v: %s", test.args, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%v: expected an error containing %q, got %v", test.args, test.expected, err)
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	example, err := ioutil.ReadFile(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []string{"mtime", "hash"} {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		restoreSkip := setFlag(skip, mode)
+		restoreOutDir := setFlag(outDir, dir)
+		restoreFormat := setFlag(format, "ndjson")
+
+		j := job{filepath.Join(dir, "pack.orcbrew"), filepath.Join(dir, "pack.ndjson")}
+		// run writes the file, if given its contents, as if modified at
+		// modTime, then converts it
+		run := func(contents []byte, modTime time.Time) result {
+			if contents != nil {
+				if err := ioutil.WriteFile(j.input, contents, 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(j.input, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s, err := readSums()
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := s.convert(j)
+			if err := s.write(); err != nil {
+				t.Fatal(err)
+			}
+			return r
+		}
+
+		// A file that fails to convert leaves no output, and is tried again
+		broken := example[:len(example)/2]
+		if r := run(broken, time.Now().Add(-time.Hour)); r.err == nil {
+			t.Errorf("%s: expected an error converting a broken file", mode)
+		}
+		if _, err := os.Stat(j.output); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no output for a broken file, got %v", mode, err)
+		}
+		if r := run(nil, time.Time{}); r.err == nil || r.skipped {
+			t.Errorf("%s: expected a broken file not to be skipped, got %+v", mode, r)
+		}
+
+		if r := run(example, time.Now().Add(-time.Hour)); r.err != nil || r.skipped {
+			t.Errorf("%s: expected the file to be converted, got %+v", mode, r)
+		}
+
+		// A file converted with other options is converted again
+		for _, options := range []string{"json", "ndjson"} {
+			*format = options
+			if r := run(nil, time.Time{}); r.err != nil || r.skipped {
+				t.Errorf("%s: expected the file to be converted again as %s, got %+v", mode, options, r)
+			}
+			if r := run(nil, time.Time{}); r.err != nil || !r.skipped {
+				t.Errorf("%s: expected the file converted as %s to be skipped, got %+v", mode, options, r)
+			}
+		}
+
+		converted, err := ioutil.ReadFile(j.output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := run(nil, time.Time{}); r.err != nil || !r.skipped {
+			t.Errorf("%s: expected an unchanged file to be skipped, got %+v", mode, r)
+		}
+
+		// When a file that was converted is broken, its output from before is
+		// kept
+		if r := run(broken, time.Now().Add(time.Hour)); r.err == nil {
+			t.Errorf("%s: expected an error converting a broken file", mode)
+		}
+		if r := run(nil, time.Time{}); r.err == nil || r.skipped {
+			t.Errorf("%s: expected a broken file not to be skipped, got %+v", mode, r)
+		}
+		if output, err := ioutil.ReadFile(j.output); err != nil || string(output) != string(converted) {
+			t.Errorf("%s: expected the output from before to be kept, got %v", mode, err)
+		}
+		if files, _ := filepath.Glob(filepath.Join(dir, ".pack.ndjson*")); len(files) != 0 {
+			t.Errorf("%s: expected temporary files to be removed, got %v", mode, files)
+		}
+
+		restoreSkip()
+		restoreOutDir()
+		restoreFormat()
+	}
+}
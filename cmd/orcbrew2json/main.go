@@ -1,188 +1,217 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"strings"
 
-	"github.com/cespare/goclj/parse"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/jsonschema"
 )
 
 var rawOutput = flag.Bool("raw", false, "Don't pretty-print JSON output")
 var noSave = flag.Bool("nosave", false, "Don't save the JSON output")
+var validate = flag.Bool("validate", false, "Validate the JSON output against the schema instead of saving it")
+var format = flag.String("format", "json", "The format to write: json, or ndjson for a line for each entity")
+var typed = flag.Bool("typed", false, "Write typed JSON, which keeps keywords, sets and namespaces so it can be converted back")
+var decode = flag.Bool("decode", false, "Convert a typed JSON file back into an .orcbrew file")
+var outDir = flag.String("outdir", "", "Save the output under this directory, keeping the directory structure of the input, rather than next to each file")
+var parallel = flag.Int("jobs", runtime.NumCPU(), "The number of files to convert at once")
+var skip = flag.String("skip", "", "Skip files that haven't changed since they were converted: mtime, when the output is newer than the file, or hash, when the file's SHA-256 is the same")
 
 func main() {
 	flag.Parse()
 
 	args := flag.Args()
-	if len(args) != 1 {
+	if len(args) == 0 {
 		printUsage()
 		os.Exit(2)
 	}
 
-	filename := args[0]
-	contentsBytes, err := ioutil.ReadFile(filename)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed when reading file %s: %s", filename, err)
+	if *format != "json" && *format != "ndjson" {
+		fmt.Fprintf(os.Stderr, "Unknown format %s, expected json or ndjson\n", *format)
+		os.Exit(2)
+	}
+	if *typed && (*format == "ndjson" || *validate || *decode) {
+		fmt.Fprintf(os.Stderr, "-typed can't be used with -format ndjson, -validate or -decode\n")
+		os.Exit(2)
+	}
+	if *skip != "" && *skip != "mtime" && *skip != "hash" {
+		fmt.Fprintf(os.Stderr, "Unknown -skip %s, expected mtime or hash\n", *skip)
+		os.Exit(2)
+	}
+	if *skip != "" && (*validate || *noSave) {
+		fmt.Fprintf(os.Stderr, "-skip can't be used with -validate or -nosave\n")
+		os.Exit(2)
+	}
+	if *parallel < 1 {
+		fmt.Fprintf(os.Stderr, "-jobs must be at least 1\n")
 		os.Exit(2)
 	}
 
-	// Remove BOM if its at the start of the file
-	contentsBytes = bytes.TrimLeft(contentsBytes, "\xef\xbb\xbf")
+	todo, err := findInputs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(2)
+	}
+	if *noSave && len(todo) != 1 {
+		fmt.Fprintf(os.Stderr, "-nosave can only be used with a single file, got %d\n", len(todo))
+		os.Exit(2)
+	}
 
-	contents := string(contentsBytes)
+	os.Exit(runBatch(todo))
+}
 
-	contents = regexp.MustCompile(`#:orcpub.dnd.e5{`).ReplaceAllString(contents, "{")
-	contents = regexp.MustCompile(`#:orcpub.dnd.e5.character{`).ReplaceAllString(contents, "{")
-	contents = regexp.MustCompile(`#:orcpub.dnd.e5.character`).ReplaceAllString(contents, "#")
-	contents = regexp.MustCompile(`:orcpub.dnd.e5.character/`).ReplaceAllString(contents, "")
-	contents = regexp.MustCompile(`orcpub.dnd.e5/`).ReplaceAllString(contents, "")
-	contents = regexp.MustCompile(`orcpub.dnd.e5.[a-z-]*/`).ReplaceAllString(contents, "")
+// convertFile converts a file as the flags ask, saving the output to output,
+// or writing it to stdout with -nosave. With -validate it returns the values
+// that don't match the schema instead.
+func convertFile(filename string, output string) ([]*jsonschema.ValidationError, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Failed when reading file %s: %s", filename, err)
+	}
+	defer file.Close()
 
-	buf := bytes.NewBufferString(contents)
+	if *decode {
+		return nil, writeOrcbrew(filename, output, file)
+	}
+	if *format == "ndjson" && !*validate {
+		return nil, writeNDJSON(filename, output, file)
+	}
 
-	tt, err := parse.Reader(buf, "input.clj", 0)
+	convert := orcbrew.ToJSON
+	if *typed {
+		convert = orcbrew.ToTypedJSON
+	}
+	jsonString, err := convert(file)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing %s as Clojure: %s", filename, err)
-		os.Exit(2)
+		return nil, fmt.Errorf("Error parsing %s: %s", filename, err)
 	}
 
-	jsonString := treeToJSON(tt)
+	if *validate {
+		return validateJSON(jsonString)
+	}
 
 	if *noSave == false {
-		fName := strings.TrimSuffix(filename, filepath.Ext(filename))
-		fmt.Fprintf(os.Stdout, fmt.Sprintf("Saved to %s.json", fName))
-		ioutil.WriteFile(fmt.Sprintf("%s.json", fName), []byte(jsonString), 0)
-	} else {
-		if *rawOutput {
-			fmt.Fprintf(os.Stdout, jsonString)
-		} else {
-			var prettyJSON bytes.Buffer
-			err = json.Indent(&prettyJSON, []byte(jsonString), "", "  ")
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error parsing JSON: %s\n%s", err, jsonString)
-
-				os.Exit(2)
-			}
-
-			fmt.Fprint(os.Stdout, prettyJSON.String())
-		}
+		return nil, save(output, []byte(jsonString))
+	}
+	if *rawOutput {
+		fmt.Fprint(os.Stdout, jsonString)
+		return nil, nil
 	}
-}
 
-func printUsage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] inputFile\n", os.Args[0])
-	flag.PrintDefaults()
+	var prettyJSON bytes.Buffer
+	err = json.Indent(&prettyJSON, []byte(jsonString), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing JSON: %s\n%s", err, jsonString)
+	}
+	fmt.Fprint(os.Stdout, prettyJSON.String())
+	return nil, nil
 }
 
-func treeToJSON(tree *parse.Tree) string {
-	return nodesToJSON(tree.Roots, 0)
+// save writes the output of a file, creating the directory it's in
+func save(output string, contents []byte) error {
+	return writeOutput(output, func(w io.Writer) error {
+		if _, err := w.Write(contents); err != nil {
+			return fmt.Errorf("Error writing %s: %s", output, err)
+		}
+		return nil
+	})
 }
 
-func nodesToJSON(nodes []parse.Node, depth int) string {
-	var buf bytes.Buffer
-	for _, node := range nodes {
-		buf.WriteString(strings.Repeat("  ", depth))
-		buf.WriteString(nodeToJSON(node))
-		buf.WriteString("\n")
+// writeNDJSON writes a line of JSON for each entity as it's read, to a
+// .ndjson file or stdout
+func writeNDJSON(filename string, output string, file *os.File) error {
+	if *noSave {
+		return streamNDJSON(filename, file, os.Stdout)
 	}
-
-	return buf.String()
+	return writeOutput(output, func(w io.Writer) error {
+		return streamNDJSON(filename, file, w)
+	})
 }
 
-func nodeToJSON(node parse.Node) string {
-	switch v := node.(type) {
-
-	case *parse.KeywordNode:
-		return fmt.Sprintf(`"%s"`, v.Val[1:])
-	case *parse.StringNode:
-		return fmt.Sprintf(`"%s"`, v.Val)
-	case *parse.NumberNode:
-		return v.Val
-	case *parse.SymbolNode:
-		return fmt.Sprintf(`"%s"`, v.Val)
-	case *parse.SetNode:
-		var vals []string
-		for _, node := range filterNonValueNodes(v.Children()) {
-			vals = append(vals, nodeToJSON(node))
-		}
-
-		return fmt.Sprintf("[%s]", strings.Join(vals, ","))
-	case *parse.ListNode:
-		var vals []string
-		for _, node := range filterNonValueNodes(v.Children()) {
-			vals = append(vals, nodeToJSON(node))
+func streamNDJSON(filename string, file *os.File, out io.Writer) error {
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	err := orcbrew.Stream(file, func(e orcbrew.RawEntity) error {
+		if e.Pack == "" {
+			// As for orcbrew.ReadFile, a source without an option pack is
+			// named after the file
+			e.Pack = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
 		}
+		return enc.Encode(e)
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("Error parsing %s: %s", filename, err)
+	}
+	return nil
+}
 
-		return fmt.Sprintf("[%s]", strings.Join(vals, ","))
-	case *parse.VectorNode:
-		var vals []string
-		for _, node := range filterNonValueNodes(v.Children()) {
-			vals = append(vals, nodeToJSON(node))
-		}
-
-		return fmt.Sprintf("[%s]", strings.Join(vals, ","))
-	case *parse.MapNode:
-		var keys []parse.Node
-		var vals []parse.Node
-
-		children := v.Children()
-		children = filterNonValueNodes(children)
+// writeOutput writes the output of a file to a temporary file beside it,
+// which only replaces output once write succeeds, so that a file that fails
+// to convert leaves the output from before alone
+func writeOutput(output string, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(output), "."+filepath.Base(output)+".*")
+	if err != nil {
+		return fmt.Errorf("Error writing %s: %s", output, err)
+	}
+	defer os.Remove(tmp.Name())
 
-		if (len(children) % 2) != 0 {
-			panic(v.String())
-		}
-		for idx, node := range children {
-			if idx == 0 || (idx%2) == 0 {
-				keys = append(keys, node)
-			} else {
-				// The value might be empty, remove the key in that case
-				_, isNilValue := node.(*parse.NilNode)
-				if isNilValue {
-					keys = keys[0 : len(keys)-1]
-				} else {
-					vals = append(vals, node)
-				}
-			}
-		}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Error writing %s: %s", output, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), output)
+}
 
-		var entries []string
-		for idx, key := range keys {
-			var val = vals[idx]
-			var keyString = nodeToJSON(key)
-			if keyString[0] != '"' {
-				keyString = fmt.Sprintf(`"%s"`, keyString)
-			}
-			entries = append(entries, fmt.Sprintf("%s: %s", keyString, nodeToJSON(val)))
-		}
+// writeOrcbrew converts a typed JSON file back into an .orcbrew file, or
+// writes it to stdout
+func writeOrcbrew(filename string, output string, file *os.File) error {
+	edn, err := orcbrew.FromTypedJSON(file)
+	if err != nil {
+		return fmt.Errorf("Error parsing %s: %s", filename, err)
+	}
 
-		return fmt.Sprintf("{%s}", strings.Join(entries, ","))
-	case *parse.NewlineNode:
-		return ""
-	default:
-		return node.String()
+	if *noSave {
+		fmt.Fprint(os.Stdout, edn)
+		return nil
 	}
+	return save(output, []byte(edn))
 }
 
-func filterNonValueNodes(nodes []parse.Node) []parse.Node {
-	var result []parse.Node
-
-	for _, node := range nodes {
-		switch v := node.(type) {
-		case *parse.NewlineNode:
-			break
-		case *parse.CommentNode:
-			break
-		default:
-			result = append(result, v)
-		}
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] inputFile|directory|pattern...\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+// validateJSON returns the values of the JSON that don't match the schema
+func validateJSON(jsonString string) ([]*jsonschema.ValidationError, error) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(jsonString), &doc)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing JSON: %s\n%s", err, jsonString)
 	}
 
-	return result
+	return jsonschema.ForDocument(doc).Validate(doc), nil
 }
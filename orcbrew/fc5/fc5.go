@@ -0,0 +1,571 @@
+// Package fc5 exports option packs as a compendium for the Fight Club 5 and
+// Game Master 5 apps, which import an XML format with an element for each
+// spell, monster, race, class, feat and background.
+//
+// The apps show most things as text, so fields they have no element for are
+// written as features or traits. Subclasses are written as optional features
+// of their class, and subraces as races named after their race, e.g.
+// "Elf (Wood)".
+package fc5
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/render"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// Compendium is the root element of a compendium file
+type Compendium struct {
+	XMLName     xml.Name     `xml:"compendium"`
+	Version     string       `xml:"version,attr"`
+	AutoIndent  string       `xml:"auto_indent,attr"`
+	Classes     []Class      `xml:"class"`
+	Races       []Race       `xml:"race"`
+	Backgrounds []Background `xml:"background"`
+	Feats       []Feat       `xml:"feat"`
+	Spells      []Spell      `xml:"spell"`
+	Monsters    []Monster    `xml:"monster"`
+}
+
+// Spell is a spell, with its school abbreviated, e.g. EV for evocation
+type Spell struct {
+	Name       string   `xml:"name"`
+	Level      int      `xml:"level"`
+	School     string   `xml:"school,omitempty"`
+	Ritual     string   `xml:"ritual,omitempty"` // YES for rituals
+	Time       string   `xml:"time"`
+	Range      string   `xml:"range"`
+	Components string   `xml:"components"`
+	Duration   string   `xml:"duration"`
+	Classes    string   `xml:"classes,omitempty"`
+	Text       []string `xml:"text"` // a paragraph each
+}
+
+// Monster is a monster's stat block
+type Monster struct {
+	Name            string  `xml:"name"`
+	Size            string  `xml:"size"`
+	Type            string  `xml:"type"`
+	Alignment       string  `xml:"alignment"`
+	AC              int     `xml:"ac"`
+	HP              string  `xml:"hp"`
+	Speed           string  `xml:"speed"`
+	Str             int     `xml:"str"`
+	Dex             int     `xml:"dex"`
+	Con             int     `xml:"con"`
+	Int             int     `xml:"int"`
+	Wis             int     `xml:"wis"`
+	Cha             int     `xml:"cha"`
+	Save            string  `xml:"save,omitempty"`
+	Skill           string  `xml:"skill,omitempty"`
+	Resist          string  `xml:"resist,omitempty"`
+	Vulnerable      string  `xml:"vulnerable,omitempty"`
+	Immune          string  `xml:"immune,omitempty"`
+	ConditionImmune string  `xml:"conditionImmune,omitempty"`
+	Passive         int     `xml:"passive"`
+	Languages       string  `xml:"languages,omitempty"`
+	CR              string  `xml:"cr"`
+	Traits          []Trait `xml:"trait"`
+	Actions         []Trait `xml:"action"`
+	Legendary       []Trait `xml:"legendary"`
+	Description     string  `xml:"description,omitempty"`
+}
+
+// Trait is a named block of text: a trait, action or feature
+type Trait struct {
+	Name string   `xml:"name"`
+	Text []string `xml:"text"`
+}
+
+// Race is a race or subrace
+type Race struct {
+	Name        string  `xml:"name"`
+	Size        string  `xml:"size,omitempty"`
+	Speed       int     `xml:"speed,omitempty"`
+	Ability     string  `xml:"ability,omitempty"` // e.g. Dex 2, Wis 1
+	Proficiency string  `xml:"proficiency,omitempty"`
+	Traits      []Trait `xml:"trait"`
+}
+
+// Class is a class, with the features gained at each level
+type Class struct {
+	Name         string      `xml:"name"`
+	HD           int         `xml:"hd,omitempty"`
+	Proficiency  string      `xml:"proficiency,omitempty"` // saving throws and skills to choose from
+	NumSkills    int         `xml:"numSkills,omitempty"`
+	SpellAbility string      `xml:"spellAbility,omitempty"`
+	Autolevels   []Autolevel `xml:"autolevel"`
+}
+
+// Autolevel is what a class gains at a level
+type Autolevel struct {
+	Level            int       `xml:"level,attr"`
+	ScoreImprovement string    `xml:"scoreImprovement,attr,omitempty"` // YES at ability score improvements
+	Features         []Feature `xml:"feature"`
+}
+
+// Feature is a class feature, optional when it belongs to a subclass
+type Feature struct {
+	Optional string   `xml:"optional,attr,omitempty"` // YES for subclass features
+	Name     string   `xml:"name"`
+	Text     []string `xml:"text"`
+}
+
+// Feat is a feat
+type Feat struct {
+	Name         string     `xml:"name"`
+	Prerequisite string     `xml:"prerequisite,omitempty"`
+	Text         []string   `xml:"text"`
+	Modifiers    []Modifier `xml:"modifier"`
+}
+
+// Modifier is a bonus the app applies, e.g. "dexterity +1" in the "ability
+// score" category
+type Modifier struct {
+	Category string `xml:"category,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// Background is a background, with its features as traits
+type Background struct {
+	Name        string  `xml:"name"`
+	Proficiency string  `xml:"proficiency,omitempty"`
+	Traits      []Trait `xml:"trait"`
+}
+
+// Export converts a source to a compendium, with the entities of each kind
+// sorted by name
+func Export(source schema.OrcbrewSource) *Compendium {
+	book := render.NewBook("", source)
+	c := &Compendium{Version: "5", AutoIndent: "NO"}
+
+	for _, class := range book.Classes {
+		c.Classes = append(c.Classes, exportClass(book, class))
+	}
+	for _, group := range book.Subclasses {
+		// The features of subclasses of built-in classes are added to a
+		// class of the same name, which the apps merge with their own
+		fc5Class := Class{Name: book.Name("classes", group.Class)}
+		for _, subclass := range group.Subclasses {
+			addSubclass(book, &fc5Class, subclass)
+		}
+		c.Classes = append(c.Classes, fc5Class)
+	}
+
+	for _, race := range book.Races {
+		c.Races = append(c.Races, exportRace(race.RaceConfig))
+		for _, subrace := range race.Subraces {
+			c.Races = append(c.Races, exportSubrace(race.Name, &race.RaceConfig, subrace))
+		}
+	}
+	for _, group := range book.Subraces {
+		for _, subrace := range group.Subraces {
+			c.Races = append(c.Races, exportSubrace(book.Name("races", group.Race), nil, subrace))
+		}
+	}
+
+	for _, background := range book.Backgrounds {
+		c.Backgrounds = append(c.Backgrounds, exportBackground(background))
+	}
+	for _, feat := range book.Feats {
+		c.Feats = append(c.Feats, exportFeat(book, feat))
+	}
+	for _, level := range book.Spells {
+		for _, spell := range level.Spells {
+			c.Spells = append(c.Spells, exportSpell(book, spell))
+		}
+	}
+	for _, monster := range book.Monsters {
+		c.Monsters = append(c.Monsters, exportMonster(monster))
+	}
+	return c
+}
+
+// Write writes the compendium as indented XML
+func (c *Compendium) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Read parses a compendium written by Write, or by the apps
+func Read(r io.Reader) (*Compendium, error) {
+	var c Compendium
+	if err := xml.NewDecoder(r).Decode(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+var schools = map[string]string{
+	"abjuration": "A", "conjuration": "C", "divination": "D", "enchantment": "EN",
+	"evocation": "EV", "illusion": "I", "necromancy": "N", "transmutation": "T",
+}
+
+func exportSpell(book *render.Book, spell schema.SpellConfig) Spell {
+	s := Spell{
+		Name:       spell.Name,
+		Level:      spell.Level,
+		School:     schools[strings.ToLower(spell.School)],
+		Time:       spell.CastingTime,
+		Range:      spell.Range,
+		Components: render.Components(spell.Components),
+		Duration:   spell.Duration,
+		Text:       lines(spell.Description),
+	}
+	if spell.Ritual {
+		s.Ritual = "YES"
+	}
+
+	var classes []string
+	for _, key := range render.Keys(spell.SpellLists) {
+		classes = append(classes, book.Name("classes", key))
+	}
+	s.Classes = strings.Join(classes, ", ")
+	return s
+}
+
+var sizes = map[schema.Size]string{
+	schema.Tiny: "T", schema.Small: "S", schema.Medium: "M",
+	schema.Large: "L", schema.Huge: "H", schema.Gargantuan: "G",
+}
+
+var abilityAbbreviations = map[string]string{
+	"str": "Str", "dex": "Dex", "con": "Con", "int": "Int", "wis": "Wis", "cha": "Cha",
+}
+
+func exportMonster(monster schema.MonsterConfig) Monster {
+	m := Monster{
+		Name:      monster.Name,
+		Size:      sizes[monster.Size],
+		Type:      monster.Type,
+		Alignment: monster.Alignment,
+		AC:        monster.ArmorClass,
+		HP:        render.HitPoints(monster),
+		Speed:     monster.Speed,
+		Str:       monster.Str,
+		Dex:       monster.Dex,
+		Con:       monster.Con,
+		Int:       monster.Int,
+		Wis:       monster.Wis,
+		Cha:       monster.Cha,
+		CR:        render.Challenge(monster.Challenge),
+	}
+
+	var saves []string
+	for _, ability := range schema.Ability("").Values() {
+		if bonus, ok := monster.SavingThrows[schema.Ability(ability)]; ok {
+			saves = append(saves, abilityAbbreviations[ability]+" "+signed(bonus))
+		}
+	}
+	m.Save = strings.Join(saves, ", ")
+
+	var skills []string
+	for _, skill := range schema.Skill("").Values() {
+		if bonus, ok := monster.Skills[schema.Skill(skill)]; ok {
+			skills = append(skills, render.Title(skill)+" "+signed(bonus))
+		}
+	}
+	m.Skill = strings.Join(skills, ", ")
+
+	m.Passive = 10 + monster.Wis/2 - 5
+	if bonus, ok := monster.Skills[schema.Perception]; ok {
+		m.Passive = 10 + bonus
+	}
+
+	if props := monster.Props; props != nil {
+		m.Resist = strings.Join(render.Keys(props.DamageResistance), ", ")
+		m.Vulnerable = strings.Join(render.Keys(props.DamageVulnerability), ", ")
+		m.Immune = strings.Join(render.Keys(props.DamageImmunity), ", ")
+		m.ConditionImmune = strings.Join(render.Keys(props.ConditionImmunity), ", ")
+		m.Languages = titles(render.Keys(props.Language))
+	}
+
+	for _, trait := range monster.Traits {
+		t := Trait{Name: trait.Name, Text: lines(trait.Description)}
+		switch trait.Type {
+		case schema.MonsterTraitActionAction:
+			m.Actions = append(m.Actions, t)
+		case schema.MonsterTraitActionLegendaryAction:
+			m.Legendary = append(m.Legendary, t)
+		default:
+			m.Traits = append(m.Traits, t)
+		}
+	}
+	if monster.LegendaryActions != nil && monster.LegendaryActions.Description != "" {
+		m.Legendary = append([]Trait{{Text: lines(monster.LegendaryActions.Description)}}, m.Legendary...)
+	}
+
+	m.Description = monster.Description
+	return m
+}
+
+func exportRace(race schema.RaceConfig) Race {
+	r := Race{
+		Name:    race.Name,
+		Size:    sizes[race.Size],
+		Speed:   race.Speed,
+		Ability: abilityBonuses(race.Abilities),
+	}
+	if race.Props != nil {
+		r.Proficiency = titles(render.Keys(race.Props.SkillProficiency))
+	}
+	r.Traits = raceTraits(race.Darkvision, race.Languages, race.Traits)
+	return r
+}
+
+// exportSubrace returns a race for a subrace, with the size and speed of its
+// race when that's in the source
+func exportSubrace(raceName string, race *schema.RaceConfig, subrace schema.SubraceConfig) Race {
+	r := Race{
+		Name:    fmt.Sprintf("%s (%s)", raceName, subrace.Name),
+		Size:    sizes[subrace.Size],
+		Speed:   subrace.Speed,
+		Ability: abilityBonuses(subrace.Abilities),
+	}
+	if race != nil {
+		if r.Size == "" {
+			r.Size = sizes[race.Size]
+		}
+		if r.Speed == 0 {
+			r.Speed = race.Speed
+		}
+	}
+	if subrace.Props != nil {
+		r.Proficiency = titles(render.Keys(subrace.Props.SkillProficiency))
+	}
+	r.Traits = raceTraits(subrace.Darkvision, subrace.Languages, subrace.Traits)
+	return r
+}
+
+func raceTraits(darkvision int, languages []string, traits []schema.LevelTrait) []Trait {
+	var result []Trait
+	if darkvision > 0 {
+		result = append(result, Trait{Name: "Darkvision", Text: []string{fmt.Sprintf("You can see in dim light within %d feet of you as if it were bright light.", darkvision)}})
+	}
+	if len(languages) > 0 {
+		result = append(result, Trait{Name: "Languages", Text: []string{strings.Join(languages, ", ")}})
+	}
+	for _, trait := range traits {
+		result = append(result, Trait{Name: trait.Name, Text: lines(trait.Description)})
+	}
+	return result
+}
+
+// abilityBonuses formats ability score increases, e.g. "Dex 2, Wis 1"
+func abilityBonuses(abilities map[schema.Ability]int) string {
+	var parts []string
+	for _, ability := range schema.Ability("").Values() {
+		if n := abilities[schema.Ability(ability)]; n != 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", abilityAbbreviations[ability], n))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func exportClass(book *render.Book, class *render.Class) Class {
+	c := Class{Name: class.Name, HD: class.HitDie}
+	if class.Spellcasting != nil {
+		c.SpellAbility = render.Title(string(class.Spellcasting.Ability))
+	}
+
+	if profs := class.Profs; profs != nil {
+		proficiencies := titlesOf(render.Keys(profs.Save))
+		if options := profs.SkillOptions; options != nil {
+			proficiencies = append(proficiencies, titlesOf(render.Keys(options.Options))...)
+			c.NumSkills = options.Choose
+		}
+		c.Proficiency = strings.Join(proficiencies, ", ")
+	}
+
+	for _, level := range class.AbilityIncreaseLevels {
+		autolevel(&c, level).ScoreImprovement = "YES"
+	}
+	if class.SubclassLevel > 0 {
+		title := class.SubclassTitle
+		if title == "" {
+			title = "Subclass"
+		}
+		addFeature(&c, class.SubclassLevel, Feature{Name: title, Text: []string{"Choose your " + title + "."}})
+	}
+	for _, selection := range class.LevelSelections {
+		name := book.Name("selections", selection.Type)
+		addFeature(&c, selection.Level, Feature{Name: name, Text: []string{fmt.Sprintf("Choose %d options from %s.", selection.Num, name)}})
+	}
+	for _, trait := range class.Traits {
+		addFeature(&c, trait.Level, Feature{Name: trait.Name, Text: lines(trait.Description)})
+	}
+	for _, modifier := range class.LevelModifiers {
+		addFeature(&c, render.ModifierLevel(modifier), modifierFeature(book, modifier, ""))
+	}
+
+	for _, subclass := range class.Subclasses {
+		addSubclass(book, &c, subclass)
+	}
+
+	sort.SliceStable(c.Autolevels, func(i, j int) bool { return c.Autolevels[i].Level < c.Autolevels[j].Level })
+	return c
+}
+
+// addSubclass adds the traits and level modifiers of a subclass to its class
+// as optional features, named after the subclass
+func addSubclass(book *render.Book, c *Class, subclass schema.SubclassConfig) {
+	for _, trait := range subclass.Traits {
+		addFeature(c, trait.Level, Feature{
+			Optional: "YES",
+			Name:     subclass.Name + ": " + trait.Name,
+			Text:     lines(trait.Description),
+		})
+	}
+	for _, modifier := range subclass.LevelModifiers {
+		addFeature(c, render.ModifierLevel(modifier), modifierFeature(book, modifier, subclass.Name))
+	}
+	sort.SliceStable(c.Autolevels, func(i, j int) bool { return c.Autolevels[i].Level < c.Autolevels[j].Level })
+}
+
+// modifierFeature returns a feature describing a level modifier, optional
+// when it belongs to a subclass
+func modifierFeature(book *render.Book, modifier schema.LevelModifier, subclass string) Feature {
+	text := book.Modifier(modifier)
+	name := strings.SplitN(text, ":", 2)[0]
+	if subclass == "" {
+		return Feature{Name: name, Text: []string{text}}
+	}
+	return Feature{Optional: "YES", Name: subclass + ": " + name, Text: []string{text}}
+}
+
+func addFeature(c *Class, level int, feature Feature) {
+	a := autolevel(c, level)
+	a.Features = append(a.Features, feature)
+}
+
+// autolevel returns the autolevel of a class for a level, adding it when
+// needed; level 0 means the first level
+func autolevel(c *Class, level int) *Autolevel {
+	if level < 1 {
+		level = 1
+	}
+	for idx := range c.Autolevels {
+		if c.Autolevels[idx].Level == level {
+			return &c.Autolevels[idx]
+		}
+	}
+	c.Autolevels = append(c.Autolevels, Autolevel{Level: level})
+	return &c.Autolevels[len(c.Autolevels)-1]
+}
+
+func exportFeat(book *render.Book, feat schema.FeatConfig) Feat {
+	f := Feat{Name: feat.Name, Text: lines(feat.Description)}
+
+	var prerequisites []string
+	for _, prereq := range feat.Prereqs {
+		switch {
+		case abilityAbbreviations[prereq] != "":
+			prerequisites = append(prerequisites, render.Title(prereq)+" 13 or higher")
+		case prereq == "spellcasting":
+			prerequisites = append(prerequisites, "The ability to cast at least one spell")
+		default:
+			prerequisites = append(prerequisites, "Proficiency with "+prereq+" armor")
+		}
+	}
+	var races []string
+	for _, race := range render.Keys(feat.PathPrereqs.Race) {
+		races = append(races, book.Name("races", race))
+	}
+	if len(races) > 0 {
+		prerequisites = append(prerequisites, strings.Join(races, " or "))
+	}
+	f.Prerequisite = strings.Join(prerequisites, ", ")
+
+	// The apps apply modifiers automatically, which only makes sense when
+	// there's no choice of ability
+	var increases []string
+	for _, ability := range feat.AbilityIncreases {
+		if abilityAbbreviations[ability] != "" {
+			increases = append(increases, ability)
+		}
+	}
+	if len(increases) == 1 {
+		f.Modifiers = append(f.Modifiers, Modifier{Category: "ability score", Value: strings.ToLower(render.Title(increases[0])) + " +1"})
+	}
+	return f
+}
+
+func exportBackground(background schema.BackgroundConfig) Background {
+	b := Background{Name: background.Name}
+	if profs := background.Profs; profs != nil {
+		b.Proficiency = titles(render.Keys(profs.Skill))
+		if tools := render.Keys(profs.Tool); len(tools) > 0 {
+			b.Traits = append(b.Traits, Trait{Name: "Tool Proficiencies", Text: []string{titles(tools)}})
+		}
+		if options := profs.LanguageOptions; options != nil && options.Choose > 0 {
+			b.Traits = append(b.Traits, Trait{Name: "Languages", Text: []string{fmt.Sprintf("%d of your choice", options.Choose)}})
+		}
+	}
+
+	var equipment []string
+	for _, item := range render.Keys(background.Equipment) {
+		if n := background.Equipment[item]; n > 1 {
+			equipment = append(equipment, fmt.Sprintf("%s (%d)", render.Title(item), n))
+		} else {
+			equipment = append(equipment, render.Title(item))
+		}
+	}
+	for _, currency := range schema.Currency("").Values() {
+		if n := background.Treasure[schema.Currency(currency)]; n > 0 {
+			equipment = append(equipment, fmt.Sprintf("%d %s", n, currency))
+		}
+	}
+	if len(equipment) > 0 {
+		b.Traits = append(b.Traits, Trait{Name: "Equipment", Text: []string{strings.Join(equipment, ", ")}})
+	}
+
+	for _, trait := range background.Traits {
+		b.Traits = append(b.Traits, Trait{Name: trait.Name, Text: lines(trait.Description)})
+	}
+	return b
+}
+
+func signed(n int) string {
+	if n >= 0 {
+		return "+" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+func titlesOf(keys []string) []string {
+	var result []string
+	for _, key := range keys {
+		result = append(result, render.Title(key))
+	}
+	return result
+}
+
+func titles(keys []string) string {
+	return strings.Join(titlesOf(keys), ", ")
+}
+
+// lines splits text into its non-blank lines, which the apps show as
+// paragraphs
+func lines(text string) []string {
+	var result []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
@@ -0,0 +1,177 @@
+package fc5
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+const exampleFile = "../schema/example.orcbrew"
+
+func exampleCompendium(t *testing.T) *Compendium {
+	f, err := orcbrew.ReadFile(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := f.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Export(source)
+}
+
+func TestRoundTrip(t *testing.T) {
+	c := exampleCompendium(t)
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<compendium version="5" auto_indent="NO">`) {
+		t.Errorf("Unexpected start of file:\n%s", buf.String()[:100])
+	}
+
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read.XMLName = c.XMLName
+	if diff := deep.Equal(read, c); diff != nil {
+		t.Error(diff)
+	}
+
+	counts := map[string]int{
+		"classes":     len(read.Classes),
+		"races":       len(read.Races),
+		"backgrounds": len(read.Backgrounds),
+		"feats":       len(read.Feats),
+		"spells":      len(read.Spells),
+		"monsters":    len(read.Monsters),
+	}
+	// The subclass of a built-in class is written as a class of its own,
+	// and the subrace of a built-in race as a race
+	expected := map[string]int{"classes": 3, "races": 2, "backgrounds": 1, "feats": 1, "spells": 1, "monsters": 1}
+	if diff := deep.Equal(counts, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestClass(t *testing.T) {
+	c := exampleCompendium(t)
+	classes := make(map[string]Class)
+	for _, class := range c.Classes {
+		classes[class.Name] = class
+	}
+
+	class := classes["MyClass"]
+	if class.Name != "MyClass" || class.SpellAbility != "Charisma" {
+		t.Errorf("Unexpected class %s with spell ability %s", class.Name, class.SpellAbility)
+	}
+
+	features := make(map[int][]string)
+	improvements := make(map[int]bool)
+	for idx, autolevel := range class.Autolevels {
+		if idx > 0 && class.Autolevels[idx-1].Level >= autolevel.Level {
+			t.Errorf("Autolevels out of order at level %d", autolevel.Level)
+		}
+		for _, feature := range autolevel.Features {
+			features[autolevel.Level] = append(features[autolevel.Level], feature.Name)
+		}
+		improvements[autolevel.Level] = autolevel.ScoreImprovement == "YES"
+	}
+
+	for _, level := range []int{4, 8, 12, 16, 19} {
+		if !improvements[level] {
+			t.Errorf("Expected an ability score improvement at level %d", level)
+		}
+	}
+	if diff := deep.Equal(features[2], []string{"MySubclassTitle", "MyClassTrait", "Advantage on saving throws against"}); diff != nil {
+		t.Error(diff)
+	}
+
+	subclasses, ok := classes["Barbarian"]
+	if !ok {
+		t.Fatalf("Expected a class for the subclass's class")
+	}
+	for _, autolevel := range subclasses.Autolevels {
+		for _, feature := range autolevel.Features {
+			if feature.Optional != "YES" || !strings.HasPrefix(feature.Name, "MySubClass: ") {
+				t.Errorf("Unexpected subclass feature %+v", feature)
+			}
+		}
+	}
+}
+
+func TestMonster(t *testing.T) {
+	m := exportMonster(schema.MonsterConfig{
+		Name: "Ash Goblin",
+		Str:  8, Dex: 14, Con: 12, Int: 10, Wis: 8, Cha: 8,
+		HitPoints:    &schema.HitDieCount{DieCount: 3, Die: 6},
+		Size:         schema.Small,
+		Skills:       map[schema.Skill]int{schema.Stealth: 6, schema.Perception: 1},
+		SavingThrows: map[schema.Ability]int{schema.Wisdom: -1, schema.Dexterity: 4},
+		Challenge:    0.25,
+		Props: &schema.MonsterProperties{
+			Language:       map[string]bool{"common": true, "goblin": true},
+			DamageImmunity: map[schema.Damage]bool{schema.Fire: true, schema.Cold: true},
+		},
+		LegendaryActions: &schema.LegendaryActionDescription{Description: "It can take 3 legendary actions."},
+		Traits: []schema.MonsterTrait{
+			{Name: "Nimble Escape", Description: "It can Disengage."},
+			{Type: schema.MonsterTraitActionAction, Name: "Scimitar", Description: "Melee Weapon Attack.\nHit: 5 slashing damage."},
+			{Type: schema.MonsterTraitActionLegendaryAction, Name: "Dash", Description: "It moves."},
+		},
+	})
+
+	expected := Monster{
+		Name: "Ash Goblin",
+		Size: "S",
+		HP:   "13 (3d6 + 3)",
+		Str:  8, Dex: 14, Con: 12, Int: 10, Wis: 8, Cha: 8,
+		Save:      "Dex +4, Wis -1",
+		Skill:     "Perception +1, Stealth +6",
+		Immune:    "cold, fire",
+		Passive:   11,
+		Languages: "Common, Goblin",
+		CR:        "1/4",
+		Traits:    []Trait{{Name: "Nimble Escape", Text: []string{"It can Disengage."}}},
+		Actions:   []Trait{{Name: "Scimitar", Text: []string{"Melee Weapon Attack.", "Hit: 5 slashing damage."}}},
+		Legendary: []Trait{
+			{Text: []string{"It can take 3 legendary actions."}},
+			{Name: "Dash", Text: []string{"It moves."}},
+		},
+	}
+	if diff := deep.Equal(m, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestSpellAndFeat(t *testing.T) {
+	c := exampleCompendium(t)
+
+	spell := c.Spells[0]
+	if spell.School != "N" || spell.Ritual != "YES" || spell.Components != "V, S, M (A pinch of salt)" {
+		t.Errorf("Unexpected spell %+v", spell)
+	}
+	if !strings.HasPrefix(spell.Classes, "Bard, Cleric, Druid") {
+		t.Errorf("Unexpected spell classes %s", spell.Classes)
+	}
+
+	feat := c.Feats[0]
+	if !strings.HasPrefix(feat.Prerequisite, "Strength 13 or higher, Constitution 13 or higher") {
+		t.Errorf("Unexpected feat prerequisite %s", feat.Prerequisite)
+	}
+	// A choice of abilities isn't written as a modifier
+	if len(feat.Modifiers) != 0 {
+		t.Errorf("Expected no modifiers, got %v", feat.Modifiers)
+	}
+
+	single := exportFeat(nil, schema.FeatConfig{Name: "Tough Mind", AbilityIncreases: []string{"wis"}})
+	if diff := deep.Equal(single.Modifiers, []Modifier{{Category: "ability score", Value: "wisdom +1"}}); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,733 @@
+package fivetools
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var casterProgressions = map[string]int{"full": 1, "artificer": 2, "1/2": 2, "1/3": 3, "pact": 1}
+
+func (im *importer) feat(obj object) {
+	name := StripTags(getString(obj, "name"))
+	key := Key(name)
+
+	feat := schema.FeatConfig{
+		Key:         key,
+		OptionPack:  im.pack,
+		Name:        name,
+		Description: Entries(getList(obj, "entries")),
+	}
+
+	for _, value := range getList(obj, "ability") {
+		increase := asObject(value)
+		for _, field := range sortedFields(increase) {
+			if field == "choose" {
+				choose := getObject(increase, "choose")
+				feat.AbilityIncreases = append(feat.AbilityIncreases, getStrings(choose, "from")...)
+				if amount := getInt(choose, "amount"); amount > 1 {
+					im.note("feats", key, "ability", "imported as an increase of 1", choose)
+				}
+			} else if _, ok := abilityNames[field]; ok {
+				feat.AbilityIncreases = append(feat.AbilityIncreases, field)
+				if amount := getInt(increase, field); amount != 1 {
+					im.note("feats", key, "ability", "imported as an increase of 1", increase)
+				}
+			}
+		}
+	}
+
+	for _, value := range getList(obj, "prerequisite") {
+		prerequisite := asObject(value)
+		for _, field := range sortedFields(prerequisite) {
+			switch field {
+			case "spellcasting", "spellcasting2020":
+				feat.Prereqs = append(feat.Prereqs, "spellcasting")
+			case "ability":
+				for _, ability := range getList(prerequisite, field) {
+					ability := asObject(ability)
+					for _, name := range sortedFields(ability) {
+						feat.Prereqs = append(feat.Prereqs, name)
+						if getInt(ability, name) != 13 {
+							im.note("feats", key, "prerequisite.ability", "imported as a score of 13, as in OrcPub", ability)
+						}
+					}
+				}
+			case "proficiency":
+				for _, proficiency := range getList(prerequisite, field) {
+					proficiency := asObject(proficiency)
+					if armor := getString(proficiency, "armor"); armor != "" && isValue(schema.Armor(armor), armor) {
+						feat.Prereqs = append(feat.Prereqs, armor)
+					} else {
+						im.note("feats", key, "prerequisite.proficiency", "not imported", proficiency)
+					}
+				}
+			case "race":
+				for _, race := range getList(prerequisite, field) {
+					if feat.PathPrereqs.Race == nil {
+						feat.PathPrereqs.Race = make(map[string]bool)
+					}
+					feat.PathPrereqs.Race[Key(getString(asObject(race), "name"))] = true
+				}
+			default:
+				im.note("feats", key, "prerequisite."+field, "not imported", prerequisite[field])
+			}
+		}
+	}
+
+	if im.add("feats", key, feat) {
+		im.unused("feats", key, obj, "name", "entries", "ability", "prerequisite")
+	}
+}
+
+// raceTraits holds what races and subraces have in common
+type raceTraits struct {
+	Abilities  map[schema.Ability]int
+	Languages  []string
+	Darkvision int
+	Size       schema.Size
+	Speed      int
+	Spells     []schema.RaceSpellConfig
+	Props      schema.RaceProperties
+	Profs      schema.RaceProficiencies
+	Traits     []schema.LevelTrait
+}
+
+func (im *importer) race(obj object) {
+	name := StripTags(getString(obj, "name"))
+	key := Key(name)
+
+	traits, used := im.raceTraits("races", key, obj)
+	race := schema.RaceConfig{
+		Key:        key,
+		OptionPack: im.pack,
+		Name:       name,
+		Abilities:  traits.Abilities,
+		Languages:  traits.Languages,
+		Darkvision: traits.Darkvision,
+		Size:       traits.Size,
+		Speed:      traits.Speed,
+		Spells:     traits.Spells,
+		Traits:     traits.Traits,
+	}
+	if !reflect.DeepEqual(traits.Props, schema.RaceProperties{}) {
+		race.Props = &traits.Props
+	}
+	if !reflect.DeepEqual(traits.Profs, schema.RaceProficiencies{}) {
+		race.Profs = &traits.Profs
+	}
+
+	if !im.add("races", key, race) {
+		return
+	}
+	im.unused("races", key, obj, append(used, "name", "subraces")...)
+
+	for _, subrace := range getList(obj, "subraces") {
+		subrace := asObject(subrace)
+		if getString(subrace, "name") == "" {
+			im.note("races", key, "subraces", "not imported, the subrace has no name", nil)
+			continue
+		}
+		subrace["raceName"] = name
+		im.subrace(subrace)
+	}
+}
+
+func (im *importer) subrace(obj object) {
+	raceName := StripTags(getString(obj, "raceName"))
+	name := StripTags(getString(obj, "name"))
+	if !strings.Contains(name, raceName) {
+		name += " " + raceName
+	}
+	key := Key(name)
+
+	traits, used := im.raceTraits("subraces", key, obj)
+	subrace := schema.SubraceConfig{
+		Key:        key,
+		OptionPack: im.pack,
+		Name:       name,
+		Race:       Key(raceName),
+		Abilities:  traits.Abilities,
+		Languages:  traits.Languages,
+		Darkvision: traits.Darkvision,
+		Size:       traits.Size,
+		Speed:      traits.Speed,
+		Spells:     traits.Spells,
+		Traits:     traits.Traits,
+	}
+	props := schema.SubraceProperties{
+		FlyingSpeed:          traits.Props.FlyingSpeed,
+		SkillProficiency:     traits.Props.SkillProficiency,
+		DamageImmunity:       traits.Props.DamageImmunity,
+		DamageResistance:     traits.Props.DamageResistance,
+		ArmorProficiency:     traits.Props.ArmorProficiency,
+		WeaponProficiency:    traits.Props.WeaponProficiency,
+		SavingThrowAdvantage: traits.Props.SavingThrowAdvantage,
+		Language:             traits.Props.Language,
+		MaxHpBonus:           traits.Props.MaxHpBonus,
+	}
+	if !reflect.DeepEqual(props, schema.SubraceProperties{}) {
+		subrace.Props = &props
+	}
+	if !reflect.DeepEqual(traits.Profs, schema.RaceProficiencies{}) {
+		subrace.Profs = &traits.Profs
+	}
+
+	if im.add("subraces", key, subrace) {
+		im.unused("subraces", key, obj, append(used, "name", "raceName", "raceSource")...)
+	}
+}
+
+// raceTraits reads the fields races and subraces have in common, returning
+// them with the names of the fields that were read
+func (im *importer) raceTraits(kind string, key string, obj object) (raceTraits, []string) {
+	traits := raceTraits{
+		Darkvision: getInt(obj, "darkvision"),
+		Size:       size(obj["size"]),
+	}
+	used := []string{"darkvision", "size", "speed", "ability", "languageProficiencies",
+		"skillProficiencies", "weaponProficiencies", "armorProficiencies",
+		"toolProficiencies", "resist", "immune", "additionalSpells", "entries"}
+
+	switch speed := obj["speed"].(type) {
+	case float64:
+		traits.Speed = int(speed)
+	case map[string]interface{}:
+		traits.Speed = getInt(speed, "walk")
+		if fly, ok := speed["fly"].(float64); ok {
+			traits.Props.FlyingSpeed = int(fly)
+		} else if speed["fly"] != nil {
+			im.note(kind, key, "speed.fly", "not imported", speed["fly"])
+		}
+		for _, mode := range []string{"burrow", "climb", "swim"} {
+			if speed[mode] != nil {
+				im.note(kind, key, "speed."+mode, "not imported", speed[mode])
+			}
+		}
+	}
+
+	for idx, value := range getList(obj, "ability") {
+		ability := asObject(value)
+		if idx > 0 {
+			im.note(kind, key, "ability", "has alternatives, only the first was imported", ability)
+			break
+		}
+		for _, field := range sortedFields(ability) {
+			if name, ok := abilityNames[field]; ok {
+				if traits.Abilities == nil {
+					traits.Abilities = make(map[schema.Ability]int)
+				}
+				traits.Abilities[name] = getInt(ability, field)
+			} else {
+				im.note(kind, key, "ability."+field, "not imported", ability[field])
+			}
+		}
+	}
+
+	for _, value := range getList(obj, "languageProficiencies") {
+		languages := asObject(value)
+		for _, field := range sortedFields(languages) {
+			if getBool(languages, field) && field != "other" {
+				traits.Languages = append(traits.Languages, strings.Title(field))
+			} else {
+				im.note(kind, key, "languageProficiencies."+field, "not imported", languages[field])
+			}
+		}
+	}
+
+	for _, value := range getList(obj, "skillProficiencies") {
+		skills := asObject(value)
+		for _, field := range sortedFields(skills) {
+			switch {
+			case field == "choose":
+				choose := getObject(skills, field)
+				traits.Profs.SkillOptions = &schema.RaceSkillOptions{Choose: getInt(choose, "count"), Options: skillSet(getStrings(choose, "from"))}
+				if traits.Profs.SkillOptions.Choose == 0 {
+					traits.Profs.SkillOptions.Choose = 1
+				}
+			case field == "any":
+				traits.Profs.SkillOptions = &schema.RaceSkillOptions{Choose: getInt(skills, field), Options: skillSet(schema.Skill("").Values())}
+			default:
+				if traits.Props.SkillProficiency == nil {
+					traits.Props.SkillProficiency = make(map[schema.Skill]bool)
+				}
+				traits.Props.SkillProficiency[schema.Skill(Key(field))] = true
+			}
+		}
+	}
+
+	for _, value := range getList(obj, "weaponProficiencies") {
+		weapons := asObject(value)
+		for _, field := range sortedFields(weapons) {
+			if getBool(weapons, field) {
+				if traits.Props.WeaponProficiency == nil {
+					traits.Props.WeaponProficiency = make(map[string]bool)
+				}
+				traits.Props.WeaponProficiency[Key(refName(field))] = true
+			} else {
+				im.note(kind, key, "weaponProficiencies."+field, "not imported", weapons[field])
+			}
+		}
+	}
+
+	for _, value := range getList(obj, "armorProficiencies") {
+		armors := asObject(value)
+		for _, field := range sortedFields(armors) {
+			if armor := Key(refName(field)); isValue(schema.Armor(armor), armor) || armor == "shield" {
+				if traits.Props.ArmorProficiency == nil {
+					traits.Props.ArmorProficiency = make(map[schema.Armor]bool)
+				}
+				traits.Props.ArmorProficiency[schema.Armor(strings.Replace(armor, "shield", "shields", 1))] = true
+			} else {
+				im.note(kind, key, "armorProficiencies."+field, "not imported", armors[field])
+			}
+		}
+	}
+
+	for _, value := range getList(obj, "toolProficiencies") {
+		tools := asObject(value)
+		for _, field := range sortedFields(tools) {
+			if getBool(tools, field) {
+				if traits.Profs.ToolOptions == nil {
+					traits.Profs.ToolOptions = make(map[string]bool)
+				}
+				traits.Profs.ToolOptions[Key(refName(field))] = true
+			} else {
+				im.note(kind, key, "toolProficiencies."+field, "not imported", tools[field])
+			}
+		}
+	}
+
+	for _, damage := range []struct {
+		field string
+		set   *map[schema.Damage]bool
+	}{
+		{"resist", &traits.Props.DamageResistance},
+		{"immune", &traits.Props.DamageImmunity},
+	} {
+		for _, value := range getList(obj, damage.field) {
+			if s, ok := value.(string); ok {
+				if *damage.set == nil {
+					*damage.set = make(map[schema.Damage]bool)
+				}
+				(*damage.set)[schema.Damage(s)] = true
+			} else {
+				im.note(kind, key, damage.field, "not imported", value)
+			}
+		}
+	}
+
+	traits.Spells = im.raceSpells(kind, key, obj)
+
+	for _, entry := range getList(obj, "entries") {
+		if name := getString(asObject(entry), "name"); name != "" {
+			traits.Traits = append(traits.Traits, schema.LevelTrait{
+				Name:        StripTags(name),
+				Description: Entries(getList(asObject(entry), "entries")),
+			})
+		} else if text := entryText(entry); text != "" {
+			im.note(kind, key, "entries", "not imported, OrcPub only has named traits", text)
+		}
+	}
+
+	return traits, used
+}
+
+// raceSpells converts the spells a race knows or can cast
+func (im *importer) raceSpells(kind string, key string, obj object) []schema.RaceSpellConfig {
+	var spells []schema.RaceSpellConfig
+	for idx, value := range getList(obj, "additionalSpells") {
+		additional := asObject(value)
+		if idx > 0 {
+			im.note(kind, key, "additionalSpells", "has alternatives, only the first was imported", nil)
+			break
+		}
+
+		ability, ok := abilityNames[getString(additional, "ability")]
+		if !ok {
+			ability = schema.Charisma
+			im.note(kind, key, "additionalSpells.ability", "not imported, using Charisma", additional["ability"])
+		}
+
+		for _, field := range sortedFields(additional) {
+			if field == "ability" {
+				continue
+			}
+			if field != "innate" && field != "known" {
+				im.note(kind, key, "additionalSpells."+field, "not imported", additional[field])
+				continue
+			}
+
+			byLevel := getObject(additional, field)
+			var levels []int
+			for _, levelField := range sortedFields(byLevel) {
+				level, err := strconv.Atoi(strings.TrimSuffix(levelField, "c"))
+				if err != nil {
+					im.note(kind, key, "additionalSpells."+field+"."+levelField, "not imported", byLevel[levelField])
+					continue
+				}
+				levels = append(levels, level)
+			}
+			sort.Ints(levels)
+
+			for _, level := range levels {
+				value := byLevel[strconv.Itoa(level)]
+				if value == nil {
+					value = byLevel[strconv.Itoa(level)+"c"]
+				}
+				for _, spell := range spellRefs(value) {
+					config := schema.RaceSpellConfig{Value: schema.SpellWithAbilityLevel{Key: Key(refName(spell)), Ability: ability}}
+					if level > 1 {
+						config.Value.Level = level
+					}
+					spells = append(spells, config)
+				}
+			}
+		}
+	}
+	return spells
+}
+
+// spellRefs returns the spells in a 5etools additionalSpells value, which may
+// be a list or organized by uses per day
+func spellRefs(value interface{}) []string {
+	var spells []string
+	switch v := value.(type) {
+	case string:
+		spells = append(spells, v)
+	case []interface{}:
+		for _, item := range v {
+			spells = append(spells, spellRefs(item)...)
+		}
+	case map[string]interface{}:
+		for _, field := range sortedFields(v) {
+			spells = append(spells, spellRefs(v[field])...)
+		}
+	}
+	return spells
+}
+
+func skillSet(skills []string) map[schema.Skill]bool {
+	set := make(map[schema.Skill]bool)
+	for _, skill := range skills {
+		set[schema.Skill(Key(skill))] = true
+	}
+	return set
+}
+
+func (im *importer) background(obj object) {
+	name := StripTags(getString(obj, "name"))
+	key := Key(name)
+
+	background := schema.BackgroundConfig{
+		Key:        key,
+		OptionPack: im.pack,
+		Name:       name,
+	}
+	profs := schema.BackgroundProfs{}
+
+	for _, value := range getList(obj, "skillProficiencies") {
+		skills := asObject(value)
+		for _, field := range sortedFields(skills) {
+			if getBool(skills, field) {
+				if profs.Skill == nil {
+					profs.Skill = make(map[schema.Skill]bool)
+				}
+				profs.Skill[schema.Skill(Key(field))] = true
+			} else {
+				im.note("backgrounds", key, "skillProficiencies."+field, "not imported", skills[field])
+			}
+		}
+	}
+
+	for _, value := range getList(obj, "languageProficiencies") {
+		languages := asObject(value)
+		for _, field := range sortedFields(languages) {
+			if field == "anyStandard" || field == "any" {
+				profs.LanguageOptions = &schema.BackgroundLanguageOptions{Choose: getInt(languages, field), Options: schema.BackgroundLanguageOptionConfig{Any: true}}
+			} else {
+				im.note("backgrounds", key, "languageProficiencies."+field, "not imported", languages[field])
+			}
+		}
+	}
+
+	for _, value := range getList(obj, "toolProficiencies") {
+		tools := asObject(value)
+		for _, field := range sortedFields(tools) {
+			switch {
+			case field == "gamingSet":
+				profs.ToolOptions = toolOptions(profs.ToolOptions)
+				profs.ToolOptions.GamingSet = getInt(tools, field)
+			case field == "musicalInstrument":
+				profs.ToolOptions = toolOptions(profs.ToolOptions)
+				profs.ToolOptions.MusicalInstrument = getInt(tools, field)
+			case getBool(tools, field):
+				if profs.Tool == nil {
+					profs.Tool = make(map[string]bool)
+				}
+				profs.Tool[Key(refName(field))] = true
+			default:
+				im.note("backgrounds", key, "toolProficiencies."+field, "not imported", tools[field])
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(profs, schema.BackgroundProfs{}) {
+		background.Profs = &profs
+	}
+
+	for _, entry := range getList(obj, "entries") {
+		entry := asObject(entry)
+		if name := getString(entry, "name"); name != "" {
+			background.Traits = append(background.Traits, schema.BackgroundTrait{
+				Name:        StripTags(name),
+				Description: Entries(getList(entry, "entries")),
+			})
+		}
+	}
+
+	if im.add("backgrounds", key, background) {
+		im.unused("backgrounds", key, obj, "name", "skillProficiencies", "languageProficiencies", "toolProficiencies", "entries")
+	}
+}
+
+func toolOptions(options *schema.BackgroundToolOptions) *schema.BackgroundToolOptions {
+	if options == nil {
+		return &schema.BackgroundToolOptions{}
+	}
+	return options
+}
+
+func (im *importer) class(obj object) {
+	name := StripTags(getString(obj, "name"))
+	key := Key(name)
+
+	class := schema.ClassConfig{
+		Key:           key,
+		OptionPack:    im.pack,
+		Name:          name,
+		HitDie:        getInt(getObject(obj, "hd"), "faces"),
+		SubclassTitle: StripTags(getString(obj, "subclassTitle")),
+	}
+	used := []string{"name", "hd", "subclassTitle", "proficiency", "startingProficiencies",
+		"spellcastingAbility", "casterProgression", "spellsKnownProgression", "classFeatures"}
+
+	profs := schema.ClassProficiencies{}
+	for _, save := range getStrings(obj, "proficiency") {
+		if profs.Save == nil {
+			profs.Save = make(map[schema.Ability]bool)
+		}
+		profs.Save[abilityNames[save]] = true
+	}
+
+	starting := getObject(obj, "startingProficiencies")
+	for _, field := range sortedFields(starting) {
+		switch field {
+		case "skills":
+			for _, value := range getList(starting, field) {
+				skills := asObject(value)
+				if choose := getObject(skills, "choose"); choose != nil {
+					profs.SkillOptions = &schema.SkillOptions{Choose: getInt(choose, "count"), Options: skillSet(getStrings(choose, "from"))}
+				} else if any := getInt(skills, "any"); any > 0 {
+					profs.SkillOptions = &schema.SkillOptions{Choose: any, Options: skillSet(schema.Skill("").Values())}
+				} else {
+					im.note("classes", key, "startingProficiencies.skills", "not imported", skills)
+				}
+			}
+		case "armor":
+			for _, armor := range getList(starting, field) {
+				if s, ok := armor.(string); ok && isValue(schema.Armor(Key(refName(s))), Key(refName(s))) {
+					class.LevelModifiers = append(class.LevelModifiers, &schema.ModifierArmorProficiency{Value: schema.Armor(Key(refName(s)))})
+				} else if ok && Key(refName(s)) == "shield" {
+					class.LevelModifiers = append(class.LevelModifiers, &schema.ModifierArmorProficiency{Value: schema.Shields})
+				} else {
+					im.note("classes", key, "startingProficiencies.armor", "not imported", armor)
+				}
+			}
+		case "weapons":
+			for _, weapon := range getList(starting, field) {
+				if s, ok := weapon.(string); ok {
+					class.LevelModifiers = append(class.LevelModifiers, &schema.ModifierWeaponProficiency{Value: Key(refName(s))})
+				} else {
+					im.note("classes", key, "startingProficiencies.weapons", "not imported", weapon)
+				}
+			}
+		case "tools":
+			for _, tool := range getList(starting, field) {
+				if s, ok := tool.(string); ok && !strings.Contains(strings.ToLower(s), "choice") {
+					class.LevelModifiers = append(class.LevelModifiers, &schema.ModifierToolProficiency{Value: Key(refName(s))})
+				} else {
+					im.note("classes", key, "startingProficiencies.tools", "not imported", tool)
+				}
+			}
+		default:
+			im.note("classes", key, "startingProficiencies."+field, "not imported", starting[field])
+		}
+	}
+	if !reflect.DeepEqual(profs, schema.ClassProficiencies{}) {
+		class.Profs = &profs
+	}
+
+	if ability := getString(obj, "spellcastingAbility"); ability != "" {
+		class.Spellcasting = &schema.SpellcastingConfig{
+			SpellListKw: key,
+			Ability:     abilityNames[ability],
+			LevelFactor: casterProgressions[getString(obj, "casterProgression")],
+			KnownMode:   "all",
+		}
+		if class.Spellcasting.LevelFactor == 0 {
+			class.Spellcasting.LevelFactor = 1
+			im.note("classes", key, "casterProgression", "not imported, using full casting", obj["casterProgression"])
+		}
+		if getString(obj, "casterProgression") == "pact" {
+			im.note("classes", key, "casterProgression", "imported as full casting, OrcPub has no pact magic", nil)
+		}
+
+		if known := getList(obj, "spellsKnownProgression"); len(known) > 0 {
+			class.Spellcasting.KnownMode = "schedule"
+			class.Spellcasting.SpellsKnown = make(map[int]int)
+			previous := 0
+			for idx, value := range known {
+				n, _ := value.(float64)
+				if int(n) > previous {
+					class.Spellcasting.SpellsKnown[idx+1] = int(n) - previous
+				}
+				previous = int(n)
+			}
+		}
+	}
+
+	for _, value := range getList(obj, "classFeatures") {
+		ref, _ := value.(string)
+		gainsSubclass := false
+		if obj := asObject(value); obj != nil {
+			ref = getString(obj, "classFeature")
+			gainsSubclass = getBool(obj, "gainSubclassFeature")
+		}
+
+		// References are "name|class|classSource|level|source"
+		parts := strings.Split(ref, "|")
+		if len(parts) < 4 {
+			im.note("classes", key, "classFeatures", "not imported", value)
+			continue
+		}
+		featureName := parts[0]
+		level, _ := strconv.Atoi(parts[3])
+
+		switch {
+		case gainsSubclass:
+			if class.SubclassLevel == 0 {
+				class.SubclassLevel = level
+			}
+		case featureName == "Ability Score Improvement":
+			class.AbilityIncreaseLevels = append(class.AbilityIncreaseLevels, level)
+		default:
+			feature := findFeature(im.classFeatures, featureName, "className", parts[1], level)
+			if feature == nil {
+				im.note("classes", key, "classFeatures", "not imported, the feature isn't in the file", featureName)
+				continue
+			}
+			class.Traits = append(class.Traits, schema.LevelTrait{
+				Name:        StripTags(featureName),
+				Level:       level,
+				Description: Entries(getList(feature, "entries")),
+			})
+		}
+	}
+
+	if im.add("classes", key, class) {
+		im.unused("classes", key, obj, used...)
+	}
+}
+
+func (im *importer) subclass(obj object) {
+	name := StripTags(getString(obj, "name"))
+	key := Key(name)
+	className := getString(obj, "className")
+
+	subclass := schema.SubclassConfig{
+		Key:        key,
+		OptionPack: im.pack,
+		Name:       name,
+		Class:      Key(className),
+	}
+
+	for _, value := range getList(obj, "subclassFeatures") {
+		ref, _ := value.(string)
+		if obj := asObject(value); obj != nil {
+			ref = getString(obj, "subclassFeature")
+		}
+
+		// References are "name|class|classSource|subclassShortName|subclassSource|level"
+		parts := strings.Split(ref, "|")
+		if len(parts) < 6 {
+			im.note("subclasses", key, "subclassFeatures", "not imported", value)
+			continue
+		}
+		featureName := parts[0]
+		level, _ := strconv.Atoi(parts[5])
+
+		feature := findFeature(im.subclassFeatures, featureName, "subclassShortName", parts[3], level)
+		if feature == nil {
+			im.note("subclasses", key, "subclassFeatures", "not imported, the feature isn't in the file", featureName)
+			continue
+		}
+		subclass.Traits = append(subclass.Traits, schema.LevelTrait{
+			Name:        StripTags(featureName),
+			Level:       level,
+			Description: Entries(getList(feature, "entries")),
+		})
+
+		// The first feature introduces the subclass, and its entries refer to
+		// the other features
+		for _, entry := range getList(feature, "entries") {
+			if ref := getString(asObject(entry), "subclassFeature"); ref != "" && getString(asObject(entry), "type") == "refSubclassFeature" {
+				parts := strings.Split(ref, "|")
+				if len(parts) < 6 {
+					continue
+				}
+				level, _ := strconv.Atoi(parts[5])
+				if containsFeature(getList(obj, "subclassFeatures"), ref) {
+					continue
+				}
+				if inner := findFeature(im.subclassFeatures, parts[0], "subclassShortName", parts[3], level); inner != nil {
+					subclass.Traits = append(subclass.Traits, schema.LevelTrait{
+						Name:        StripTags(parts[0]),
+						Level:       level,
+						Description: Entries(getList(inner, "entries")),
+					})
+				}
+			}
+		}
+	}
+
+	if im.add("subclasses", key, subclass) {
+		im.unused("subclasses", key, obj, "name", "shortName", "className", "classSource", "subclassFeatures")
+	}
+}
+
+// findFeature returns the class or subclass feature with the given name and
+// level, belonging to the class or subclass named owner
+func findFeature(features []object, name string, ownerField string, owner string, level int) object {
+	for _, feature := range features {
+		if strings.EqualFold(getString(feature, "name"), name) &&
+			strings.EqualFold(getString(feature, ownerField), owner) &&
+			getInt(feature, "level") == level {
+			return feature
+		}
+	}
+	return nil
+}
+
+// containsFeature returns whether a list of feature references includes ref
+func containsFeature(refs []interface{}, ref string) bool {
+	for _, value := range refs {
+		if value == ref || getString(asObject(value), "subclassFeature") == ref {
+			return true
+		}
+	}
+	return false
+}
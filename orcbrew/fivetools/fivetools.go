@@ -0,0 +1,355 @@
+// Package fivetools imports homebrew written in the JSON format used by
+// 5etools, converting spells, monsters, feats, races, subraces, backgrounds,
+// classes and subclasses to the types in the schema package.
+//
+// OrcPub's model is simpler than 5etools', so some fields can't be imported,
+// or are only imported in part. These are listed as Notes, so they can be
+// added by hand.
+package fivetools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// DefaultPack is the name of the option pack when none is given and the file
+// doesn't name its source
+const DefaultPack = "5etools"
+
+// Note describes a field that couldn't be represented in OrcPub
+type Note struct {
+	Kind   string // the kind of entity, e.g. spells
+	Key    string // the key of the entity
+	Field  string // the 5etools field, e.g. senses
+	Reason string // what happened to it, e.g. "not imported"
+	Value  string // the value of the field as text, when it's short
+}
+
+func (n Note) String() string {
+	s := fmt.Sprintf("%s/%s: %s %s", n.Kind, n.Key, n.Field, n.Reason)
+	if n.Kind == "" {
+		s = fmt.Sprintf("%s: %s", n.Field, n.Reason)
+	}
+	if n.Value != "" {
+		s += fmt.Sprintf(" (%s)", n.Value)
+	}
+	return s
+}
+
+// object is a JSON object from a 5etools file
+type object = map[string]interface{}
+
+// importer holds the state of a single import
+type importer struct {
+	pack   string
+	source schema.OrcbrewSource
+	notes  []Note
+
+	// Features of classes and subclasses, which 5etools lists separately
+	classFeatures    []object
+	subclassFeatures []object
+}
+
+// metadata lists fields which only describe where an entity was published,
+// or which 5etools derives from the text for filtering, and so aren't worth
+// reporting when they aren't imported
+var metadata = []string{
+	"source", "page", "srd", "basicRules", "otherSources", "additionalSources",
+	"reprintedAs", "hasFluff", "hasFluffImages", "hasToken", "tokenUrl",
+	"soundClip", "altArt", "isReprinted", "_isCopy",
+
+	"damageInflict", "damageResist", "damageImmune", "damageVulnerable",
+	"conditionInflict", "savingThrow", "abilityCheck",
+	"affectsCreatureType", "miscTags", "areaTags", "scalingLevelDice",
+	"traitTags", "actionTags", "senseTags", "languageTags", "damageTags",
+	"damageTagsLegendary", "damageTagsSpell", "spellcastingTags",
+	"conditionInflictLegendary", "conditionInflictSpell", "attachedItems",
+	"savingThrowForced", "savingThrowForcedLegendary", "savingThrowForcedSpell",
+	"dragonCastingColor", "dragonAge", "familiar",
+}
+
+// Import reads a 5etools JSON file, returning its contents as an option pack.
+// When pack is empty, the option pack is named after the first source in the
+// file's _meta, or DefaultPack.
+func Import(r io.Reader, pack string) (schema.OrcbrewExportAll, []Note, error) {
+	var top object
+	if err := json.NewDecoder(r).Decode(&top); err != nil {
+		return nil, nil, err
+	}
+
+	if pack == "" {
+		pack = DefaultPack
+		if meta := getObject(top, "_meta"); meta != nil {
+			for _, source := range getList(meta, "sources") {
+				if name := getString(asObject(source), "full"); name != "" {
+					pack = name
+					break
+				}
+			}
+		}
+	}
+
+	im := &importer{pack: pack}
+	for _, feature := range getList(top, "classFeature") {
+		im.classFeatures = append(im.classFeatures, asObject(feature))
+	}
+	for _, feature := range getList(top, "subclassFeature") {
+		im.subclassFeatures = append(im.subclassFeatures, asObject(feature))
+	}
+
+	kinds := []struct {
+		field string
+		read  func(object)
+	}{
+		{"spell", im.spell},
+		{"monster", im.monster},
+		{"feat", im.feat},
+		{"race", im.race},
+		{"subrace", im.subrace},
+		{"background", im.background},
+		{"class", im.class},
+		{"subclass", im.subclass},
+	}
+	known := map[string]bool{"_meta": true, "classFeature": true, "subclassFeature": true}
+	for _, kind := range kinds {
+		known[kind.field] = true
+		for _, value := range getList(top, kind.field) {
+			if obj := asObject(value); obj != nil {
+				kind.read(obj)
+			}
+		}
+	}
+
+	var unknown []string
+	for field := range top {
+		if !known[field] {
+			unknown = append(unknown, field)
+		}
+	}
+	sort.Strings(unknown)
+	for _, field := range unknown {
+		count := fmt.Sprintf("%d entries", len(getList(top, field)))
+		if len(getList(top, field)) == 1 {
+			count = "1 entry"
+		}
+		im.notes = append(im.notes, Note{Field: field, Reason: "not imported", Value: count})
+	}
+
+	return schema.OrcbrewExportAll{pack: im.source}, im.notes, nil
+}
+
+// note records a field of an entity that couldn't be represented
+func (im *importer) note(kind string, key string, field string, reason string, value interface{}) {
+	text := ""
+	if value != nil {
+		text = plainValue(value)
+		if runes := []rune(text); len(runes) > 60 {
+			text = string(runes[:57]) + "..."
+		}
+	}
+	im.notes = append(im.notes, Note{Kind: kind, Key: key, Field: field, Reason: reason, Value: text})
+}
+
+// unused notes any fields of obj which weren't imported, other than metadata
+func (im *importer) unused(kind string, key string, obj object, used ...string) {
+	var fields []string
+	for field := range obj {
+		if !containsString(used, field) && !containsString(metadata, field) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		if field == "_copy" {
+			im.note(kind, key, field, "not supported, only the fields in the entity itself were imported", nil)
+			continue
+		}
+		im.note(kind, key, field, "not imported", obj[field])
+	}
+}
+
+// add adds an entity to the option pack, returning false when there's already
+// an entity of that kind with the same key
+func (im *importer) add(kind string, key string, entity interface{}) bool {
+	v := reflect.ValueOf(&im.source).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0] != kind {
+			continue
+		}
+
+		entities := v.Field(i)
+		if entities.IsNil() {
+			entities.Set(reflect.MakeMap(entities.Type()))
+		}
+		if entities.MapIndex(reflect.ValueOf(key)).IsValid() {
+			im.note(kind, key, "name", "is used by more than one entity, only the first was imported", nil)
+			return false
+		}
+		entities.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(entity))
+		return true
+	}
+	panic("unknown kind " + kind)
+}
+
+var nonKeyChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Key returns the key OrcPub uses for a name, e.g. "smiths-tools" for
+// "Smith's Tools"
+func Key(name string) string {
+	name = strings.ToLower(StripTags(name))
+	name = strings.NewReplacer("'", "", "’", "").Replace(name)
+	return strings.Trim(nonKeyChars.ReplaceAllString(name, "-"), "-")
+}
+
+// refName returns the name in a 5etools reference such as "longsword|phb" or
+// "hellish rebuke#2"
+func refName(ref string) string {
+	if idx := strings.IndexAny(ref, "|#"); idx >= 0 {
+		ref = ref[:idx]
+	}
+	return strings.TrimSpace(ref)
+}
+
+func getString(obj object, field string) string {
+	switch v := obj[field].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func getInt(obj object, field string) int {
+	switch v := obj[field].(type) {
+	case float64:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(strings.TrimPrefix(v, "+"))
+		return n
+	}
+	return 0
+}
+
+func getBool(obj object, field string) bool {
+	b, _ := obj[field].(bool)
+	return b
+}
+
+func getObject(obj object, field string) object {
+	return asObject(obj[field])
+}
+
+func asObject(value interface{}) object {
+	obj, _ := value.(map[string]interface{})
+	return obj
+}
+
+// getList returns a list field, treating a single value as a list of one
+func getList(obj object, field string) []interface{} {
+	switch v := obj[field].(type) {
+	case []interface{}:
+		return v
+	case nil:
+		return nil
+	default:
+		return []interface{}{v}
+	}
+}
+
+// getStrings returns the strings in a list field
+func getStrings(obj object, field string) []string {
+	var result []string
+	for _, value := range getList(obj, field) {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// sortedFields returns the fields of an object in order
+func sortedFields(obj object) []string {
+	var fields []string
+	for field := range obj {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// plainValue returns a short description of a JSON value for notes
+func plainValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return StripTags(s)
+	}
+	if list, ok := value.([]interface{}); ok {
+		var items []string
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				items = append(items, StripTags(s))
+			}
+		}
+		if len(items) == len(list) {
+			return strings.Join(items, ", ")
+		}
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+var abilityNames = map[string]schema.Ability{
+	"str": schema.Strength, "strength": schema.Strength,
+	"dex": schema.Dexterity, "dexterity": schema.Dexterity,
+	"con": schema.Constitution, "constitution": schema.Constitution,
+	"int": schema.Intelligence, "intelligence": schema.Intelligence,
+	"wis": schema.Wisdom, "wisdom": schema.Wisdom,
+	"cha": schema.Charisma, "charisma": schema.Charisma,
+}
+
+var abilityTitles = map[schema.Ability]string{
+	schema.Strength: "Strength", schema.Dexterity: "Dexterity", schema.Constitution: "Constitution",
+	schema.Intelligence: "Intelligence", schema.Wisdom: "Wisdom", schema.Charisma: "Charisma",
+}
+
+var sizes = map[string]schema.Size{
+	"T": schema.Tiny, "S": schema.Small, "M": schema.Medium,
+	"L": schema.Large, "H": schema.Huge, "G": schema.Gargantuan,
+}
+
+// size returns the first size in a 5etools size field
+func size(value interface{}) schema.Size {
+	switch v := value.(type) {
+	case string:
+		return sizes[v]
+	case []interface{}:
+		if len(v) > 0 {
+			return size(v[0])
+		}
+	}
+	return ""
+}
+
+// isValue returns whether s is one of the values of a schema enumeration,
+// such as schema.Damage
+func isValue(values interface{ Values() []string }, s string) bool {
+	return containsString(values.Values(), s)
+}
@@ -0,0 +1,278 @@
+package fivetools
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+func importHomebrew(t *testing.T, pack string) (schema.OrcbrewSource, []string) {
+	f, err := os.Open("testdata/homebrew.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	all, notes, err := Import(f, pack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pack == "" {
+		pack = "My Homebrew"
+	}
+	source, ok := all[pack]
+	if !ok || len(all) != 1 {
+		t.Fatalf("Expected a single option pack named %s, got %v", pack, all)
+	}
+
+	var lines []string
+	for _, note := range notes {
+		lines = append(lines, note.String())
+	}
+	return source, lines
+}
+
+func TestStripTags(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"{@spell fireball}", "fireball"},
+		{"{@spell fireball|phb}", "fireball"},
+		{"{@creature goblin|mm|goblins}", "goblins"},
+		{"{@damage 2d6} fire", "2d6 fire"},
+		{"{@atk mw,rw} {@hit 5} to hit. {@h}{@damage 1d8}", "Melee or Ranged Weapon Attack: +5 to hit. Hit: 1d8"},
+		{"{@dc 15}", "DC 15"},
+		{"{@recharge 5}", "(Recharge 5–6)"},
+		{"{@recharge}", "(Recharge 6)"},
+		{"{@b bold {@i and {@spell light}}}", "bold and light"},
+		{"{@chance 25}", "25 percent"},
+		{"{@filter spells|spells|level=1}", "spells"},
+		{"no tags", "no tags"},
+		{"{@broken", "{@broken"},
+	}
+
+	for _, test := range tests {
+		if got := StripTags(test.text); got != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.text, test.expected, got)
+		}
+	}
+}
+
+func TestEntries(t *testing.T) {
+	entries := []interface{}{
+		"First {@b paragraph}.",
+		map[string]interface{}{"type": "entries", "name": "Named.", "entries": []interface{}{"Section text."}},
+		map[string]interface{}{"type": "list", "items": []interface{}{"one", map[string]interface{}{"type": "item", "name": "Two", "entry": "text"}}},
+		map[string]interface{}{"type": "table", "colLabels": []interface{}{"d4", "Effect"}, "rows": []interface{}{
+			[]interface{}{"1", "Nothing"},
+			[]interface{}{map[string]interface{}{"type": "cell", "roll": map[string]interface{}{"min": 2.0, "max": 4.0}}, "{@condition Prone}"},
+		}},
+		map[string]interface{}{"type": "abilityDc", "name": "Spell", "attributes": []interface{}{"int"}},
+		map[string]interface{}{"type": "image"},
+	}
+
+	expected := "First paragraph.\n\nNamed. Section text.\n\n• one\n• Two. text\n\nd4 | Effect\n1 | Nothing\n2–4 | Prone\n\n" +
+		"Spell save DC = 8 + your proficiency bonus + your Intelligence modifier"
+	if got := Entries(entries); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func TestKey(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Smith's Tools":   "smiths-tools",
+		"Fire Bolt":       "fire-bolt",
+		"sleight of hand": "sleight-of-hand",
+		"  Odd -- Name! ": "odd-name",
+	} {
+		if got := Key(name); got != expected {
+			t.Errorf("%q: expected %q, got %q", name, expected, got)
+		}
+	}
+}
+
+func TestImportSpells(t *testing.T) {
+	source, _ := importHomebrew(t, "")
+
+	expected := schema.SpellConfig{
+		Key: "ember-lance", OptionPack: "My Homebrew", Name: "Ember Lance", Level: 2,
+		School: "evocation", CastingTime: "1 bonus action", Range: "60 feet",
+		Duration: "Concentration, up to 1 minute", AttackRoll: true,
+		Components: &schema.SpellComponents{Verbal: true, Somatic: true, Material: true, MaterialComponent: "a coal worth at least 10 gp"},
+		SpellLists: map[string]bool{"sorcerer": true, "wizard": true},
+		Description: "You hurl a lance of embers. Make a ranged spell attack; on a hit the target takes 3d6 fire damage " +
+			"and must succeed on a DC 13 Constitution saving throw or be blinded.\n\n" +
+			"• The lance ignites flasks of oil.\n• It sheds light.\n\n" +
+			"At Higher Levels. The damage increases by 1d6 for each slot level above 2nd.",
+	}
+	if diff := deep.Equal(source.Spells["ember-lance"], expected); diff != nil {
+		t.Error(diff)
+	}
+
+	spell := source.Spells["cone-of-whispers"]
+	if spell.Range != "Self (15-foot cone)" || spell.Duration != "Instantaneous" || !spell.Ritual {
+		t.Errorf("Unexpected spell %+v", spell)
+	}
+}
+
+func TestImportMonsters(t *testing.T) {
+	source, _ := importHomebrew(t, "")
+
+	monster := source.Monsters["ash-goblin"]
+	if monster.Type != "humanoid (goblinoid)" || monster.Alignment != "neutral evil" || monster.Size != schema.Small ||
+		monster.ArmorClass != 15 || monster.Challenge != 0.25 || monster.Speed != "30 ft., fly 20 ft. (clumsy) (hover)" {
+		t.Errorf("Unexpected monster %+v", monster)
+	}
+	if *monster.HitPoints != (schema.HitDieCount{DieCount: 2, Die: 6}) {
+		t.Errorf("Unexpected hit points %+v", monster.HitPoints)
+	}
+	if diff := deep.Equal(monster.Skills, map[schema.Skill]int{schema.Stealth: 6, schema.SleightOfHand: 4}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(monster.Props.Language, map[string]bool{"common": true, "goblin": true}); diff != nil {
+		t.Error(diff)
+	}
+
+	var names []string
+	for _, trait := range monster.Traits {
+		names = append(names, string(trait.Type)+":"+trait.Name)
+	}
+	expected := []string{":Nimble Escape", ":Innate Spellcasting", "action:Scimitar", "action:Duck (Reaction)", "legendary-action:Scurry"}
+	if diff := deep.Equal(names, expected); diff != nil {
+		t.Error(diff)
+	}
+	if description := monster.Traits[1].Description; !strings.Contains(description, "1/day each: burning hands, ember lance") {
+		t.Errorf("Unexpected spellcasting %q", description)
+	}
+	if monster.Traits[2].Description != "Melee Weapon Attack: +4 to hit, reach 5 ft., one target. Hit: 5 (1d6 + 2) slashing damage." {
+		t.Errorf("Unexpected action %q", monster.Traits[2].Description)
+	}
+}
+
+func TestImportCharacterOptions(t *testing.T) {
+	source, _ := importHomebrew(t, "Renamed")
+
+	feat := source.Feats["ember-adept"]
+	if diff := deep.Equal(feat.AbilityIncreases, []string{"int", "wis", "cha"}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(feat.Prereqs, []string{"int", "spellcasting"}); diff != nil {
+		t.Error(diff)
+	}
+
+	race := source.Races["cinderkin"]
+	if race.OptionPack != "Renamed" || race.Speed != 30 || race.Darkvision != 60 || race.Size != schema.Medium {
+		t.Errorf("Unexpected race %+v", race)
+	}
+	expectedSpells := []schema.RaceSpellConfig{
+		{Value: schema.SpellWithAbilityLevel{Key: "burning-hands", Ability: schema.Charisma, Level: 3}},
+		{Value: schema.SpellWithAbilityLevel{Key: "produce-flame", Ability: schema.Charisma}},
+	}
+	if diff := deep.Equal(race.Spells, expectedSpells); diff != nil {
+		t.Error(diff)
+	}
+	if !race.Props.WeaponProficiency["light-hammer"] || !race.Profs.ToolOptions["smiths-tools"] || !race.Props.DamageResistance[schema.Fire] {
+		t.Errorf("Unexpected race proficiencies %+v %+v", race.Props, race.Profs)
+	}
+
+	subrace := source.Subraces["ashen-cinderkin"]
+	if subrace.Race != "cinderkin" || subrace.Name != "Ashen Cinderkin" || subrace.Abilities[schema.Wisdom] != 1 {
+		t.Errorf("Unexpected subrace %+v", subrace)
+	}
+
+	background := source.Backgrounds["forge-acolyte"]
+	if background.Profs.LanguageOptions.Choose != 1 || background.Profs.ToolOptions.GamingSet != 1 || len(background.Traits) != 1 {
+		t.Errorf("Unexpected background %+v", background)
+	}
+
+	class := source.Classes["flamecaller"]
+	if class.HitDie != 8 || class.SubclassLevel != 2 || class.Spellcasting.SpellsKnown[2] != 1 {
+		t.Errorf("Unexpected class %+v", class)
+	}
+	if diff := deep.Equal(class.AbilityIncreaseLevels, []int{4}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(class.Traits, []schema.LevelTrait{{Name: "Kindle", Level: 1, Description: "You can light candles."}}); diff != nil {
+		t.Error(diff)
+	}
+
+	subclass := source.Subclasses["hearth-flame"]
+	if subclass.Class != "flamecaller" || len(subclass.Traits) != 2 || subclass.Traits[1].Name != "Warmth" {
+		t.Errorf("Unexpected subclass %+v", subclass)
+	}
+}
+
+func TestImportNotes(t *testing.T) {
+	_, notes := importHomebrew(t, "")
+
+	expected := []string{
+		"spells/ember-lance: components.r not imported, OrcPub has no royalty component",
+		"monsters/ash-goblin: ac.from not imported, OrcPub only has the armor class (leather armor, shield)",
+		"monsters/ash-goblin: resist imported without the conditions on it (bludgeoning, piercing from nonmagical attacks)",
+		"monsters/ash-goblin: languages not imported (telepathy 30 ft.)",
+		"monsters/ash-goblin: reaction imported as actions, as OrcPub has no reactions",
+		"monsters/ash-goblin: senses not imported (darkvision 60 ft.)",
+		"feats/ember-adept: prerequisite.level not imported (4)",
+		"races/cinderkin: speed.swim not imported (20)",
+		"backgrounds/forge-acolyte: startingEquipment not imported",
+		"classes/flamecaller: classFeatures not imported, the feature isn't in the file (Wildfire)",
+		"classes/flamecaller: multiclassing not imported",
+		"item: not imported (1 entry)",
+	}
+	for _, note := range expected {
+		found := false
+		for _, line := range notes {
+			if strings.HasPrefix(line, note) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected a note %q in:\n%s", note, strings.Join(notes, "\n"))
+		}
+	}
+
+	for _, line := range notes {
+		if strings.Contains(line, "damageInflict") || strings.Contains(line, "source") || strings.Contains(line, "areaTags") {
+			t.Errorf("Unexpected note for metadata: %s", line)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	if _, _, err := Import(strings.NewReader("{not json"), ""); err == nil {
+		t.Errorf("Expected an error for invalid JSON")
+	}
+
+	all, _, err := Import(strings.NewReader(`{"spell": [{"name": "A"}, {"name": "a"}]}`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all[DefaultPack].Spells) != 1 {
+		t.Errorf("Expected duplicate keys to be skipped, got %v", all)
+	}
+}
+
+func TestAlignment(t *testing.T) {
+	tests := []struct {
+		codes    []interface{}
+		expected string
+	}{
+		{[]interface{}{"L", "G"}, "lawful good"},
+		{[]interface{}{"N"}, "neutral"},
+		{[]interface{}{"U"}, "unaligned"},
+		{[]interface{}{"A"}, "any alignment"},
+		{[]interface{}{"L", "NX", "C", "E"}, "any evil alignment"},
+		{[]interface{}{"L", "NX", "C", "NY", "E"}, "any non-good alignment"},
+		{[]interface{}{map[string]interface{}{"alignment": []interface{}{"C", "G"}}, map[string]interface{}{"alignment": []interface{}{"N", "E"}}}, "chaotic good or neutral evil"},
+	}
+
+	for _, test := range tests {
+		if got := alignment(test.codes); got != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.codes, test.expected, got)
+		}
+	}
+}
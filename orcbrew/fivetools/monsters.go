@@ -0,0 +1,412 @@
+package fivetools
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var alignments = map[string]string{
+	"L": "lawful", "N": "neutral", "NX": "neutral", "NY": "neutral", "C": "chaotic",
+	"G": "good", "E": "evil", "U": "unaligned", "A": "any alignment",
+}
+
+var dicePattern = regexp.MustCompile(`^\s*(\d+)d(\d+)`)
+
+// languagePattern matches languages that can be imported as keys, rather
+// than text such as "understands Common but can't speak"
+var languagePattern = regexp.MustCompile(`^[A-Z][A-Za-z' -]*$`)
+
+func (im *importer) monster(obj object) {
+	name := StripTags(getString(obj, "name"))
+	key := Key(name)
+
+	monster := schema.MonsterConfig{
+		Key:        key,
+		OptionPack: im.pack,
+		Name:       name,
+		Str:        getInt(obj, "str"),
+		Dex:        getInt(obj, "dex"),
+		Con:        getInt(obj, "con"),
+		Int:        getInt(obj, "int"),
+		Wis:        getInt(obj, "wis"),
+		Cha:        getInt(obj, "cha"),
+		Size:       size(obj["size"]),
+		Type:       creatureType(obj["type"]),
+		Alignment:  alignment(getList(obj, "alignment")),
+		Speed:      speed(getObject(obj, "speed")),
+	}
+	used := []string{"name", "str", "dex", "con", "int", "wis", "cha", "size", "type", "alignment", "speed"}
+
+	if fluff := getObject(obj, "fluff"); fluff != nil {
+		monster.Description = Entries(getList(fluff, "entries"))
+		used = append(used, "fluff")
+	}
+
+	if ac := getList(obj, "ac"); len(ac) > 0 {
+		used = append(used, "ac")
+		switch v := ac[0].(type) {
+		case float64:
+			monster.ArmorClass = int(v)
+		case map[string]interface{}:
+			monster.ArmorClass = getInt(v, "ac")
+			if from := getList(v, "from"); len(from) > 0 {
+				im.note("monsters", key, "ac.from", "not imported, OrcPub only has the armor class", from)
+			}
+		}
+		if len(ac) > 1 {
+			im.note("monsters", key, "ac", "has several values, only the first was imported", ac)
+		}
+	}
+
+	if hp := getObject(obj, "hp"); hp != nil {
+		used = append(used, "hp")
+		if match := dicePattern.FindStringSubmatch(getString(hp, "formula")); match != nil {
+			count, _ := strconv.Atoi(match[1])
+			die, _ := strconv.Atoi(match[2])
+			monster.HitPoints = &schema.HitDieCount{DieCount: count, Die: die}
+		} else {
+			im.note("monsters", key, "hp", "not imported, OrcPub needs hit dice", hp)
+		}
+	}
+
+	if cr := obj["cr"]; cr != nil {
+		used = append(used, "cr")
+		if crObj := asObject(cr); crObj != nil {
+			cr = crObj["cr"]
+			if crObj["lair"] != nil || crObj["coven"] != nil {
+				im.note("monsters", key, "cr", "only the base challenge rating was imported", crObj)
+			}
+		}
+		monster.Challenge = float32(challenge(fmt.Sprint(cr)))
+	}
+
+	if saves := getObject(obj, "save"); saves != nil {
+		used = append(used, "save")
+		monster.SavingThrows = make(map[schema.Ability]int)
+		for _, field := range sortedFields(saves) {
+			monster.SavingThrows[abilityNames[field]] = getInt(saves, field)
+		}
+	}
+
+	if skills := getObject(obj, "skill"); skills != nil {
+		used = append(used, "skill")
+		monster.Skills = make(map[schema.Skill]int)
+		for _, field := range sortedFields(skills) {
+			if skill := schema.Skill(Key(field)); isValue(skill, string(skill)) {
+				monster.Skills[skill] = getInt(skills, field)
+			} else {
+				im.note("monsters", key, "skill."+field, "not imported", skills[field])
+			}
+		}
+	}
+
+	props := &schema.MonsterProperties{}
+	for _, damage := range []struct {
+		field string
+		set   *map[schema.Damage]bool
+	}{
+		{"resist", &props.DamageResistance},
+		{"immune", &props.DamageImmunity},
+		{"vulnerable", &props.DamageVulnerability},
+	} {
+		values := im.damageList(key, obj, damage.field)
+		if len(values) > 0 {
+			*damage.set = make(map[schema.Damage]bool)
+			for _, value := range values {
+				(*damage.set)[schema.Damage(value)] = true
+			}
+		}
+		used = append(used, damage.field)
+	}
+	if values := im.damageList(key, obj, "conditionImmune"); len(values) > 0 {
+		props.ConditionImmunity = make(map[schema.Condition]bool)
+		for _, value := range values {
+			props.ConditionImmunity[schema.Condition(value)] = true
+		}
+	}
+	used = append(used, "conditionImmune")
+
+	for _, language := range getStrings(obj, "languages") {
+		if language = StripTags(language); languagePattern.MatchString(language) {
+			if props.Language == nil {
+				props.Language = make(map[string]bool)
+			}
+			props.Language[Key(language)] = true
+		} else {
+			im.note("monsters", key, "languages", "not imported", language)
+		}
+	}
+	used = append(used, "languages")
+
+	if !reflect.DeepEqual(*props, schema.MonsterProperties{}) {
+		monster.Props = props
+	}
+
+	for _, traits := range []struct {
+		field      string
+		traitType  schema.MonsterTraitAction
+		nameSuffix string
+	}{
+		{"trait", "", ""},
+		{"action", schema.MonsterTraitActionAction, ""},
+		{"bonus", schema.MonsterTraitActionAction, " (Bonus Action)"},
+		{"reaction", schema.MonsterTraitActionAction, " (Reaction)"},
+		{"legendary", schema.MonsterTraitActionLegendaryAction, ""},
+	} {
+		for _, trait := range getList(obj, traits.field) {
+			trait := asObject(trait)
+			monster.Traits = append(monster.Traits, schema.MonsterTrait{
+				Type:        traits.traitType,
+				Name:        StripTags(getString(trait, "name")) + traits.nameSuffix,
+				Description: Entries(getList(trait, "entries")),
+			})
+		}
+		if traits.field == "trait" {
+			monster.Traits = append(monster.Traits, spellcasting(obj)...)
+		}
+		if traits.nameSuffix != "" && obj[traits.field] != nil {
+			im.note("monsters", key, traits.field, "imported as actions, as OrcPub has no "+strings.ToLower(strings.Trim(traits.nameSuffix, " ()"))+"s", nil)
+		}
+		used = append(used, traits.field)
+	}
+
+	if header := getList(obj, "legendaryHeader"); len(header) > 0 {
+		monster.LegendaryActions = &schema.LegendaryActionDescription{Description: Entries(header)}
+	}
+	used = append(used, "legendaryHeader", "spellcasting")
+
+	if im.add("monsters", key, monster) {
+		im.unused("monsters", key, obj, used...)
+	}
+}
+
+// spellcasting converts a monster's spellcasting to traits
+func spellcasting(obj object) []schema.MonsterTrait {
+	var traits []schema.MonsterTrait
+	for _, value := range getList(obj, "spellcasting") {
+		spellcasting := asObject(value)
+		paragraphs := []interface{}{}
+		paragraphs = append(paragraphs, getList(spellcasting, "headerEntries")...)
+
+		var lines []string
+		if will := getStrings(spellcasting, "will"); len(will) > 0 {
+			lines = append(lines, "At will: "+spellList(will))
+		}
+		for _, perDay := range []struct{ field, text string }{{"daily", "day"}, {"rest", "rest"}, {"weekly", "week"}} {
+			uses := getObject(spellcasting, perDay.field)
+			for _, field := range sortedFields(uses) {
+				text := fmt.Sprintf("%s/%s", strings.TrimSuffix(field, "e"), perDay.text)
+				if strings.HasSuffix(field, "e") {
+					text += " each"
+				}
+				lines = append(lines, text+": "+spellList(getStrings(uses, field)))
+			}
+		}
+
+		levels := getObject(spellcasting, "spells")
+		var keys []int
+		for _, field := range sortedFields(levels) {
+			level, _ := strconv.Atoi(field)
+			keys = append(keys, level)
+		}
+		sort.Ints(keys)
+		for _, level := range keys {
+			spells := getObject(levels, strconv.Itoa(level))
+			text := "Cantrips (at will)"
+			if level > 0 {
+				text = fmt.Sprintf("%s level", ordinal(level))
+				if slots := getInt(spells, "slots"); slots > 0 {
+					text += fmt.Sprintf(" (%d %s)", slots, plural("slot", slots))
+				}
+			}
+			lines = append(lines, text+": "+spellList(getStrings(spells, "spells")))
+		}
+
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+		}
+		paragraphs = append(paragraphs, getList(spellcasting, "footerEntries")...)
+
+		name := StripTags(getString(spellcasting, "name"))
+		if name == "" {
+			name = "Spellcasting"
+		}
+		trait := schema.MonsterTrait{Name: name, Description: Entries(paragraphs)}
+		if getString(spellcasting, "displayAs") == "action" {
+			trait.Type = schema.MonsterTraitActionAction
+		}
+		traits = append(traits, trait)
+	}
+	return traits
+}
+
+func spellList(spells []string) string {
+	for idx := range spells {
+		spells[idx] = StripTags(spells[idx])
+	}
+	return strings.Join(spells, ", ")
+}
+
+// damageList returns the damage types or conditions in a monster's resist,
+// immune, vulnerable or conditionImmune field, noting any conditions on them
+func (im *importer) damageList(key string, obj object, field string) []string {
+	var values []string
+	for _, value := range getList(obj, field) {
+		switch v := value.(type) {
+		case string:
+			values = append(values, v)
+		case map[string]interface{}:
+			if special := getString(v, "special"); special != "" {
+				im.note("monsters", key, field, "not imported", special)
+				continue
+			}
+			inner := im.damageList(key, v, field)
+			values = append(values, inner...)
+			note := strings.TrimSpace(getString(v, "preNote") + " " + strings.Join(inner, ", ") + " " + getString(v, "note"))
+			im.note("monsters", key, field, "imported without the conditions on it", note)
+		}
+	}
+	return values
+}
+
+// creatureType returns a monster's type, e.g. "humanoid (goblinoid)"
+func creatureType(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	obj := asObject(value)
+	text := getString(obj, "type")
+	if swarm := getString(obj, "swarmSize"); swarm != "" {
+		text = fmt.Sprintf("swarm of %s %ss", strings.Title(string(sizes[swarm])), text)
+	}
+
+	var tags []string
+	for _, tag := range getList(obj, "tags") {
+		if s, ok := tag.(string); ok {
+			tags = append(tags, s)
+		} else {
+			tags = append(tags, strings.TrimSpace(getString(asObject(tag), "prefix")+" "+getString(asObject(tag), "tag")))
+		}
+	}
+	if len(tags) > 0 {
+		text += " (" + strings.Join(tags, ", ") + ")"
+	}
+	return text
+}
+
+// alignment returns a monster's alignment, e.g. "chaotic evil" or "any
+// non-good alignment"
+func alignment(values []interface{}) string {
+	var codes, choices []string
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			codes = append(codes, v)
+		case map[string]interface{}:
+			if special := getString(v, "special"); special != "" {
+				choices = append(choices, special)
+			} else {
+				choices = append(choices, alignment(getList(v, "alignment")))
+			}
+		}
+	}
+	if len(choices) > 0 {
+		return strings.Join(choices, " or ")
+	}
+
+	switch len(codes) {
+	case 0:
+		return ""
+	case 1:
+		return alignments[codes[0]]
+	case 2:
+		if codes[0] == "N" && codes[1] == "N" {
+			return "neutral"
+		}
+		return alignments[codes[0]] + " " + alignments[codes[1]]
+	}
+
+	has := func(code string) bool { return containsString(codes, code) }
+	if len(codes) == 5 {
+		for _, code := range []string{"G", "E", "L", "C"} {
+			if !has(code) {
+				return "any non-" + alignments[code] + " alignment"
+			}
+		}
+	}
+	switch {
+	case has("G") && !has("E"):
+		return "any good alignment"
+	case has("E") && !has("G"):
+		return "any evil alignment"
+	case has("C") && !has("L"):
+		return "any chaotic alignment"
+	case has("L") && !has("C"):
+		return "any lawful alignment"
+	}
+	return "any alignment"
+}
+
+// speed returns a monster's speeds, e.g. "30 ft., fly 60 ft. (hover)"
+func speed(obj object) string {
+	var speeds []string
+	for _, mode := range []string{"walk", "burrow", "climb", "fly", "swim"} {
+		if obj[mode] == nil {
+			continue
+		}
+
+		var text string
+		if inner := getObject(obj, mode); inner != nil {
+			text = fmt.Sprintf("%d ft.", getInt(inner, "number"))
+			if condition := getString(inner, "condition"); condition != "" {
+				text += " " + StripTags(condition)
+			}
+		} else if getBool(obj, mode) {
+			text = "equal to walking speed"
+		} else {
+			text = fmt.Sprintf("%d ft.", getInt(obj, mode))
+		}
+		if mode == "fly" && getBool(obj, "canHover") {
+			text += " (hover)"
+		}
+		if mode != "walk" {
+			text = mode + " " + text
+		}
+		speeds = append(speeds, text)
+	}
+	return strings.Join(speeds, ", ")
+}
+
+// challenge returns a challenge rating as a number, e.g. 0.25 for "1/4"
+func challenge(cr string) float64 {
+	if parts := strings.Split(cr, "/"); len(parts) == 2 {
+		numerator, _ := strconv.ParseFloat(parts[0], 64)
+		denominator, _ := strconv.ParseFloat(parts[1], 64)
+		if denominator != 0 {
+			return numerator / denominator
+		}
+	}
+	n, _ := strconv.ParseFloat(cr, 64)
+	return n
+}
+
+func ordinal(n int) string {
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+		return fmt.Sprintf("%dth", n)
+	case n%10 == 1:
+		return fmt.Sprintf("%dst", n)
+	case n%10 == 2:
+		return fmt.Sprintf("%dnd", n)
+	case n%10 == 3:
+		return fmt.Sprintf("%drd", n)
+	}
+	return fmt.Sprintf("%dth", n)
+}
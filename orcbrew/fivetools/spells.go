@@ -0,0 +1,167 @@
+package fivetools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+var schools = map[string]string{
+	"A": "abjuration", "C": "conjuration", "D": "divination", "E": "enchantment",
+	"V": "evocation", "I": "illusion", "N": "necromancy", "T": "transmutation",
+}
+
+var timeUnits = map[string]string{
+	"action": "action", "bonus": "bonus action", "reaction": "reaction",
+	"round": "round", "minute": "minute", "hour": "hour", "day": "day",
+}
+
+func (im *importer) spell(obj object) {
+	name := StripTags(getString(obj, "name"))
+	key := Key(name)
+
+	spell := schema.SpellConfig{
+		Key:         key,
+		OptionPack:  im.pack,
+		Name:        name,
+		Level:       getInt(obj, "level"),
+		School:      schools[getString(obj, "school")],
+		CastingTime: castingTime(obj),
+		Range:       spellRange(getObject(obj, "range")),
+		Duration:    duration(obj),
+		Ritual:      getBool(getObject(obj, "meta"), "ritual"),
+		AttackRoll:  len(getList(obj, "spellAttack")) > 0,
+		Description: Entries(append(getList(obj, "entries"), getList(obj, "entriesHigherLevel")...)),
+	}
+	if spell.School == "" && getString(obj, "school") != "" {
+		im.note("spells", key, "school", "isn't a school OrcPub knows", obj["school"])
+	}
+
+	if components := getObject(obj, "components"); components != nil {
+		spell.Components = &schema.SpellComponents{
+			Verbal:  getBool(components, "v"),
+			Somatic: getBool(components, "s"),
+		}
+		switch m := components["m"].(type) {
+		case bool:
+			spell.Components.Material = m
+		case string:
+			spell.Components.Material = true
+			spell.Components.MaterialComponent = StripTags(m)
+		case map[string]interface{}:
+			spell.Components.Material = true
+			spell.Components.MaterialComponent = StripTags(getString(m, "text"))
+		}
+		if components["r"] != nil {
+			im.note("spells", key, "components.r", "not imported, OrcPub has no royalty component", nil)
+		}
+	}
+
+	for _, class := range getList(getObject(obj, "classes"), "fromClassList") {
+		if className := getString(asObject(class), "name"); className != "" {
+			if spell.SpellLists == nil {
+				spell.SpellLists = make(map[string]bool)
+			}
+			spell.SpellLists[Key(className)] = true
+		}
+	}
+
+	if im.add("spells", key, spell) {
+		im.unused("spells", key, obj, "name", "level", "school", "time", "range",
+			"duration", "meta", "spellAttack", "entries", "entriesHigherLevel",
+			"components", "classes")
+	}
+}
+
+// castingTime returns the casting time of a spell, e.g. "1 bonus action"
+func castingTime(obj object) string {
+	var times []string
+	for _, t := range getList(obj, "time") {
+		t := asObject(t)
+		number := getInt(t, "number")
+		text := fmt.Sprintf("%d %s", number, plural(timeUnits[getString(t, "unit")], number))
+		if condition := getString(t, "condition"); condition != "" {
+			text += ", " + StripTags(condition)
+		}
+		times = append(times, text)
+	}
+	return strings.Join(times, " or ")
+}
+
+// spellRange returns the range of a spell, e.g. "Self (15-foot cone)"
+func spellRange(obj object) string {
+	distance := getObject(obj, "distance")
+	amount := getInt(distance, "amount")
+
+	switch rangeType := getString(obj, "type"); rangeType {
+	case "point":
+		switch unit := getString(distance, "type"); unit {
+		case "self", "touch", "sight", "unlimited":
+			return strings.Title(unit)
+		case "feet":
+			return fmt.Sprintf("%d feet", amount)
+		default:
+			return fmt.Sprintf("%d %s", amount, plural(strings.TrimSuffix(unit, "s"), amount))
+		}
+	case "radius", "sphere", "cone", "line", "cube", "hemisphere", "cylinder":
+		return fmt.Sprintf("Self (%d-%s %s)", amount, footOrMile(getString(distance, "type")), rangeType)
+	case "special":
+		return "Special"
+	}
+	return ""
+}
+
+// footOrMile returns the unit used before an area, as in "15-foot cone"
+func footOrMile(unit string) string {
+	if unit == "miles" {
+		return "mile"
+	}
+	return "foot"
+}
+
+// duration returns the duration of a spell, e.g. "Concentration, up to 1
+// minute"
+func duration(obj object) string {
+	var durations []string
+	for _, d := range getList(obj, "duration") {
+		d := asObject(d)
+		switch getString(d, "type") {
+		case "instant":
+			durations = append(durations, "Instantaneous")
+		case "timed":
+			inner := getObject(d, "duration")
+			amount := getInt(inner, "amount")
+			text := fmt.Sprintf("%d %s", amount, plural(getString(inner, "type"), amount))
+			if getBool(d, "concentration") {
+				text = "Concentration, up to " + text
+			} else if getBool(inner, "upTo") {
+				text = "Up to " + text
+			}
+			durations = append(durations, text)
+		case "permanent":
+			ends := getStrings(d, "ends")
+			switch {
+			case containsString(ends, "dispel") && containsString(ends, "trigger"):
+				durations = append(durations, "Until dispelled or triggered")
+			case containsString(ends, "dispel"):
+				durations = append(durations, "Until dispelled")
+			case containsString(ends, "trigger"):
+				durations = append(durations, "Until triggered")
+			default:
+				durations = append(durations, "Permanent")
+			}
+		case "special":
+			durations = append(durations, "Special")
+		}
+	}
+	return strings.Join(durations, " or ")
+}
+
+// plural returns a unit such as "minute" in the plural when n isn't 1
+func plural(unit string, n int) string {
+	if n == 1 || unit == "" {
+		return unit
+	}
+	return unit + "s"
+}
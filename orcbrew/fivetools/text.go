@@ -0,0 +1,208 @@
+package fivetools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tagPattern matches a 5etools tag without tags inside it, such as
+// {@spell fireball|phb}
+var tagPattern = regexp.MustCompile(`\{@(\w+)\s*([^{}]*)\}`)
+
+var attackTypes = map[string]string{
+	"mw":    "Melee Weapon Attack:",
+	"rw":    "Ranged Weapon Attack:",
+	"ms":    "Melee Spell Attack:",
+	"rs":    "Ranged Spell Attack:",
+	"mw,rw": "Melee or Ranged Weapon Attack:",
+	"ms,rs": "Melee or Ranged Spell Attack:",
+	"m":     "Melee Attack:",
+	"r":     "Ranged Attack:",
+}
+
+// StripTags converts the tags in 5etools text to plain text, e.g.
+// "{@damage 2d6} fire damage" to "2d6 fire damage" and
+// "{@creature goblin|mm|goblins}" to "goblins"
+func StripTags(text string) string {
+	// Tags can contain other tags, so replace the innermost first
+	for strings.Contains(text, "{@") {
+		replaced := tagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+			match := tagPattern.FindStringSubmatch(tag)
+			return tagText(match[1], match[2])
+		})
+		if replaced == text {
+			break
+		}
+		text = replaced
+	}
+	return text
+}
+
+// tagText returns the plain text for a single tag
+func tagText(tag string, text string) string {
+	parts := strings.Split(text, "|")
+	for idx := range parts {
+		parts[idx] = strings.TrimSpace(parts[idx])
+	}
+
+	switch tag {
+	case "h":
+		return "Hit: "
+	case "m":
+		return "Miss: "
+	case "hit":
+		if strings.HasPrefix(parts[0], "-") {
+			return parts[0]
+		}
+		return "+" + parts[0]
+	case "d20":
+		if strings.HasPrefix(parts[0], "-") {
+			return parts[0]
+		}
+		return "+" + parts[0]
+	case "atk":
+		if name, ok := attackTypes[parts[0]]; ok {
+			return name
+		}
+		return "Attack:"
+	case "dc":
+		return "DC " + parts[0]
+	case "recharge":
+		if parts[0] == "" || parts[0] == "6" {
+			return "(Recharge 6)"
+		}
+		return fmt.Sprintf("(Recharge %s–6)", parts[0])
+	case "chance":
+		if len(parts) > 1 && parts[1] != "" {
+			return parts[1]
+		}
+		return parts[0] + " percent"
+	case "scaledice", "scaledamage":
+		return parts[len(parts)-1]
+	case "note", "b", "bold", "i", "italic", "s", "strike", "u", "underline",
+		"dice", "damage", "skill", "sense", "action", "filter", "link",
+		"5etools", "footnote", "homebrew", "area", "code", "style", "font",
+		"highlight", "help", "color", "sup", "sub", "kbd", "tip":
+		return parts[0]
+	}
+
+	// Tags linking to other entities show the name, or the display text when
+	// there is one
+	if len(parts) > 2 && parts[2] != "" {
+		return parts[2]
+	}
+	return parts[0]
+}
+
+// Entries converts 5etools entries, which may be text, lists, tables or named
+// sections, to plain text with paragraphs separated by blank lines
+func Entries(entries []interface{}) string {
+	var paragraphs []string
+	for _, entry := range entries {
+		if text := entryText(entry); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// entryText converts a single entry to plain text
+func entryText(entry interface{}) string {
+	if s, ok := entry.(string); ok {
+		return strings.TrimSpace(StripTags(s))
+	}
+
+	obj := asObject(entry)
+	if obj == nil {
+		return ""
+	}
+
+	switch getString(obj, "type") {
+	case "list":
+		var items []string
+		for _, item := range getList(obj, "items") {
+			if text := entryText(item); text != "" {
+				items = append(items, "• "+strings.Replace(text, "\n\n", "\n", -1))
+			}
+		}
+		return strings.Join(items, "\n")
+
+	case "table":
+		var rows []string
+		if caption := getString(obj, "caption"); caption != "" {
+			rows = append(rows, StripTags(caption))
+		}
+		if labels := getList(obj, "colLabels"); len(labels) > 0 {
+			rows = append(rows, tableRow(labels))
+		}
+		for _, row := range getList(obj, "rows") {
+			if cells, ok := row.([]interface{}); ok {
+				rows = append(rows, tableRow(cells))
+			} else if cells := getList(asObject(row), "row"); cells != nil {
+				rows = append(rows, tableRow(cells))
+			}
+		}
+		return strings.Join(rows, "\n")
+
+	case "quote":
+		text := Entries(getList(obj, "entries"))
+		if by := getString(obj, "by"); by != "" {
+			text += "\n\n— " + StripTags(by)
+		}
+		return text
+
+	case "abilityDc":
+		return fmt.Sprintf("%s save DC = 8 + your proficiency bonus + your %s modifier",
+			StripTags(getString(obj, "name")), abilityList(obj))
+
+	case "abilityAttackMod":
+		return fmt.Sprintf("%s attack modifier = your proficiency bonus + your %s modifier",
+			StripTags(getString(obj, "name")), abilityList(obj))
+
+	case "refClassFeature", "refSubclassFeature", "refOptionalfeature", "image", "hr":
+		return ""
+
+	case "cell":
+		if roll := getObject(obj, "roll"); roll != nil {
+			if exact := getString(roll, "exact"); exact != "" {
+				return exact
+			}
+			return getString(roll, "min") + "–" + getString(roll, "max")
+		}
+		return entryText(obj["entry"])
+	}
+
+	// Entries, sections, insets, items and anything else with text
+	text := Entries(getList(obj, "entries"))
+	if text == "" {
+		text = entryText(obj["entry"])
+	}
+	if name := getString(obj, "name"); name != "" {
+		name = strings.TrimSuffix(StripTags(name), ".")
+		if text == "" {
+			return name
+		}
+		return name + ". " + text
+	}
+	return text
+}
+
+// tableRow returns a row of a table as text
+func tableRow(cells []interface{}) string {
+	var text []string
+	for _, cell := range cells {
+		text = append(text, strings.Replace(entryText(cell), "\n", " ", -1))
+	}
+	return strings.Join(text, " | ")
+}
+
+// abilityList returns the abilities in an abilityDc or abilityAttackMod entry
+// as text, e.g. "Intelligence or Wisdom"
+func abilityList(obj object) string {
+	var names []string
+	for _, ability := range getStrings(obj, "attributes") {
+		names = append(names, abilityTitles[abilityNames[ability]])
+	}
+	return strings.Join(names, " or ")
+}
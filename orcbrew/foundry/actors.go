@@ -0,0 +1,347 @@
+package foundry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// NPCData is the system data of an NPC actor
+type NPCData struct {
+	Abilities  map[string]AbilityData `json:"abilities"`
+	Attributes Attributes             `json:"attributes"`
+	Details    Details                `json:"details"`
+	Traits     Traits                 `json:"traits"`
+	Skills     map[string]SkillData   `json:"skills"`
+	Resources  Resources              `json:"resources"`
+}
+
+// AbilityData is an ability score and whether the NPC is proficient in its
+// saving throw
+type AbilityData struct {
+	Value      int `json:"value"`
+	Proficient int `json:"proficient"`
+}
+
+// Attributes are an NPC's armor class, hit points and speeds
+type Attributes struct {
+	AC       ArmorClass `json:"ac"`
+	HP       HitPoints  `json:"hp"`
+	Movement Movement   `json:"movement"`
+	Senses   Senses     `json:"senses"`
+}
+
+// ArmorClass is an NPC's armor class
+type ArmorClass struct {
+	Flat int    `json:"flat"`
+	Calc string `json:"calc"`
+}
+
+// HitPoints are an NPC's hit points
+type HitPoints struct {
+	Value   int    `json:"value"`
+	Max     int    `json:"max"`
+	Formula string `json:"formula"`
+}
+
+// Details describe an NPC
+type Details struct {
+	Biography Description `json:"biography"`
+	Alignment string      `json:"alignment"`
+	Type      TypeValue   `json:"type"`
+	CR        float32     `json:"cr"`
+	Source    Source      `json:"source"`
+}
+
+// Traits are an NPC's size, damage and condition modifiers and languages
+type Traits struct {
+	Size      string     `json:"size"`
+	DI        TraitValue `json:"di"`
+	DR        TraitValue `json:"dr"`
+	DV        TraitValue `json:"dv"`
+	CI        TraitValue `json:"ci"`
+	Languages TraitValue `json:"languages"`
+}
+
+// TraitValue is a list of keys known to dnd5e, with anything else written out
+// in Custom
+type TraitValue struct {
+	Value  []string `json:"value"`
+	Custom string   `json:"custom"`
+}
+
+// SkillData is an NPC's proficiency in a skill, 1 when proficient or 2 with
+// expertise
+type SkillData struct {
+	Value   int    `json:"value"`
+	Ability string `json:"ability"`
+}
+
+// Resources are an NPC's legendary actions
+type Resources struct {
+	Legact LegendaryActions `json:"legact"`
+}
+
+// LegendaryActions is the number of legendary actions an NPC can take
+type LegendaryActions struct {
+	Value int `json:"value"`
+	Max   int `json:"max"`
+}
+
+var skills = map[schema.Skill]struct {
+	key     string
+	ability schema.Ability
+}{
+	schema.Acrobatics:     {"acr", schema.Dexterity},
+	schema.AnimalHandling: {"ani", schema.Wisdom},
+	schema.Arcana:         {"arc", schema.Intelligence},
+	schema.Athletics:      {"ath", schema.Strength},
+	schema.Deception:      {"dec", schema.Charisma},
+	schema.History:        {"his", schema.Intelligence},
+	schema.Insight:        {"ins", schema.Wisdom},
+	schema.Intimidation:   {"itm", schema.Charisma},
+	schema.Investigation:  {"inv", schema.Intelligence},
+	schema.Medicine:       {"med", schema.Wisdom},
+	schema.Nature:         {"nat", schema.Intelligence},
+	schema.Perception:     {"prc", schema.Wisdom},
+	schema.Performance:    {"prf", schema.Charisma},
+	schema.Persuasion:     {"per", schema.Charisma},
+	schema.Religion:       {"rel", schema.Intelligence},
+	schema.SleightOfHand:  {"slt", schema.Dexterity},
+	schema.Stealth:        {"ste", schema.Dexterity},
+	schema.Survival:       {"sur", schema.Wisdom},
+}
+
+// languages maps orcbrew's language keys to dnd5e's where they differ
+var languages = map[string]string{
+	"deep-speech":  "deep",
+	"thieves-cant": "cant",
+}
+
+var knownLanguages = map[string]bool{
+	"common": true, "dwarvish": true, "elvish": true, "giant": true, "gnomish": true,
+	"goblin": true, "halfling": true, "orc": true, "abyssal": true, "celestial": true,
+	"deep": true, "draconic": true, "infernal": true, "primordial": true, "sylvan": true,
+	"undercommon": true, "druidic": true, "cant": true, "aquan": true, "auran": true,
+	"ignan": true, "terran": true, "telepathy": true,
+}
+
+var speedPattern = regexp.MustCompile(`(?:(burrow|climb|fly|swim)\s+)?(\d+)\s*ft\.?(\s*\(hover\))?`)
+
+func npc(m schema.MonsterConfig) Document {
+	scores := map[schema.Ability]int{
+		schema.Strength: m.Str, schema.Dexterity: m.Dex, schema.Constitution: m.Con,
+		schema.Intelligence: m.Int, schema.Wisdom: m.Wis, schema.Charisma: m.Cha,
+	}
+
+	data := NPCData{
+		Abilities: make(map[string]AbilityData),
+		Attributes: Attributes{
+			AC:       ArmorClass{Flat: m.ArmorClass, Calc: "natural"},
+			HP:       hitPoints(m.HitPoints, m.Con),
+			Movement: speed(m.Speed),
+			Senses:   Senses{Units: "ft"},
+		},
+		Details: Details{
+			Biography: Description{Value: HTML(m.Description)},
+			Alignment: m.Alignment,
+			Type:      creatureType(m.Type),
+			CR:        m.Challenge,
+			Source:    Source{Custom: m.OptionPack},
+		},
+		Traits: Traits{Size: sizes[m.Size]},
+		Skills: make(map[string]SkillData),
+	}
+
+	for ability, score := range scores {
+		proficient := 0
+		if save, ok := m.SavingThrows[ability]; ok && save > modifier(score) {
+			proficient = 1
+		}
+		data.Abilities[string(ability)] = AbilityData{Value: score, Proficient: proficient}
+	}
+
+	pb := proficiencyBonus(m.Challenge)
+	for skill, bonus := range m.Skills {
+		info, ok := skills[skill]
+		if !ok {
+			continue
+		}
+		value := 1
+		if bonus >= modifier(scores[info.ability])+2*pb {
+			value = 2
+		}
+		data.Skills[info.key] = SkillData{Value: value, Ability: string(info.ability)}
+	}
+
+	if props := m.Props; props != nil {
+		data.Traits.DI = damageTraits(props.DamageImmunity)
+		data.Traits.DR = damageTraits(props.DamageResistance)
+		data.Traits.DV = damageTraits(props.DamageVulnerability)
+		for _, condition := range sortedKeys(props.ConditionImmunity) {
+			if props.ConditionImmunity[schema.Condition(condition)] {
+				data.Traits.CI.Value = append(data.Traits.CI.Value, condition)
+			}
+		}
+		data.Traits.Languages = languageTraits(props.Language)
+	}
+	for _, trait := range []*TraitValue{&data.Traits.DI, &data.Traits.DR, &data.Traits.DV, &data.Traits.CI, &data.Traits.Languages} {
+		if trait.Value == nil {
+			trait.Value = []string{}
+		}
+	}
+
+	if m.LegendaryActions != nil {
+		data.Resources.Legact = LegendaryActions{Value: 3, Max: 3}
+		if m.LegendaryActions.Description != "" {
+			data.Details.Biography.Value += "\n" + namedHTML("Legendary Actions", m.LegendaryActions.Description)
+		}
+	}
+
+	doc := newDocument("npc", "monsters", m.Key, m.OptionPack, m.Name, data)
+	for idx, trait := range m.Traits {
+		doc.Items = append(doc.Items, monsterItem(doc.ID, m, idx, trait))
+	}
+	return doc
+}
+
+// monsterItem returns an embedded feat item for a monster's trait or action
+func monsterItem(actorID string, m schema.MonsterConfig, idx int, trait schema.MonsterTrait) Document {
+	data := FeatData{
+		Description: Description{Value: HTML(trait.Description)},
+		Source:      Source{Custom: m.OptionPack},
+		Type:        TypeValue{Value: "monster"},
+	}
+	switch trait.Type {
+	case schema.MonsterTraitActionAction:
+		cost := 1
+		data.Activation = Activation{Type: "action", Cost: &cost}
+	case schema.MonsterTraitActionLegendaryAction:
+		cost := 1
+		data.Activation = Activation{Type: "legendary", Cost: &cost}
+	}
+
+	id := documentID("monsters", m.OptionPack, m.Key, strconv.Itoa(idx), trait.Name)
+	return Document{
+		ID:      id,
+		Key:     fmt.Sprintf("!actors.items!%s.%s", actorID, id),
+		Name:    trait.Name,
+		Type:    "feat",
+		Img:     itemIcon,
+		System:  data,
+		Effects: []interface{}{},
+		Flags:   Flags{Orcbrew: OrcbrewFlags{Kind: "monsters", Key: m.Key, OptionPack: m.OptionPack}},
+	}
+}
+
+// modifier returns the modifier for an ability score
+func modifier(score int) int {
+	if score < 10 {
+		return (score - 11) / 2
+	}
+	return (score - 10) / 2
+}
+
+// proficiencyBonus returns the proficiency bonus for a challenge rating
+func proficiencyBonus(cr float32) int {
+	if cr < 5 {
+		return 2
+	}
+	return 2 + (int(cr)-1)/4
+}
+
+// hitPoints returns the average hit points for a monster's hit dice
+func hitPoints(hd *schema.HitDieCount, con int) HitPoints {
+	if hd == nil || hd.DieCount == 0 {
+		return HitPoints{}
+	}
+
+	bonus := hd.DieCount * modifier(con)
+	average := hd.DieCount*(hd.Die+1)/2 + bonus
+	if average < 1 {
+		average = 1
+	}
+
+	formula := fmt.Sprintf("%dd%d", hd.DieCount, hd.Die)
+	if bonus > 0 {
+		formula += fmt.Sprintf(" + %d", bonus)
+	} else if bonus < 0 {
+		formula += fmt.Sprintf(" - %d", -bonus)
+	}
+	return HitPoints{Value: average, Max: average, Formula: formula}
+}
+
+// speed parses a speed such as "30 ft., fly 60 ft. (hover)"
+func speed(text string) Movement {
+	movement := Movement{Units: "ft"}
+	for _, match := range speedPattern.FindAllStringSubmatch(strings.ToLower(text), -1) {
+		n, _ := strconv.Atoi(match[2])
+		switch match[1] {
+		case "burrow":
+			movement.Burrow = n
+		case "climb":
+			movement.Climb = n
+		case "fly":
+			movement.Fly = n
+		case "swim":
+			movement.Swim = n
+		default:
+			movement.Walk = n
+		}
+		if match[3] != "" {
+			movement.Hover = true
+		}
+	}
+	return movement
+}
+
+// creatureType splits a type such as "humanoid (goblinoid)"
+func creatureType(text string) TypeValue {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if idx := strings.Index(text, "("); idx >= 0 {
+		return TypeValue{
+			Value:   strings.TrimSpace(text[:idx]),
+			Subtype: strings.Trim(text[idx:], "() "),
+		}
+	}
+	return TypeValue{Value: text}
+}
+
+// damageTraits converts a set of damage types, which dnd5e uses the same
+// keys for except for traps
+func damageTraits(set map[schema.Damage]bool) TraitValue {
+	var trait TraitValue
+	for _, damage := range sortedKeys(set) {
+		if !set[schema.Damage(damage)] {
+			continue
+		}
+		if schema.Damage(damage) == schema.Traps {
+			trait.Custom = "Traps"
+			continue
+		}
+		trait.Value = append(trait.Value, damage)
+	}
+	return trait
+}
+
+func languageTraits(set map[string]bool) TraitValue {
+	var trait TraitValue
+	var custom []string
+	for _, language := range setKeys(set) {
+		if key, ok := languages[language]; ok {
+			language = key
+		}
+		if knownLanguages[language] {
+			trait.Value = append(trait.Value, language)
+		} else {
+			custom = append(custom, title(language))
+		}
+	}
+	sort.Strings(trait.Value)
+	trait.Custom = strings.Join(custom, "; ")
+	return trait
+}
@@ -0,0 +1,258 @@
+// Package foundry exports option packs as documents for the dnd5e system of
+// Foundry VTT: spells, feats, backgrounds and races as items, and monsters as
+// NPC actors with their traits and actions as embedded items.
+//
+// Documents are written as a folder of JSON files for each compendium, the
+// source format compiled into compendium packs by Foundry's CLI (fvtt package
+// pack), so no LevelDB is needed. README.md documents where each field goes.
+package foundry
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// Document is a Foundry item or actor
+type Document struct {
+	ID      string        `json:"_id"`
+	Key     string        `json:"_key"` // the database key, used by Foundry's CLI
+	Name    string        `json:"name"`
+	Type    string        `json:"type"`
+	Img     string        `json:"img"`
+	System  interface{}   `json:"system"`
+	Items   []Document    `json:"items,omitempty"`
+	Effects []interface{} `json:"effects"`
+	Flags   Flags         `json:"flags"`
+}
+
+// Flags records where a document came from
+type Flags struct {
+	Orcbrew OrcbrewFlags `json:"orcbrew"`
+}
+
+// OrcbrewFlags identifies the entity a document was exported from
+type OrcbrewFlags struct {
+	Kind       string `json:"kind"`
+	Key        string `json:"key"`
+	OptionPack string `json:"option-pack"`
+}
+
+// Compendium is a compendium pack of documents of a single type
+type Compendium struct {
+	Name      string     `json:"name"`
+	Label     string     `json:"label"`
+	Path      string     `json:"path"`
+	Type      string     `json:"type"` // Item or Actor
+	System    string     `json:"system"`
+	Documents []Document `json:"-"`
+}
+
+// Default icons from Foundry's core
+const (
+	itemIcon  = "icons/svg/item-bag.svg"
+	spellIcon = "icons/svg/book.svg"
+	actorIcon = "icons/svg/mystery-man.svg"
+)
+
+// Export converts the spells, monsters, feats, backgrounds and races in the
+// option packs to Foundry documents, returning a compendium for each kind
+// that has entities
+func Export(all schema.OrcbrewExportAll) []*Compendium {
+	compendiums := []*Compendium{
+		{Name: "spells", Label: "Spells", Type: "Item"},
+		{Name: "monsters", Label: "Monsters", Type: "Actor"},
+		{Name: "feats", Label: "Feats", Type: "Item"},
+		{Name: "backgrounds", Label: "Backgrounds", Type: "Item"},
+		{Name: "races", Label: "Races", Type: "Item"},
+	}
+
+	var packs []string
+	for pack := range all {
+		packs = append(packs, pack)
+	}
+	sort.Strings(packs)
+
+	for _, pack := range packs {
+		source := all[pack]
+		for _, key := range sortedKeys(source.Spells) {
+			compendiums[0].Documents = append(compendiums[0].Documents, spell(source.Spells[key]))
+		}
+		for _, key := range sortedKeys(source.Monsters) {
+			compendiums[1].Documents = append(compendiums[1].Documents, npc(source.Monsters[key]))
+		}
+		for _, key := range sortedKeys(source.Feats) {
+			compendiums[2].Documents = append(compendiums[2].Documents, feat(source.Feats[key]))
+		}
+		for _, key := range sortedKeys(source.Backgrounds) {
+			compendiums[3].Documents = append(compendiums[3].Documents, background(source.Backgrounds[key]))
+		}
+		for _, key := range sortedKeys(source.Races) {
+			compendiums[4].Documents = append(compendiums[4].Documents, race(source.Races[key]))
+		}
+	}
+
+	var result []*Compendium
+	for _, compendium := range compendiums {
+		if len(compendium.Documents) > 0 {
+			compendium.Path = "packs/" + compendium.Name
+			compendium.System = "dnd5e"
+			result = append(result, compendium)
+		}
+	}
+	return result
+}
+
+// WritePacks writes the compendiums for the option packs to dir, with a
+// directory of JSON files for each compendium and a packs.json listing them,
+// to be copied into a module's module.json
+func WritePacks(all schema.OrcbrewExportAll, dir string) error {
+	compendiums := Export(all)
+	for _, compendium := range compendiums {
+		packDir := filepath.Join(dir, compendium.Name)
+		if err := os.MkdirAll(packDir, 0755); err != nil {
+			return err
+		}
+
+		for _, doc := range compendium.Documents {
+			filename := filepath.Join(packDir, fmt.Sprintf("%s_%s.json", orcbrew.SafeFilename(doc.Name), doc.ID))
+			// A document that has been renamed since it was last exported
+			// leaves a file with the same ID, which would be packed twice
+			stale, err := filepath.Glob(filepath.Join(packDir, "*_"+doc.ID+".json"))
+			if err != nil {
+				return err
+			}
+			for _, old := range stale {
+				if old != filename {
+					if err := os.Remove(old); err != nil {
+						return err
+					}
+				}
+			}
+			if err := writeJSON(filename, doc); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writeJSON(filepath.Join(dir, "packs.json"), compendiums)
+}
+
+// writeJSON writes v as indented JSON, leaving the HTML in descriptions
+// readable
+func writeJSON(filename string, v interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+const idChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// documentID returns a Foundry ID for an entity, which is the same every time
+// it's exported so that documents can be updated in place
+func documentID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "/")))
+	id := make([]byte, 16)
+	for idx := range id {
+		id[idx] = idChars[int(sum[idx])%len(idChars)]
+	}
+	return string(id)
+}
+
+// newDocument returns an item or actor for an entity
+func newDocument(docType string, kind string, key string, pack string, name string, system interface{}) Document {
+	id := documentID(kind, pack, key)
+	collection := "items"
+	img := itemIcon
+	switch docType {
+	case "npc":
+		collection, img = "actors", actorIcon
+	case "spell":
+		img = spellIcon
+	}
+
+	return Document{
+		ID:      id,
+		Key:     fmt.Sprintf("!%s!%s", collection, id),
+		Name:    name,
+		Type:    docType,
+		Img:     img,
+		System:  system,
+		Effects: []interface{}{},
+		Flags:   Flags{Orcbrew: OrcbrewFlags{Kind: kind, Key: key, OptionPack: pack}},
+	}
+}
+
+// Description is the description of an item or actor
+type Description struct {
+	Value string `json:"value"` // HTML
+	Chat  string `json:"chat"`
+}
+
+// Source is where an item or actor was published
+type Source struct {
+	Custom string `json:"custom"`
+}
+
+// HTML converts plain text to HTML paragraphs, one per line
+func HTML(text string) string {
+	var paragraphs []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paragraphs = append(paragraphs, "<p>"+html.EscapeString(line)+"</p>")
+		}
+	}
+	return strings.Join(paragraphs, "\n")
+}
+
+// namedHTML returns a paragraph starting with a bold name, as used for traits
+func namedHTML(name string, text string) string {
+	body := HTML(text)
+	if strings.HasPrefix(body, "<p>") {
+		return "<p><strong>" + html.EscapeString(name) + ".</strong> " + body[3:]
+	}
+	return "<p><strong>" + html.EscapeString(name) + ".</strong></p>" + body
+}
+
+// sortedKeys returns the keys of a map of entities, sorted
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// title returns a key as a title, e.g. "Sleight Of Hand" for "sleight-of-hand"
+func title(key string) string {
+	return strings.Title(strings.Replace(key, "-", " ", -1))
+}
+
+// setKeys returns the keys of a set which are true, sorted
+func setKeys(set map[string]bool) []string {
+	var keys []string
+	for key, ok := range set {
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,298 @@
+package foundry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+const exampleFile = "../schema/example.orcbrew"
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestSpell(t *testing.T) {
+	doc := spell(schema.SpellConfig{
+		Key:         "counter-hex",
+		OptionPack:  "Test",
+		Name:        "Counter Hex",
+		Description: "You interrupt a creature.\nIt fails.",
+		Level:       3,
+		School:      "Abjuration",
+		Duration:    "Concentration, up to 1 minute",
+		Components:  &schema.SpellComponents{Verbal: true, Somatic: true},
+		CastingTime: "1 reaction, which you take when you see a creature casting a spell",
+		Range:       "Self (15-foot cone)",
+		AttackRoll:  true,
+	})
+
+	if doc.Type != "spell" || doc.Name != "Counter Hex" || doc.Key != "!items!"+doc.ID {
+		t.Errorf("Unexpected document %s %q %s", doc.Type, doc.Name, doc.Key)
+	}
+
+	cost := 1
+	expected := SpellData{
+		Description: Description{Value: "<p>You interrupt a creature.</p>\n<p>It fails.</p>"},
+		Source:      Source{Custom: "Test"},
+		Activation:  Activation{Type: "reaction", Cost: &cost, Condition: "which you take when you see a creature casting a spell"},
+		Duration:    ValueUnits{Value: "1", Units: "minute"},
+		Target:      Target{Value: intPtr(15), Units: "ft", Type: "cone"},
+		Range:       Range{Units: "self"},
+		Level:       3,
+		School:      "abj",
+		Properties:  []string{"concentration", "vocal", "somatic"},
+		Preparation: Preparation{Mode: "prepared"},
+		ActionType:  "msak",
+	}
+	if diff := deep.Equal(doc.System, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestSpellFields(t *testing.T) {
+	for _, test := range []struct {
+		castingTime string
+		activation  Activation
+	}{
+		{"1 bonus action", Activation{Type: "bonus", Cost: intPtr(1)}},
+		{"10 minutes", Activation{Type: "minute", Cost: intPtr(10)}},
+		{"1 Action", Activation{Type: "action", Cost: intPtr(1)}},
+		{"When you are hit", Activation{Type: "special", Condition: "When you are hit"}},
+		{"", Activation{}},
+	} {
+		if diff := deep.Equal(castingTime(test.castingTime), test.activation); diff != nil {
+			t.Errorf("%q: %s", test.castingTime, diff)
+		}
+	}
+
+	for _, test := range []struct {
+		duration string
+		expected ValueUnits
+	}{
+		{"Instantaneous", ValueUnits{Units: "inst"}},
+		{"Until dispelled", ValueUnits{Units: "perm"}},
+		{"8 hours", ValueUnits{Value: "8", Units: "hour"}},
+		{"Special", ValueUnits{Units: "spec"}},
+	} {
+		if actual, _ := duration(test.duration, nil); actual != test.expected {
+			t.Errorf("%q: expected %v, got %v", test.duration, test.expected, actual)
+		}
+	}
+
+	for _, test := range []struct {
+		text     string
+		expected Range
+	}{
+		{"Touch", Range{Units: "touch"}},
+		{"120 feet", Range{Value: intPtr(120), Units: "ft"}},
+		{"1 mile", Range{Value: intPtr(1), Units: "mi"}},
+		{"Unlimited", Range{Units: "any"}},
+		{"Sight", Range{Units: "spec"}},
+	} {
+		actual, _ := spellRange(test.text)
+		if diff := deep.Equal(actual, test.expected); diff != nil {
+			t.Errorf("%q: %s", test.text, diff)
+		}
+	}
+}
+
+func TestNPC(t *testing.T) {
+	doc := npc(schema.MonsterConfig{
+		Key:        "ash-goblin",
+		OptionPack: "Test",
+		Name:       "Ash Goblin",
+		Str:        8, Dex: 14, Con: 12, Int: 10, Wis: 8, Cha: 8,
+		HitPoints:    &schema.HitDieCount{DieCount: 3, Die: 6},
+		Speed:        "30 ft., fly 40 ft. (hover), swim 20 ft.",
+		Alignment:    "neutral evil",
+		Size:         schema.Small,
+		ArmorClass:   15,
+		Type:         "humanoid (goblinoid)",
+		Skills:       map[schema.Skill]int{schema.Stealth: 6, schema.Perception: 1},
+		SavingThrows: map[schema.Ability]int{schema.Dexterity: 4, schema.Wisdom: -1},
+		Challenge:    0.25,
+		Props: &schema.MonsterProperties{
+			Language:          map[string]bool{"common": true, "goblin": true, "deep-speech": true, "ashen": true},
+			DamageImmunity:    map[schema.Damage]bool{schema.Fire: true, schema.Traps: true},
+			ConditionImmunity: map[schema.Condition]bool{schema.Charmed: true},
+		},
+		Traits: []schema.MonsterTrait{
+			{Name: "Nimble Escape", Description: "It can Disengage."},
+			{Type: schema.MonsterTraitActionAction, Name: "Scimitar", Description: "Melee Weapon Attack."},
+		},
+	})
+
+	if doc.Type != "npc" || doc.Key != "!actors!"+doc.ID {
+		t.Errorf("Unexpected document %s %s", doc.Type, doc.Key)
+	}
+
+	data := doc.System.(NPCData)
+	if diff := deep.Equal(data.Abilities["dex"], AbilityData{Value: 14, Proficient: 1}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(data.Abilities["wis"], AbilityData{Value: 8, Proficient: 0}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(data.Attributes.HP, HitPoints{Value: 13, Max: 13, Formula: "3d6 + 3"}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(data.Attributes.Movement, Movement{Walk: 30, Fly: 40, Swim: 20, Hover: true, Units: "ft"}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(data.Details.Type, TypeValue{Value: "humanoid", Subtype: "goblinoid"}); diff != nil {
+		t.Error(diff)
+	}
+	expectedTraits := Traits{
+		Size:      "sm",
+		DI:        TraitValue{Value: []string{"fire"}, Custom: "Traps"},
+		DR:        TraitValue{Value: []string{}},
+		DV:        TraitValue{Value: []string{}},
+		CI:        TraitValue{Value: []string{"charmed"}},
+		Languages: TraitValue{Value: []string{"common", "deep", "goblin"}, Custom: "Ashen"},
+	}
+	if diff := deep.Equal(data.Traits, expectedTraits); diff != nil {
+		t.Error(diff)
+	}
+	expectedSkills := map[string]SkillData{
+		"ste": {Value: 2, Ability: "dex"},
+		"prc": {Value: 1, Ability: "wis"},
+	}
+	if diff := deep.Equal(data.Skills, expectedSkills); diff != nil {
+		t.Error(diff)
+	}
+	if data.Resources.Legact.Max != 0 {
+		t.Errorf("Expected no legendary actions, got %d", data.Resources.Legact.Max)
+	}
+
+	if len(doc.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(doc.Items))
+	}
+	for idx, activation := range []string{"", "action"} {
+		item := doc.Items[idx]
+		if item.Type != "feat" || item.Key != "!actors.items!"+doc.ID+"."+item.ID {
+			t.Errorf("Unexpected item %s %s", item.Type, item.Key)
+		}
+		if actual := item.System.(FeatData).Activation.Type; actual != activation {
+			t.Errorf("%s: expected activation %q, got %q", item.Name, activation, actual)
+		}
+	}
+}
+
+func TestFeat(t *testing.T) {
+	doc := feat(schema.FeatConfig{
+		Key:              "elven-grace",
+		OptionPack:       "Test",
+		Name:             "Elven Grace",
+		AbilityIncreases: []string{"dex", "cha"},
+		Prereqs:          []string{"dex", "spellcasting"},
+		PathPrereqs:      schema.FeatPathPrereqs{Race: map[string]bool{"elf": true, "half-elf": true}},
+	})
+
+	data := doc.System.(FeatData)
+	if expected := "Dexterity 13 or higher, The ability to cast at least one spell, Elf or Half Elf"; data.Requirements != expected {
+		t.Errorf("Expected requirements %q, got %q", expected, data.Requirements)
+	}
+	if expected := "<p>Increase your Dexterity or Charisma score by 1, to a maximum of 20.</p>"; data.Description.Value != expected {
+		t.Errorf("Expected description %q, got %q", expected, data.Description.Value)
+	}
+}
+
+func TestExport(t *testing.T) {
+	f, err := orcbrew.ReadFile(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compendiums := Export(f.Packs)
+	var names []string
+	for _, compendium := range compendiums {
+		names = append(names, compendium.Name)
+		if len(compendium.Documents) != 1 {
+			t.Errorf("%s: expected 1 document, got %d", compendium.Name, len(compendium.Documents))
+		}
+	}
+	if diff := deep.Equal(names, []string{"spells", "monsters", "feats", "backgrounds", "races"}); diff != nil {
+		t.Error(diff)
+	}
+
+	// IDs are the same on every export
+	again := Export(f.Packs)
+	for idx, compendium := range compendiums {
+		if id := again[idx].Documents[0].ID; id != compendium.Documents[0].ID {
+			t.Errorf("%s: ID changed from %s to %s", compendium.Name, compendium.Documents[0].ID, id)
+		}
+	}
+
+	race := compendiums[4].Documents[0].System.(RaceData)
+	if len(race.Advancement) == 0 {
+		t.Errorf("Expected the race to have advancements")
+	}
+}
+
+func TestWritePacks(t *testing.T) {
+	f, err := orcbrew.ReadFile(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "foundry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := WritePacks(f.Packs, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "spells", "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one spell file, got %v (%v)", files, err)
+	}
+	data, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["type"] != "spell" || doc["_key"] != "!items!"+doc["_id"].(string) {
+		t.Errorf("Unexpected spell document %v", doc)
+	}
+
+	// Writing again after a rename replaces the file of the document
+	for _, source := range f.Packs {
+		for key, spell := range source.Spells {
+			spell.Name = "Renamed"
+			source.Spells[key] = spell
+		}
+	}
+	if err := WritePacks(f.Packs, dir); err != nil {
+		t.Fatal(err)
+	}
+	renamed, err := filepath.Glob(filepath.Join(dir, "spells", "*.json"))
+	if err != nil || len(renamed) != 1 || !strings.HasPrefix(filepath.Base(renamed[0]), "Renamed_") {
+		t.Errorf("Expected the spell file to be renamed, got %v (%v)", renamed, err)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "packs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var packs []map[string]string
+	if err := json.Unmarshal(data, &packs); err != nil {
+		t.Fatal(err)
+	}
+	if len(packs) != 5 || packs[1]["type"] != "Actor" || packs[1]["path"] != "packs/monsters" {
+		t.Errorf("Unexpected packs.json %v", packs)
+	}
+}
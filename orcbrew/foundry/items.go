@@ -0,0 +1,437 @@
+package foundry
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// SpellData is the system data of a spell item
+type SpellData struct {
+	Description Description `json:"description"`
+	Source      Source      `json:"source"`
+	Activation  Activation  `json:"activation"`
+	Duration    ValueUnits  `json:"duration"`
+	Target      Target      `json:"target"`
+	Range       Range       `json:"range"`
+	Level       int         `json:"level"`
+	School      string      `json:"school"`
+	Properties  []string    `json:"properties"`
+	Materials   Materials   `json:"materials"`
+	Preparation Preparation `json:"preparation"`
+	ActionType  string      `json:"actionType"`
+}
+
+// Activation is how an item is used, e.g. 1 bonus action
+type Activation struct {
+	Type      string `json:"type"`
+	Cost      *int   `json:"cost"`
+	Condition string `json:"condition"`
+}
+
+// ValueUnits is an amount of something, e.g. 1 minute
+type ValueUnits struct {
+	Value string `json:"value"`
+	Units string `json:"units"`
+}
+
+// Target is the area of a spell
+type Target struct {
+	Value *int   `json:"value"`
+	Units string `json:"units"`
+	Type  string `json:"type"`
+}
+
+// Range is the range of a spell
+type Range struct {
+	Value *int   `json:"value"`
+	Long  *int   `json:"long"`
+	Units string `json:"units"`
+}
+
+// Materials are the material components of a spell
+type Materials struct {
+	Value    string `json:"value"`
+	Consumed bool   `json:"consumed"`
+	Cost     int    `json:"cost"`
+	Supply   int    `json:"supply"`
+}
+
+// Preparation is how a spell is prepared
+type Preparation struct {
+	Mode     string `json:"mode"`
+	Prepared bool   `json:"prepared"`
+}
+
+var schools = map[string]string{
+	"abjuration": "abj", "conjuration": "con", "divination": "div", "enchantment": "enc",
+	"evocation": "evo", "illusion": "ill", "necromancy": "nec", "transmutation": "trs",
+}
+
+var (
+	castingTimePattern = regexp.MustCompile(`^(\d+)\s+(bonus action|action|reaction|minute|hour|day)s?\b,?\s*(.*)$`)
+	durationPattern    = regexp.MustCompile(`(\d+)\s+(turn|round|minute|hour|day|month|year)s?`)
+	rangePattern       = regexp.MustCompile(`^(\d+)\s*(feet|foot|ft\.?|miles?|mi\.?)`)
+	areaPattern        = regexp.MustCompile(`(\d+)[- ](?:foot|feet|ft\.?|mile)[- ]?(radius|cone|cube|line|sphere|cylinder|square|wall)`)
+)
+
+func spell(s schema.SpellConfig) Document {
+	data := SpellData{
+		Description: Description{Value: HTML(s.Description)},
+		Source:      Source{Custom: s.OptionPack},
+		Activation:  castingTime(s.CastingTime),
+		Level:       s.Level,
+		School:      schools[strings.ToLower(s.School)],
+		Properties:  []string{},
+		Preparation: Preparation{Mode: "prepared"},
+	}
+	data.Duration, data.Properties = duration(s.Duration, data.Properties)
+	data.Range, data.Target = spellRange(s.Range)
+
+	if c := s.Components; c != nil {
+		if c.Verbal {
+			data.Properties = append(data.Properties, "vocal")
+		}
+		if c.Somatic {
+			data.Properties = append(data.Properties, "somatic")
+		}
+		if c.Material {
+			data.Properties = append(data.Properties, "material")
+			data.Materials.Value = c.MaterialComponent
+		}
+	}
+	if s.Ritual {
+		data.Properties = append(data.Properties, "ritual")
+	}
+	if s.AttackRoll {
+		data.ActionType = "rsak"
+		if data.Range.Units == "touch" || data.Range.Units == "self" {
+			data.ActionType = "msak"
+		}
+	}
+
+	return newDocument("spell", "spells", s.Key, s.OptionPack, s.Name, data)
+}
+
+// castingTime converts a casting time such as "1 reaction, which you take
+// when you see a creature within 60 feet of you casting a spell"
+func castingTime(text string) Activation {
+	text = strings.TrimSpace(text)
+	match := castingTimePattern.FindStringSubmatch(strings.ToLower(text))
+	if match == nil {
+		if text == "" {
+			return Activation{}
+		}
+		return Activation{Type: "special", Condition: text}
+	}
+
+	cost, _ := strconv.Atoi(match[1])
+	activationType := strings.Replace(match[2], "bonus action", "bonus", 1)
+	// Keep the condition as written
+	condition := strings.TrimSpace(text[len(text)-len(match[3]):])
+	return Activation{Type: activationType, Cost: &cost, Condition: condition}
+}
+
+// duration converts a duration such as "Concentration, up to 1 minute",
+// adding the concentration property
+func duration(text string, properties []string) (ValueUnits, []string) {
+	lower := strings.ToLower(text)
+	if strings.Contains(lower, "concentration") {
+		properties = append(properties, "concentration")
+	}
+
+	switch {
+	case text == "":
+		return ValueUnits{}, properties
+	case strings.HasPrefix(lower, "instant"):
+		return ValueUnits{Units: "inst"}, properties
+	case strings.Contains(lower, "until dispelled") || strings.Contains(lower, "permanent"):
+		return ValueUnits{Units: "perm"}, properties
+	}
+
+	if match := durationPattern.FindStringSubmatch(lower); match != nil {
+		return ValueUnits{Value: match[1], Units: match[2]}, properties
+	}
+	return ValueUnits{Units: "spec"}, properties
+}
+
+// spellRange converts a range such as "60 feet" or "Self (15-foot cone)"
+func spellRange(text string) (Range, Target) {
+	lower := strings.ToLower(strings.TrimSpace(text))
+
+	var target Target
+	if match := areaPattern.FindStringSubmatch(lower); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		target = Target{Value: &n, Units: "ft", Type: match[2]}
+	}
+
+	switch {
+	case lower == "":
+		return Range{}, target
+	case strings.HasPrefix(lower, "self"):
+		return Range{Units: "self"}, target
+	case strings.HasPrefix(lower, "touch"):
+		return Range{Units: "touch"}, target
+	case strings.HasPrefix(lower, "unlimited"):
+		return Range{Units: "any"}, target
+	}
+
+	if match := rangePattern.FindStringSubmatch(lower); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		units := "ft"
+		if strings.HasPrefix(match[2], "mi") {
+			units = "mi"
+		}
+		return Range{Value: &n, Units: units}, target
+	}
+	return Range{Units: "spec"}, target
+}
+
+// FeatData is the system data of a feat item, which is also used for the
+// traits and actions of monsters
+type FeatData struct {
+	Description  Description `json:"description"`
+	Source       Source      `json:"source"`
+	Activation   Activation  `json:"activation"`
+	Type         TypeValue   `json:"type"`
+	Requirements string      `json:"requirements"`
+}
+
+// TypeValue is the type of an item or creature, with its subtype
+type TypeValue struct {
+	Value   string `json:"value"`
+	Subtype string `json:"subtype"`
+}
+
+func feat(f schema.FeatConfig) Document {
+	data := FeatData{
+		Description:  Description{Value: HTML(f.Description)},
+		Source:       Source{Custom: f.OptionPack},
+		Type:         TypeValue{Value: "feat"},
+		Requirements: featRequirements(f),
+	}
+
+	if len(f.AbilityIncreases) > 0 {
+		var names []string
+		for _, ability := range f.AbilityIncreases {
+			if name, ok := abilityTitles[schema.Ability(ability)]; ok {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			increase := fmt.Sprintf("<p>Increase your %s score by 1, to a maximum of 20.</p>", strings.Join(orList(names), ""))
+			data.Description.Value = strings.TrimPrefix(data.Description.Value+"\n"+increase, "\n")
+		}
+	}
+
+	return newDocument("feat", "feats", f.Key, f.OptionPack, f.Name, data)
+}
+
+// featRequirements describes a feat's prerequisites, e.g. "Strength 13 or
+// higher, Elf"
+func featRequirements(f schema.FeatConfig) string {
+	var requirements []string
+	for _, prereq := range f.Prereqs {
+		switch {
+		case abilityTitles[schema.Ability(prereq)] != "":
+			requirements = append(requirements, abilityTitles[schema.Ability(prereq)]+" 13 or higher")
+		case prereq == "spellcasting":
+			requirements = append(requirements, "The ability to cast at least one spell")
+		default:
+			requirements = append(requirements, "Proficiency with "+prereq+" armor")
+		}
+	}
+	if races := setKeys(f.PathPrereqs.Race); len(races) > 0 {
+		var names []string
+		for _, race := range races {
+			names = append(names, title(race))
+		}
+		requirements = append(requirements, strings.Join(orList(names), ""))
+	}
+	return strings.Join(requirements, ", ")
+}
+
+// orList joins names as "a, b or c"
+func orList(names []string) []string {
+	if len(names) < 2 {
+		return names
+	}
+	return []string{strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]}
+}
+
+// BackgroundData is the system data of a background item
+type BackgroundData struct {
+	Description Description   `json:"description"`
+	Source      Source        `json:"source"`
+	Advancement []interface{} `json:"advancement"`
+}
+
+func background(b schema.BackgroundConfig) Document {
+	var paragraphs []string
+	if profs := b.Profs; profs != nil {
+		var skills []string
+		for _, skill := range sortedKeys(profs.Skill) {
+			if profs.Skill[schema.Skill(skill)] {
+				skills = append(skills, title(skill))
+			}
+		}
+		if len(skills) > 0 {
+			paragraphs = append(paragraphs, namedHTML("Skill Proficiencies", strings.Join(skills, ", ")))
+		}
+
+		var tools []string
+		for _, tool := range setKeys(profs.Tool) {
+			tools = append(tools, title(tool))
+		}
+		if options := profs.ToolOptions; options != nil {
+			if options.GamingSet > 0 {
+				tools = append(tools, fmt.Sprintf("%d type(s) of gaming set", options.GamingSet))
+			}
+			if options.MusicalInstrument > 0 {
+				tools = append(tools, fmt.Sprintf("%d type(s) of musical instrument", options.MusicalInstrument))
+			}
+		}
+		if len(tools) > 0 {
+			paragraphs = append(paragraphs, namedHTML("Tool Proficiencies", strings.Join(tools, ", ")))
+		}
+
+		if options := profs.LanguageOptions; options != nil && options.Choose > 0 {
+			paragraphs = append(paragraphs, namedHTML("Languages", fmt.Sprintf("%d of your choice", options.Choose)))
+		}
+	}
+
+	var equipment []string
+	for _, item := range sortedKeys(b.Equipment) {
+		equipment = append(equipment, quantity(title(item), b.Equipment[item]))
+	}
+	for _, choice := range b.EquipmentChoices {
+		equipment = append(equipment, "one of "+html.UnescapeString(choice.Name))
+	}
+	for _, currency := range schema.Currency("").Values() {
+		if amount := b.Treasure[schema.Currency(currency)]; amount > 0 {
+			equipment = append(equipment, fmt.Sprintf("%d %s", amount, currency))
+		}
+	}
+	if len(equipment) > 0 {
+		paragraphs = append(paragraphs, namedHTML("Equipment", strings.Join(equipment, ", ")))
+	}
+
+	for _, trait := range b.Traits {
+		paragraphs = append(paragraphs, namedHTML(trait.Name, trait.Description))
+	}
+
+	data := BackgroundData{
+		Description: Description{Value: strings.Join(paragraphs, "\n")},
+		Source:      Source{Custom: b.OptionPack},
+		Advancement: []interface{}{},
+	}
+	return newDocument("background", "backgrounds", b.Key, b.OptionPack, b.Name, data)
+}
+
+func quantity(name string, n int) string {
+	if n > 1 {
+		return fmt.Sprintf("%s (%d)", name, n)
+	}
+	return name
+}
+
+// RaceData is the system data of a race item
+type RaceData struct {
+	Description Description   `json:"description"`
+	Source      Source        `json:"source"`
+	Identifier  string        `json:"identifier"`
+	Movement    Movement      `json:"movement"`
+	Senses      Senses        `json:"senses"`
+	Type        TypeValue     `json:"type"`
+	Advancement []Advancement `json:"advancement"`
+}
+
+// Movement is the speeds of a race or actor, in feet
+type Movement struct {
+	Burrow int    `json:"burrow"`
+	Climb  int    `json:"climb"`
+	Fly    int    `json:"fly"`
+	Swim   int    `json:"swim"`
+	Walk   int    `json:"walk"`
+	Units  string `json:"units"`
+	Hover  bool   `json:"hover"`
+}
+
+// Senses is the senses of a race or actor, in feet
+type Senses struct {
+	Darkvision  int    `json:"darkvision"`
+	Blindsight  int    `json:"blindsight"`
+	Tremorsense int    `json:"tremorsense"`
+	Truesight   int    `json:"truesight"`
+	Units       string `json:"units"`
+	Special     string `json:"special"`
+}
+
+// Advancement is something a race grants, such as ability score increases
+type Advancement struct {
+	ID            string      `json:"_id"`
+	Type          string      `json:"type"`
+	Configuration interface{} `json:"configuration"`
+	Level         int         `json:"level"`
+	Title         string      `json:"title"`
+}
+
+func race(r schema.RaceConfig) Document {
+	var paragraphs []string
+	for _, trait := range r.Traits {
+		paragraphs = append(paragraphs, namedHTML(trait.Name, trait.Description))
+	}
+	if len(r.Languages) > 0 {
+		paragraphs = append(paragraphs, namedHTML("Languages", strings.Join(r.Languages, ", ")))
+	}
+
+	data := RaceData{
+		Description: Description{Value: strings.Join(paragraphs, "\n")},
+		Source:      Source{Custom: r.OptionPack},
+		Identifier:  r.Key,
+		Movement:    Movement{Walk: r.Speed, Units: "ft"},
+		Senses:      Senses{Darkvision: r.Darkvision, Units: "ft"},
+		Type:        TypeValue{Value: "humanoid"},
+		Advancement: []Advancement{},
+	}
+	if r.Props != nil {
+		data.Movement.Fly = r.Props.FlyingSpeed
+	}
+
+	if len(r.Abilities) > 0 {
+		fixed := make(map[string]int)
+		for ability, n := range r.Abilities {
+			fixed[string(ability)] = n
+		}
+		data.Advancement = append(data.Advancement, Advancement{
+			ID:            documentID("races", r.OptionPack, r.Key, "asi"),
+			Type:          "AbilityScoreImprovement",
+			Configuration: map[string]interface{}{"fixed": fixed, "points": 0, "cap": 2},
+			Title:         "Ability Score Increase",
+		})
+	}
+	if size, ok := sizes[r.Size]; ok {
+		data.Advancement = append(data.Advancement, Advancement{
+			ID:            documentID("races", r.OptionPack, r.Key, "size"),
+			Type:          "Size",
+			Configuration: map[string]interface{}{"sizes": []string{size}},
+			Title:         "Size",
+		})
+	}
+
+	return newDocument("race", "races", r.Key, r.OptionPack, r.Name, data)
+}
+
+var abilityTitles = map[schema.Ability]string{
+	schema.Strength: "Strength", schema.Dexterity: "Dexterity", schema.Constitution: "Constitution",
+	schema.Intelligence: "Intelligence", schema.Wisdom: "Wisdom", schema.Charisma: "Charisma",
+}
+
+var sizes = map[schema.Size]string{
+	schema.Tiny: "tiny", schema.Small: "sm", schema.Medium: "med",
+	schema.Large: "lg", schema.Huge: "huge", schema.Gargantuan: "grg",
+}
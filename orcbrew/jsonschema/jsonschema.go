@@ -0,0 +1,217 @@
+// Package jsonschema describes the JSON produced by orcbrew2json as a JSON
+// Schema (draft 2020-12) document, derived by reflecting over the structs in
+// the schema package, and validates JSON documents against it.
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// Draft is the JSON Schema dialect used by generated documents
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema used to describe orcbrew data
+type Schema struct {
+	Schema string             `json:"$schema,omitempty"`
+	ID     string             `json:"$id,omitempty"`
+	Ref    string             `json:"$ref,omitempty"`
+	Defs   map[string]*Schema `json:"$defs,omitempty"`
+
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	PropertyNames        *Schema            `json:"propertyNames,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+
+	Items *Schema `json:"items,omitempty"`
+
+	Enum    []string    `json:"enum,omitempty"`
+	Const   interface{} `json:"const,omitempty"`
+	Pattern string      `json:"pattern,omitempty"`
+
+	OneOf []*Schema `json:"oneOf,omitempty"`
+}
+
+// enum is implemented by the generated string types in the schema package
+type enum interface {
+	Values() []string
+}
+
+var (
+	enumType          = reflect.TypeOf((*enum)(nil)).Elem()
+	levelModifierList = reflect.TypeOf(schema.LevelModifierList{})
+)
+
+// ForSource returns the schema for a single exported source
+func ForSource() *Schema {
+	return generate(reflect.TypeOf(schema.OrcbrewSource{}))
+}
+
+// ForExportAll returns the schema for the "Export All" format, which maps
+// option pack names to sources
+func ForExportAll() *Schema {
+	return generate(reflect.TypeOf(schema.OrcbrewExportAll{}))
+}
+
+// ForDocument returns the schema matching the top-level shape of a decoded
+// JSON document: ForSource when every key names an entity kind, otherwise
+// ForExportAll
+func ForDocument(doc interface{}) *Schema {
+	source := ForSource()
+
+	object, ok := doc.(map[string]interface{})
+	if !ok {
+		return source
+	}
+
+	kinds := source.Defs["OrcbrewSource"].Properties
+	for key := range object {
+		if _, ok := kinds[key]; !ok {
+			return ForExportAll()
+		}
+	}
+
+	return source
+}
+
+func generate(root reflect.Type) *Schema {
+	g := &generator{defs: make(map[string]*Schema)}
+
+	result := g.schemaFor(root)
+	result.Schema = Draft
+	result.Defs = g.defs
+	return result
+}
+
+type generator struct {
+	defs map[string]*Schema
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/$defs/" + name}
+}
+
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == levelModifierList {
+		return &Schema{Type: "array", Items: g.levelModifier()}
+	}
+
+	if t.Kind() == reflect.String && t.Implements(enumType) {
+		if _, ok := g.defs[t.Name()]; !ok {
+			values := reflect.Zero(t).Interface().(enum).Values()
+			g.defs[t.Name()] = &Schema{Type: "string", Enum: values}
+		}
+		return ref(t.Name())
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			PropertyNames:        g.propertyNames(t.Key()),
+			AdditionalProperties: g.schemaFor(t.Elem()),
+		}
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			// Reserve the name first, so recursive types terminate
+			g.defs[t.Name()] = nil
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return ref(t.Name())
+	}
+
+	// interface{} and anything else we can't describe accepts any value
+	return &Schema{}
+}
+
+func (g *generator) propertyNames(key reflect.Type) *Schema {
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Pattern: "^-?[0-9]+$"}
+	case reflect.String:
+		if key.Implements(enumType) {
+			return g.schemaFor(key)
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	result := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := JSONName(field)
+		if name == "-" {
+			continue
+		}
+
+		result.Properties[name] = g.schemaFor(field.Type)
+	}
+
+	return result
+}
+
+// levelModifier returns a schema that discriminates between the LevelModifier
+// variants using their "type" field
+func (g *generator) levelModifier() *Schema {
+	if _, ok := g.defs["LevelModifier"]; !ok {
+		var variants []*Schema
+		for _, key := range schema.LevelModifierKeys {
+			modifier, _ := schema.NewLevelModifier(key)
+			t := reflect.TypeOf(modifier).Elem()
+			value, _ := t.FieldByName("Value")
+
+			g.defs[t.Name()] = &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"type":  &Schema{Const: key},
+					"level": &Schema{Type: "integer"},
+					"value": g.schemaFor(value.Type),
+				},
+				Required: []string{"type", "value"},
+			}
+			variants = append(variants, ref(t.Name()))
+		}
+
+		g.defs["LevelModifier"] = &Schema{OneOf: variants}
+	}
+
+	return ref("LevelModifier")
+}
+
+// JSONName returns the name used for a struct field when encoded as JSON
+func JSONName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
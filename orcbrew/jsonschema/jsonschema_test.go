@@ -0,0 +1,100 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+func loadExample(t *testing.T) map[string]interface{} {
+	jsonBytes, err := ioutil.ReadFile("../schema/example.json")
+	if err != nil {
+		t.Fatalf("Error reading example.json: %s", err)
+	}
+
+	var doc map[string]interface{}
+	err = json.Unmarshal(jsonBytes, &doc)
+	if err != nil {
+		t.Fatalf("Error parsing example.json: %s", err)
+	}
+
+	return doc
+}
+
+func TestExampleIsValid(t *testing.T) {
+	doc := loadExample(t)
+
+	for _, err := range ForSource().Validate(doc) {
+		t.Error(err)
+	}
+
+	exportAll := map[string]interface{}{"Test": doc}
+	for _, err := range ForExportAll().Validate(exportAll) {
+		t.Error(err)
+	}
+}
+
+func TestInvalidValues(t *testing.T) {
+	doc := loadExample(t)
+
+	classes := doc["classes"].(map[string]interface{})
+	myclass := classes["myclass"].(map[string]interface{})
+	myclass["hit-die"] = "six"
+	myclass["level-modifiers"] = []interface{}{
+		map[string]interface{}{"type": "skill-prof", "value": "juggling"},
+		map[string]interface{}{"type": "no-such-modifier", "value": 1.0},
+	}
+
+	monsters := doc["monsters"].(map[string]interface{})
+	mymonster := monsters["mymonster"].(map[string]interface{})
+	mymonster["saving-throws"] = map[string]interface{}{"luck": 1.0}
+
+	errs := ForSource().Validate(doc)
+
+	expected := []string{
+		"/classes/myclass/hit-die",
+		"/classes/myclass/level-modifiers/0/value",
+		"/classes/myclass/level-modifiers/1/type",
+		"/monsters/mymonster/saving-throws/luck",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(errs), errs)
+	}
+
+	for idx, err := range errs {
+		if err.Path != expected[idx] {
+			t.Errorf("Expected error at %s, got %s", expected[idx], err)
+		}
+	}
+}
+
+func TestModifierDiscrimination(t *testing.T) {
+	s := ForSource()
+
+	modifier := s.Defs["LevelModifier"]
+	if modifier == nil || len(modifier.OneOf) != 12 {
+		t.Fatalf("Expected LevelModifier with 12 alternatives, got %+v", modifier)
+	}
+
+	spell := s.Defs["ModifierSpell"]
+	if spell.Properties["type"].Const != "spell" {
+		t.Errorf("Expected ModifierSpell to be discriminated by type spell, got %v", spell.Properties["type"].Const)
+	}
+	if spell.Properties["value"].Ref != "#/$defs/SpellWithAbility" {
+		t.Errorf("Expected ModifierSpell value to reference SpellWithAbility, got %+v", spell.Properties["value"])
+	}
+}
+
+func TestForDocument(t *testing.T) {
+	doc := loadExample(t)
+
+	if s := ForDocument(doc); s.Ref != "#/$defs/OrcbrewSource" {
+		t.Errorf("Expected source schema, got %s", s.Ref)
+	}
+
+	exportAll := map[string]interface{}{"Test": doc}
+	if s := ForDocument(exportAll); s.Type != "object" || s.AdditionalProperties.Ref != "#/$defs/OrcbrewSource" {
+		t.Errorf("Expected Export All schema, got %+v", s)
+	}
+}
@@ -0,0 +1,236 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidationError describes a single place where a document does not match
+// a schema
+type ValidationError struct {
+	Path    string // JSON pointer to the offending value
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+// Validate checks a decoded JSON document (as produced by json.Unmarshal into
+// an interface{}) against the schema, returning every mismatch found
+func (s *Schema) Validate(doc interface{}) []*ValidationError {
+	v := &validator{root: s}
+	errs := v.validate(s, doc, "")
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Path < errs[j].Path
+	})
+	return errs
+}
+
+type validator struct {
+	root *Schema
+}
+
+func (v *validator) resolve(ref string) (*Schema, error) {
+	const prefix = "#/$defs/"
+	if !strings.HasPrefix(ref, prefix) {
+		return nil, fmt.Errorf("unsupported $ref %s", ref)
+	}
+
+	def, ok := v.root.Defs[strings.TrimPrefix(ref, prefix)]
+	if !ok || def == nil {
+		return nil, fmt.Errorf("unresolved $ref %s", ref)
+	}
+
+	return def, nil
+}
+
+func (v *validator) validate(s *Schema, doc interface{}, path string) []*ValidationError {
+	fail := func(format string, args ...interface{}) []*ValidationError {
+		return []*ValidationError{&ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if s.Ref != "" {
+		def, err := v.resolve(s.Ref)
+		if err != nil {
+			return fail("%s", err)
+		}
+		return v.validate(def, doc, path)
+	}
+
+	if s.Type != "" && !hasType(doc, s.Type) {
+		return fail("expected %s, got %s", s.Type, typeOf(doc))
+	}
+
+	if s.Const != nil && !reflect.DeepEqual(s.Const, doc) {
+		return fail("expected %v, got %v", s.Const, doc)
+	}
+
+	if len(s.Enum) > 0 {
+		str, _ := doc.(string)
+		if !contains(s.Enum, str) {
+			return fail("%v is not one of %s", doc, strings.Join(s.Enum, ", "))
+		}
+	}
+
+	if s.Pattern != "" {
+		str, _ := doc.(string)
+		if matched, err := regexp.MatchString(s.Pattern, str); err != nil || !matched {
+			return fail("%q does not match %s", str, s.Pattern)
+		}
+	}
+
+	var errs []*ValidationError
+
+	if len(s.OneOf) > 0 {
+		errs = append(errs, v.validateOneOf(s.OneOf, doc, path)...)
+	}
+
+	switch value := doc.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				errs = append(errs, fail("missing required property %q", name)...)
+			}
+		}
+
+		for name, child := range value {
+			childPath := path + "/" + escape(name)
+
+			if s.PropertyNames != nil {
+				errs = append(errs, v.validate(s.PropertyNames, name, childPath)...)
+			}
+
+			if prop, ok := s.Properties[name]; ok {
+				errs = append(errs, v.validate(prop, child, childPath)...)
+			} else if s.AdditionalProperties != nil {
+				errs = append(errs, v.validate(s.AdditionalProperties, child, childPath)...)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for idx, child := range value {
+				errs = append(errs, v.validate(s.Items, child, fmt.Sprintf("%s/%d", path, idx))...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateOneOf requires exactly one alternative to match. Alternatives that
+// are discriminated by a constant "type" property are selected directly, so
+// that errors can be reported against the intended variant. Otherwise, when
+// none match, the errors from the closest alternative are reported.
+func (v *validator) validateOneOf(alternatives []*Schema, doc interface{}, path string) []*ValidationError {
+	if object, ok := doc.(map[string]interface{}); ok {
+		if alternative, discriminated := v.discriminate(alternatives, object); discriminated {
+			if alternative == nil {
+				return []*ValidationError{&ValidationError{Path: path + "/type", Message: fmt.Sprintf("unknown type %v", object["type"])}}
+			}
+			return v.validate(alternative, doc, path)
+		}
+	}
+
+	var closest []*ValidationError
+	matches := 0
+
+	for idx, alternative := range alternatives {
+		errs := v.validate(alternative, doc, path)
+		if len(errs) == 0 {
+			matches++
+		} else if idx == 0 || len(errs) < len(closest) {
+			closest = errs
+		}
+	}
+
+	switch {
+	case matches == 1:
+		return nil
+	case matches > 1:
+		return []*ValidationError{&ValidationError{Path: path, Message: fmt.Sprintf("matches %d alternatives, expected exactly one", matches)}}
+	}
+
+	return closest
+}
+
+// discriminate returns the alternative whose constant "type" property matches
+// the given object, and whether the alternatives are discriminated at all
+func (v *validator) discriminate(alternatives []*Schema, object map[string]interface{}) (*Schema, bool) {
+	for _, alternative := range alternatives {
+		if alternative.Ref != "" {
+			def, err := v.resolve(alternative.Ref)
+			if err != nil {
+				return nil, false
+			}
+			alternative = def
+		}
+
+		discriminator, ok := alternative.Properties["type"]
+		if !ok || discriminator.Const == nil {
+			return nil, false
+		}
+
+		if reflect.DeepEqual(discriminator.Const, object["type"]) {
+			return alternative, true
+		}
+	}
+
+	return nil, true
+}
+
+func hasType(doc interface{}, t string) bool {
+	switch t {
+	case "integer":
+		f, ok := doc.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := doc.(float64)
+		return ok
+	}
+
+	return typeOf(doc) == t
+}
+
+func typeOf(doc interface{}) string {
+	switch doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", doc)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// escape encodes a property name for use in a JSON pointer
+func escape(name string) string {
+	name = strings.Replace(name, "~", "~0", -1)
+	return strings.Replace(name, "/", "~1", -1)
+}
@@ -0,0 +1,227 @@
+package orcbrew
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+const exampleFile = "schema/example.orcbrew"
+
+func readExample(t *testing.T) *File {
+	f, err := ReadFile(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestToJSON(t *testing.T) {
+	file, err := os.Open(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	jsonString, err := ToJSON(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedBytes, err := ioutil.ReadFile("schema/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result, expected interface{}
+	if err := json.Unmarshal([]byte(jsonString), &result); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(expectedBytes, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(result, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestRead(t *testing.T) {
+	f := readExample(t)
+
+	if f.ExportAll {
+		t.Error("Expected example to be a single source")
+	}
+
+	source, ok := f.Packs["Test"]
+	if !ok {
+		t.Fatalf("Expected source keyed by option pack Test, got %v", f.Packs)
+	}
+
+	if source.Spells["myspell"].School != "necromancy" {
+		t.Errorf("Expected myspell to be read, got %+v", source.Spells["myspell"])
+	}
+}
+
+func TestReadUnknown(t *testing.T) {
+	if f := readExample(t); len(f.Unknown) != 0 {
+		t.Errorf("Expected every key of the example to be in the schema, got %v", f.Unknown)
+	}
+
+	tests := []struct {
+		edn      string
+		expected []string
+	}{
+		{
+			`{:orcpub.dnd.e5/spells {:myspell {:key :myspell, :name "MySpell", :source "PHB"}}}`,
+			[]string{"spells/myspell/source"},
+		},
+		{
+			`{"Test" {:orcpub.dnd.e5/races {:myrace {:key :myrace, :option-pack "Test", :size :medium, :flavour "x", :traits [{:name "T", :colour :red}]}}}}`,
+			[]string{"Test/races/myrace/flavour", "Test/races/myrace/traits/0/colour"},
+		},
+	}
+	for _, test := range tests {
+		f, err := Read(strings.NewReader(test.edn))
+		if err != nil {
+			t.Errorf("%s: %s", test.edn, err)
+			continue
+		}
+		if diff := deep.Equal(f.Unknown, test.expected); diff != nil {
+			t.Errorf("%s: %v", test.edn, diff)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	for _, exportAll := range []bool{false, true} {
+		f := readExample(t)
+		f.ExportAll = exportAll
+
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := Read(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Error reading written file: %s\n%s", err, buf.String())
+		}
+
+		if result.ExportAll != exportAll {
+			t.Errorf("Expected ExportAll to be %v", exportAll)
+		}
+
+		if diff := deep.Equal(result.Packs, f.Packs); diff != nil {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestWriteTypes(t *testing.T) {
+	f := readExample(t)
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	expected := []string{
+		`{:orcpub.dnd.e5/backgrounds`,
+		`:abilities {:orcpub.dnd.e5.character/con 1, :orcpub.dnd.e5.character/str 1}`,
+		`:languages #{"Abyssal"}`,
+		`:school "necromancy"`,
+		`:subclass-title "MySubclassTitle"`,
+		`:class :barbarian`,
+		`:saving-throws {:cha 1, :con 1, :dex 0, :int 1, :str 0, :wis 0}`,
+		`:value {:ability :orcpub.dnd.e5.character/str, :key :druidcraft}`,
+		`1 #{:burning-hands :speak-with-animals}`,
+		"#{:orcpub.dnd.e5.character/str\n     :saves?",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(output, s) {
+			t.Errorf("Expected output to contain %s", s)
+		}
+	}
+}
+
+func TestWriteSource(t *testing.T) {
+	source := schema.OrcbrewSource{
+		Languages: map[string]schema.LanguageConfig{
+			"pig-latin": schema.LanguageConfig{
+				Key:         "pig-latin",
+				OptionPack:  "Test",
+				Name:        "Pig latin",
+				Description: "Line one\n\"Line two\"",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSource(&buf, source); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{:orcpub.dnd.e5/languages
+ {:pig-latin
+  {:description "Line one\n\"Line two\""
+   :key :pig-latin
+   :name "Pig latin"
+   :option-pack "Test"}}}
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteConflicts(t *testing.T) {
+	ours := schema.LanguageConfig{Key: "pig-latin", OptionPack: "Test", Name: "Pig latin", Description: "Igpay"}
+	theirs := schema.LanguageConfig{Key: "pig-latin", OptionPack: "Test", Name: "Dog latin", Description: "Igpay"}
+	deleted := schema.LanguageConfig{Key: "gibberish", OptionPack: "Test", Name: "Gibberish", Description: "Blah"}
+
+	f := &File{Packs: schema.OrcbrewExportAll{
+		"Test": schema.OrcbrewSource{Languages: map[string]schema.LanguageConfig{"pig-latin": ours}},
+	}}
+	conflicts := []schema.Merge3Conflict{
+		{Pack: "Test", Kind: "languages", Key: "gibberish", Theirs: deleted},
+		{Pack: "Test", Kind: "languages", Key: "pig-latin", Fields: []string{"name"}, Ours: ours, Theirs: theirs},
+	}
+
+	var buf bytes.Buffer
+	if err := f.WriteConflicts(&buf, conflicts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{:orcpub.dnd.e5/languages
+ {:gibberish
+<<<<<<< ours
+  nil
+=======
+  {:description "Blah", :key :gibberish, :name "Gibberish", :option-pack "Test"}
+>>>>>>> theirs
+  :pig-latin
+<<<<<<< ours
+  {:description "Igpay"
+   :key :pig-latin
+   :name "Pig latin"
+   :option-pack "Test"}
+=======
+  {:description "Igpay"
+   :key :pig-latin
+   :name "Dog latin"
+   :option-pack "Test"}
+>>>>>>> theirs
+ }}
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
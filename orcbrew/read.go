@@ -0,0 +1,357 @@
+// Package orcbrew reads and writes .orcbrew files, the EDN exports produced
+// by OrcPub, converting them to and from the types in the schema package.
+package orcbrew
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cespare/goclj/parse"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// Namespaces used by keywords in .orcbrew files
+const (
+	NamespaceE5        = "orcpub.dnd.e5"
+	NamespaceCharacter = "orcpub.dnd.e5.character"
+)
+
+// File is the contents of an .orcbrew file, which can either contain a single
+// source or an "Export All" of several option packs
+type File struct {
+	Packs     schema.OrcbrewExportAll // the sources in the file, by option pack
+	ExportAll bool                    // whether the file is an "Export All"
+
+	// Unknown are the paths of the keys in the file that the schema doesn't
+	// have, e.g. "spells/fireball/source". They aren't read into Packs, so
+	// they are lost when the file is written.
+	Unknown []string
+}
+
+// Source returns the only source in the file, or an error when the file
+// contains more than one option pack
+func (f *File) Source() (schema.OrcbrewSource, error) {
+	if len(f.Packs) > 1 {
+		return schema.OrcbrewSource{}, fmt.Errorf("Expected a single option pack, got %d", len(f.Packs))
+	}
+
+	for _, source := range f.Packs {
+		return source, nil
+	}
+	return schema.OrcbrewSource{}, nil
+}
+
+// ToJSON converts the EDN contents of an .orcbrew file into JSON
+func ToJSON(r io.Reader) (string, error) {
+	contentsBytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	// Remove BOM if its at the start of the file
+	contentsBytes = bytes.TrimLeft(contentsBytes, "\xef\xbb\xbf")
+
+	buf := bytes.NewBufferString(stripNamespaces(string(contentsBytes)))
+
+	tt, err := parse.Reader(buf, "input.clj", 0)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing as Clojure: %s", err)
+	}
+
+	return treeToJSON(tt), nil
+}
+
+// namespaces are the replacements that remove OrcPub's namespaces from
+// keywords and maps, in order
+var namespaces = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`#:orcpub.dnd.e5{`), "{"},
+	{regexp.MustCompile(`#:orcpub.dnd.e5.character{`), "{"},
+	{regexp.MustCompile(`#:orcpub.dnd.e5.character`), "#"},
+	{regexp.MustCompile(`:orcpub.dnd.e5.character/`), ""},
+	{regexp.MustCompile(`orcpub.dnd.e5/`), ""},
+	{regexp.MustCompile(`orcpub.dnd.e5.[a-z-]*/`), ""},
+}
+
+// stripNamespaces removes OrcPub's namespaces from EDN, e.g.
+// :orcpub.dnd.e5/spells becomes :spells
+func stripNamespaces(contents string) string {
+	for _, ns := range namespaces {
+		contents = ns.re.ReplaceAllString(contents, ns.repl)
+	}
+	return contents
+}
+
+// Read parses the contents of an .orcbrew file. A file containing a single
+// source is returned keyed by the option pack its entities belong to.
+func Read(r io.Reader) (*File, error) {
+	jsonString, err := ToJSON(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeJSON([]byte(jsonString))
+}
+
+// ReadFile reads an .orcbrew file, see Read. A single source whose entities
+// don't name an option pack is keyed by the file name instead.
+func ReadFile(filename string) (*File, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := Read(bytes.NewReader(contents))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %s", filename, err)
+	}
+
+	if source, ok := f.Packs[""]; ok && !f.ExportAll {
+		delete(f.Packs, "")
+		f.Packs[strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))] = source
+	}
+
+	return f, nil
+}
+
+func decodeJSON(jsonBytes []byte) (*File, error) {
+	var top map[string]json.RawMessage
+	err := json.Unmarshal(jsonBytes, &top)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{Packs: make(schema.OrcbrewExportAll)}
+	for key := range top {
+		if !schema.IsKind(key) {
+			f.ExportAll = true
+		}
+	}
+
+	var generic map[string]interface{}
+	if err := json.Unmarshal(jsonBytes, &generic); err != nil {
+		return nil, err
+	}
+	sourceType := reflect.TypeOf(schema.OrcbrewSource{})
+
+	if f.ExportAll {
+		for pack, value := range generic {
+			f.Unknown = append(f.Unknown, unknownKeys(value, sourceType, pack)...)
+		}
+		sort.Strings(f.Unknown)
+		err = json.Unmarshal(jsonBytes, &f.Packs)
+		return f, err
+	}
+	f.Unknown = unknownKeys(generic, sourceType, "")
+	sort.Strings(f.Unknown)
+
+	var source schema.OrcbrewSource
+	err = json.Unmarshal(jsonBytes, &source)
+	if err != nil {
+		return nil, err
+	}
+
+	var pack string
+	if packs := source.OptionPacks(); len(packs) > 0 {
+		pack = packs[0]
+	}
+	f.Packs[pack] = source
+
+	return f, nil
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownKeys returns the paths of the keys of a decoded JSON value that
+// have no field in the type it's unmarshalled into
+func unknownKeys(value interface{}, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		// Decoded by its own rules, e.g. level modifiers
+		return nil
+	}
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "/" + key
+	}
+
+	var unknown []string
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		for key, v := range m {
+			field, ok := fields[strings.ToLower(key)]
+			if !ok {
+				unknown = append(unknown, join(key))
+				continue
+			}
+			unknown = append(unknown, unknownKeys(v, field, join(key))...)
+		}
+	case reflect.Map:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for key, v := range m {
+			unknown = append(unknown, unknownKeys(v, t.Elem(), join(key))...)
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for idx, v := range list {
+			unknown = append(unknown, unknownKeys(v, t.Elem(), join(strconv.Itoa(idx)))...)
+		}
+	}
+	return unknown
+}
+
+// jsonFields returns the types of the fields of a struct by their JSON name,
+// in lower case as encoding/json matches them
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for name, fieldType := range jsonFields(field.Type) {
+				fields[name] = fieldType
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[strings.ToLower(name)] = field.Type
+	}
+	return fields
+}
+
+func treeToJSON(tree *parse.Tree) string {
+	return nodesToJSON(tree.Roots, 0)
+}
+
+func nodesToJSON(nodes []parse.Node, depth int) string {
+	var buf bytes.Buffer
+	for _, node := range nodes {
+		buf.WriteString(strings.Repeat("  ", depth))
+		buf.WriteString(nodeToJSON(node))
+		buf.WriteString("\n")
+	}
+
+	return buf.String()
+}
+
+func nodeToJSON(node parse.Node) string {
+	switch v := node.(type) {
+
+	case *parse.KeywordNode:
+		return fmt.Sprintf(`"%s"`, v.Val[1:])
+	case *parse.StringNode:
+		return fmt.Sprintf(`"%s"`, v.Val)
+	case *parse.NumberNode:
+		return v.Val
+	case *parse.SymbolNode:
+		return fmt.Sprintf(`"%s"`, v.Val)
+	case *parse.SetNode:
+		var vals []string
+		for _, node := range filterNonValueNodes(v.Children()) {
+			vals = append(vals, nodeToJSON(node))
+		}
+
+		return fmt.Sprintf("[%s]", strings.Join(vals, ","))
+	case *parse.ListNode:
+		var vals []string
+		for _, node := range filterNonValueNodes(v.Children()) {
+			vals = append(vals, nodeToJSON(node))
+		}
+
+		return fmt.Sprintf("[%s]", strings.Join(vals, ","))
+	case *parse.VectorNode:
+		var vals []string
+		for _, node := range filterNonValueNodes(v.Children()) {
+			vals = append(vals, nodeToJSON(node))
+		}
+
+		return fmt.Sprintf("[%s]", strings.Join(vals, ","))
+	case *parse.MapNode:
+		var keys []parse.Node
+		var vals []parse.Node
+
+		children := v.Children()
+		children = filterNonValueNodes(children)
+
+		if (len(children) % 2) != 0 {
+			panic(v.String())
+		}
+		for idx, node := range children {
+			if idx == 0 || (idx%2) == 0 {
+				keys = append(keys, node)
+			} else {
+				// The value might be empty, remove the key in that case
+				_, isNilValue := node.(*parse.NilNode)
+				if isNilValue {
+					keys = keys[0 : len(keys)-1]
+				} else {
+					vals = append(vals, node)
+				}
+			}
+		}
+
+		var entries []string
+		for idx, key := range keys {
+			var val = vals[idx]
+			var keyString = nodeToJSON(key)
+			if keyString[0] != '"' {
+				keyString = fmt.Sprintf(`"%s"`, keyString)
+			}
+			entries = append(entries, fmt.Sprintf("%s: %s", keyString, nodeToJSON(val)))
+		}
+
+		return fmt.Sprintf("{%s}", strings.Join(entries, ","))
+	case *parse.NewlineNode:
+		return ""
+	default:
+		return node.String()
+	}
+}
+
+func filterNonValueNodes(nodes []parse.Node) []parse.Node {
+	var result []parse.Node
+
+	for _, node := range nodes {
+		switch v := node.(type) {
+		case *parse.NewlineNode:
+			break
+		case *parse.CommentNode:
+			break
+		default:
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,350 @@
+// Package render turns option packs into documents for players to read. The
+// documents are produced by executing templates against a Book, so they can
+// be restyled by supplying different templates.
+package render
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// Book is the view of a source that templates are executed against, with
+// entities sorted by name and related entities grouped together
+type Book struct {
+	Title string
+
+	Classes     []*Class
+	Subclasses  []*SubclassGroup // subclasses of classes not defined in the source
+	Races       []*Race
+	Subraces    []*SubraceGroup // subraces of races not defined in the source
+	Backgrounds []schema.BackgroundConfig
+	Feats       []schema.FeatConfig
+	Invocations []schema.InvocationConfig
+	Selections  []schema.SelectionConfig
+	Languages   []schema.LanguageConfig
+	Spells      []*SpellLevel
+	Monsters    []schema.MonsterConfig
+	Encounters  []schema.EncounterConfig
+
+	index *schema.Index
+}
+
+// Class is a class along with its level table and subclasses
+type Class struct {
+	schema.ClassConfig
+	Levels     []*Level
+	Subclasses []schema.SubclassConfig
+}
+
+// Level is a row of a class's level table
+type Level struct {
+	Level            int
+	ProficiencyBonus int
+	SpellsKnown      int // the total from the class's spells-known schedule, if any
+	Features         []string
+}
+
+// SubclassGroup holds the subclasses for a class that is defined elsewhere,
+// usually one of the built-in classes
+type SubclassGroup struct {
+	Class      string // the key of the class
+	Subclasses []schema.SubclassConfig
+}
+
+// Race is a race along with its subraces
+type Race struct {
+	schema.RaceConfig
+	Subraces []schema.SubraceConfig
+}
+
+// SubraceGroup holds the subraces for a race that is defined elsewhere
+type SubraceGroup struct {
+	Race     string // the key of the race
+	Subraces []schema.SubraceConfig
+}
+
+// SpellLevel holds the spells of one level
+type SpellLevel struct {
+	Level  int
+	Spells []schema.SpellConfig
+}
+
+// NewBook returns the view of a source used to render it
+func NewBook(title string, source schema.OrcbrewSource) *Book {
+	b := &Book{Title: title, index: schema.NewIndex(source)}
+
+	for _, class := range source.Classes {
+		b.Classes = append(b.Classes, &Class{ClassConfig: class})
+	}
+	sort.Slice(b.Classes, func(i, j int) bool { return lessByName(b.Classes[i].ClassConfig, b.Classes[j].ClassConfig) })
+	for _, class := range b.Classes {
+		class.Levels = levelTable(class.ClassConfig, b)
+	}
+
+	for _, subclass := range sortedEntities(source.Subclasses).([]schema.SubclassConfig) {
+		if class := b.class(subclass.Class); class != nil {
+			class.Subclasses = append(class.Subclasses, subclass)
+			continue
+		}
+
+		var group *SubclassGroup
+		for _, g := range b.Subclasses {
+			if g.Class == subclass.Class {
+				group = g
+			}
+		}
+		if group == nil {
+			group = &SubclassGroup{Class: subclass.Class}
+			b.Subclasses = append(b.Subclasses, group)
+		}
+		group.Subclasses = append(group.Subclasses, subclass)
+	}
+	sort.Slice(b.Subclasses, func(i, j int) bool { return b.Subclasses[i].Class < b.Subclasses[j].Class })
+
+	for _, race := range sortedEntities(source.Races).([]schema.RaceConfig) {
+		b.Races = append(b.Races, &Race{RaceConfig: race})
+	}
+	for _, subrace := range sortedEntities(source.Subraces).([]schema.SubraceConfig) {
+		if race := b.race(subrace.Race); race != nil {
+			race.Subraces = append(race.Subraces, subrace)
+			continue
+		}
+
+		var group *SubraceGroup
+		for _, g := range b.Subraces {
+			if g.Race == subrace.Race {
+				group = g
+			}
+		}
+		if group == nil {
+			group = &SubraceGroup{Race: subrace.Race}
+			b.Subraces = append(b.Subraces, group)
+		}
+		group.Subraces = append(group.Subraces, subrace)
+	}
+	sort.Slice(b.Subraces, func(i, j int) bool { return b.Subraces[i].Race < b.Subraces[j].Race })
+
+	spells := sortedEntities(source.Spells).([]schema.SpellConfig)
+	sort.SliceStable(spells, func(i, j int) bool { return spells[i].Level < spells[j].Level })
+	for _, spell := range spells {
+		if len(b.Spells) == 0 || b.Spells[len(b.Spells)-1].Level != spell.Level {
+			b.Spells = append(b.Spells, &SpellLevel{Level: spell.Level})
+		}
+		level := b.Spells[len(b.Spells)-1]
+		level.Spells = append(level.Spells, spell)
+	}
+
+	b.Backgrounds = sortedEntities(source.Backgrounds).([]schema.BackgroundConfig)
+	b.Feats = sortedEntities(source.Feats).([]schema.FeatConfig)
+	b.Invocations = sortedEntities(source.Invocations).([]schema.InvocationConfig)
+	b.Selections = sortedEntities(source.Selections).([]schema.SelectionConfig)
+	b.Languages = sortedEntities(source.Languages).([]schema.LanguageConfig)
+	b.Monsters = sortedEntities(source.Monsters).([]schema.MonsterConfig)
+	b.Encounters = sortedEntities(source.Encounters).([]schema.EncounterConfig)
+
+	return b
+}
+
+func (b *Book) class(key string) *Class {
+	for _, class := range b.Classes {
+		if class.Key == key {
+			return class
+		}
+	}
+	return nil
+}
+
+func (b *Book) race(key string) *Race {
+	for _, race := range b.Races {
+		if race.Key == key {
+			return race
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the entity of the given kind and key, or a title
+// made from the key for entities not in the book, e.g. built-in classes
+func (b *Book) Name(kind string, key string) string {
+	if entity, ok := b.index.Lookup(kind, key); ok && entity.EntityName() != "" {
+		return entity.EntityName()
+	}
+	return Title(key)
+}
+
+// levelTable returns the rows of a class's level table, listing the features
+// gained at each level
+func levelTable(class schema.ClassConfig, b *Book) []*Level {
+	var levels []*Level
+	for level := 1; level <= 20; level++ {
+		levels = append(levels, &Level{Level: level, ProficiencyBonus: 2 + (level-1)/4})
+	}
+
+	add := func(level int, feature string) {
+		if level < 1 {
+			level = 1
+		}
+		if level <= len(levels) {
+			levels[level-1].Features = append(levels[level-1].Features, feature)
+		}
+	}
+
+	if class.SubclassLevel > 0 {
+		title := class.SubclassTitle
+		if title == "" {
+			title = "Subclass"
+		}
+		add(class.SubclassLevel, title)
+	}
+	for _, level := range class.AbilityIncreaseLevels {
+		add(level, "Ability Score Improvement")
+	}
+	for _, selection := range class.LevelSelections {
+		add(selection.Level, fmt.Sprintf("%s (%d)", b.Name("selections", selection.Type), selection.Num))
+	}
+	for _, trait := range class.Traits {
+		add(trait.Level, trait.Name)
+	}
+	for _, modifier := range class.LevelModifiers {
+		add(ModifierLevel(modifier), b.Modifier(modifier))
+	}
+
+	if class.Spellcasting != nil {
+		known := 0
+		for _, level := range levels {
+			known += class.Spellcasting.SpellsKnown[level.Level]
+			level.SpellsKnown = known
+		}
+	}
+
+	return levels
+}
+
+// LevelFeature is something gained at a level, such as a level selection or a
+// level modifier
+type LevelFeature struct {
+	Level int
+	Text  string
+}
+
+// LevelFeatures returns the level selections and level modifiers of a class or
+// subclass as a single list sorted by level, with those that apply from the
+// first level at level 1
+func (b *Book) LevelFeatures(selections []schema.LevelSelection, modifiers schema.LevelModifierList) []LevelFeature {
+	var features []LevelFeature
+	add := func(level int, text string) {
+		if level < 1 {
+			level = 1
+		}
+		features = append(features, LevelFeature{Level: level, Text: text})
+	}
+
+	for _, selection := range selections {
+		add(selection.Level, fmt.Sprintf("%s (%d)", b.Name("selections", selection.Type), selection.Num))
+	}
+	for _, modifier := range modifiers {
+		add(ModifierLevel(modifier), b.Modifier(modifier))
+	}
+	sort.SliceStable(features, func(i, j int) bool { return features[i].Level < features[j].Level })
+	return features
+}
+
+// Modifier describes a level modifier, e.g. "Skill proficiency: Athletics"
+func (b *Book) Modifier(modifier schema.LevelModifier) string {
+	value := reflect.ValueOf(modifier).Elem().FieldByName("Value").Interface()
+
+	var text string
+	switch v := value.(type) {
+	case schema.SpellWithAbility:
+		text = b.Name("spells", v.Key)
+		if v.Ability != "" {
+			text += " (" + Title(string(v.Ability)) + ")"
+		}
+	case int:
+		text = strconv.Itoa(v)
+	default:
+		text = Title(fmt.Sprint(v))
+	}
+
+	label, ok := modifierLabels[string(modifier.Type())]
+	if !ok {
+		label = Title(string(modifier.Type()))
+	}
+	return label + ": " + text
+}
+
+var modifierLabels = map[string]string{
+	"armor-prof":                        "Armor proficiency",
+	"damage-immunity":                   "Damage immunity",
+	"damage-resistance":                 "Damage resistance",
+	"flying-speed":                      "Flying speed",
+	"flying-speed-equals-walking-speed": "Flying speed equal to walking speed",
+	"num-attacks":                       "Attacks",
+	"saving-throw-advantage":            "Advantage on saving throws against",
+	"skill-prof":                        "Skill proficiency",
+	"spell":                             "Spell",
+	"swimming-speed":                    "Swimming speed",
+	"tool-prof":                         "Tool proficiency",
+	"weapon-prof":                       "Weapon proficiency",
+}
+
+// ModifierLevel returns the level at which a modifier applies, with 0 meaning
+// from the first level
+func ModifierLevel(modifier schema.LevelModifier) int {
+	return int(reflect.ValueOf(modifier).Elem().FieldByName("Level").Int())
+}
+
+// abilityNames are the titles of abbreviated abilities
+var abilityNames = map[string]string{
+	"str": "Strength",
+	"dex": "Dexterity",
+	"con": "Constitution",
+	"int": "Intelligence",
+	"wis": "Wisdom",
+	"cha": "Charisma",
+}
+
+// Title turns a keyword such as "animal-handling" into "Animal Handling", and
+// abilities such as "str" into their full names
+func Title(keyword string) string {
+	if name, ok := abilityNames[keyword]; ok {
+		return name
+	}
+
+	words := strings.FieldsFunc(keyword, func(r rune) bool { return r == '-' || r == '_' })
+	for idx, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		words[idx] = string(unicode.ToUpper(r)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// sortedEntities returns the values of a map of entities as a slice, sorted
+// by name and then key
+func sortedEntities(entities interface{}) interface{} {
+	m := reflect.ValueOf(entities)
+	list := reflect.MakeSlice(reflect.SliceOf(m.Type().Elem()), 0, m.Len())
+	for _, key := range m.MapKeys() {
+		list = reflect.Append(list, m.MapIndex(key))
+	}
+
+	sort.Slice(list.Interface(), func(i, j int) bool {
+		return lessByName(list.Index(i).Interface().(schema.Entity), list.Index(j).Interface().(schema.Entity))
+	})
+	return list.Interface()
+}
+
+func lessByName(a schema.Entity, b schema.Entity) bool {
+	nameA, nameB := strings.ToLower(a.EntityName()), strings.ToLower(b.EntityName())
+	if nameA != nameB {
+		return nameA < nameB
+	}
+	return a.EntityKey() < b.EntityKey()
+}
@@ -0,0 +1,241 @@
+package render
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// Funcs returns the functions available to templates rendering the book:
+//
+//	name KIND KEY          the name of an entity, see Book.Name
+//	names KIND SET         the names of the entities in a set, joined by commas
+//	modifier MODIFIER      a description of a level modifier
+//	modifierLevel MODIFIER the level a modifier applies from
+//	levelFeatures SELECTIONS MODIFIERS
+//	                       level selections and modifiers sorted by level, see Book.LevelFeatures
+//	treasure MAP           amounts of currency, e.g. "5 sp, 10 gp"
+//	title KEYWORD          a keyword as a title, e.g. "Animal Handling"
+//	list SET               the titles of the members of a set or list, joined by commas
+//	bonuses MAP            the titles and signed values of a map, e.g. "Strength +1"
+//	keys MAP               the sorted keys of a map whose values are set
+//	join LIST SEP          strings.Join
+//	ordinal N              1st, 2nd, 3rd...
+//	spellLevel N           "Cantrip" or "1st-level"
+//	abilityMod SCORE       the modifier for an ability score, e.g. "+2"
+//	hitPoints MONSTER      average hit points and hit dice, e.g. "13 (3d8)"
+//	challenge CR           a challenge rating, e.g. "1/4"
+//	components COMPONENTS  spell components, e.g. "V, S, M (a pinch of salt)"
+//	traits TRAITS TYPE     the monster traits of a type, "" for plain traits
+//	lines TEXT             text split into its non-empty lines
+func (b *Book) Funcs() map[string]interface{} {
+	return map[string]interface{}{
+		"name":          b.Name,
+		"names":         b.names,
+		"modifier":      b.Modifier,
+		"modifierLevel": ModifierLevel,
+		"levelFeatures": b.LevelFeatures,
+		"treasure":      Treasure,
+		"title":         Title,
+		"list":          list,
+		"bonuses":       bonuses,
+		"keys":          Keys,
+		"join":          strings.Join,
+		"ordinal":       Ordinal,
+		"spellLevel":    SpellLevelName,
+		"abilityMod":    AbilityModifier,
+		"hitPoints":     HitPoints,
+		"challenge":     Challenge,
+		"components":    Components,
+		"traits":        monsterTraits,
+		"lines":         lines,
+	}
+}
+
+func (b *Book) names(kind string, set interface{}) string {
+	var names []string
+	for _, key := range Keys(set) {
+		names = append(names, b.Name(kind, key))
+	}
+	return strings.Join(names, ", ")
+}
+
+func list(set interface{}) string {
+	var titles []string
+	if v := reflect.ValueOf(set); v.Kind() == reflect.Slice {
+		for idx := 0; idx < v.Len(); idx++ {
+			titles = append(titles, Title(fmt.Sprint(v.Index(idx).Interface())))
+		}
+	} else {
+		for _, key := range Keys(set) {
+			titles = append(titles, Title(key))
+		}
+	}
+	return strings.Join(titles, ", ")
+}
+
+func bonuses(m interface{}) string {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		return ""
+	}
+
+	var parts []string
+	for _, key := range Keys(m) {
+		value := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		bonus := fmt.Sprint(value.Interface())
+		if !strings.HasPrefix(bonus, "-") {
+			bonus = "+" + bonus
+		}
+		parts = append(parts, Title(key)+" "+bonus)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Keys returns the sorted keys of a map whose values are true or non-zero,
+// as used by .orcbrew files for sets
+func Keys(m interface{}) []string {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		return nil
+	}
+
+	var keys []string
+	for _, key := range v.MapKeys() {
+		value := v.MapIndex(key)
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+		if value.IsValid() && !value.IsZero() {
+			keys = append(keys, fmt.Sprint(key.Interface()))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Treasure formats amounts of currency from copper up, e.g. "5 sp, 10 gp"
+func Treasure(treasure map[schema.Currency]int) string {
+	var parts []string
+	for _, currency := range schema.Currency("").Values() {
+		if n := treasure[schema.Currency(currency)]; n != 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, currency))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Ordinal returns n with its ordinal suffix, e.g. "2nd"
+func Ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// SpellLevelName returns "Cantrip" for level 0 and e.g. "3rd-level" otherwise
+func SpellLevelName(level int) string {
+	if level == 0 {
+		return "Cantrip"
+	}
+	return Ordinal(level) + "-level"
+}
+
+// AbilityModifier returns the signed modifier for an ability score
+func AbilityModifier(score int) string {
+	modifier := score/2 - 5
+	if modifier >= 0 {
+		return "+" + strconv.Itoa(modifier)
+	}
+	return strconv.Itoa(modifier)
+}
+
+// HitPoints formats the average hit points of a monster along with its hit
+// dice and the bonus from its constitution, e.g. "27 (5d8 + 5)"
+func HitPoints(monster schema.MonsterConfig) string {
+	if monster.HitPoints == nil || monster.HitPoints.DieCount == 0 {
+		return ""
+	}
+
+	count, die := monster.HitPoints.DieCount, monster.HitPoints.Die
+	bonus := count * (monster.Con/2 - 5)
+	average := count*(die+1)/2 + bonus
+	if average < 1 {
+		average = 1
+	}
+
+	switch {
+	case bonus > 0:
+		return fmt.Sprintf("%d (%dd%d + %d)", average, count, die, bonus)
+	case bonus < 0:
+		return fmt.Sprintf("%d (%dd%d - %d)", average, count, die, -bonus)
+	}
+	return fmt.Sprintf("%d (%dd%d)", average, count, die)
+}
+
+// Challenge formats a challenge rating, using fractions below 1
+func Challenge(challenge float32) string {
+	switch challenge {
+	case 0.125:
+		return "1/8"
+	case 0.25:
+		return "1/4"
+	case 0.5:
+		return "1/2"
+	}
+	return strconv.FormatFloat(float64(challenge), 'f', -1, 32)
+}
+
+// Components formats the components of a spell
+func Components(components *schema.SpellComponents) string {
+	if components == nil {
+		return ""
+	}
+
+	var parts []string
+	if components.Verbal {
+		parts = append(parts, "V")
+	}
+	if components.Somatic {
+		parts = append(parts, "S")
+	}
+	if components.Material {
+		if components.MaterialComponent != "" {
+			parts = append(parts, "M ("+components.MaterialComponent+")")
+		} else {
+			parts = append(parts, "M")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func monsterTraits(traits []schema.MonsterTrait, traitType string) []schema.MonsterTrait {
+	var result []schema.MonsterTrait
+	for _, trait := range traits {
+		if string(trait.Type) == traitType {
+			result = append(result, trait)
+		}
+	}
+	return result
+}
+
+func lines(text string) []string {
+	var result []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
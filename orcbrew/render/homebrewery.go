@@ -0,0 +1,265 @@
+package render
+
+// HomebreweryTemplate renders a book as Markdown in the V3 dialect of
+// Homebrewery (https://homebrewery.naturalcrit.com), which GM Binder also
+// reads. Monsters are stat blocks in {{monster,frame}} blocks, classes have
+// a {{classTable,frame}} level table, and each chapter starts on a new page.
+// Homebrewery's braces are written as {{ "{{" }} and {{ "}}" }} so that they
+// aren't taken as template actions.
+const HomebreweryTemplate = partials + `{{ define "hbTraits" }}
+{{- range . }}
+***{{ .Name }}.*** {{ range $idx, $line := lines .Description }}{{ if $idx }}
+
+{{ end }}{{ $line }}{{ end }}
+
+{{ end }}
+{{- end }}
+
+{{- define "hbSubclass" }}
+## {{ .Name }}
+{{ template "proficiencies" . }}
+{{- template "levelFeatures" . }}
+
+{{ template "hbTraits" .Traits }}
+{{- end }}
+
+{{- define "hbClass" }}
+
+\page
+
+# {{ .Name }}
+
+{{ "{{" }}classTable,frame
+##### The {{ .Name }}
+{{- $known := false }}{{ with .Spellcasting }}{{ if .SpellsKnown }}{{ $known = true }}{{ end }}{{ end }}
+| Level | Proficiency Bonus | Features |{{ if $known }} Spells Known |{{ end }}
+|:-----:|:-----------------:|:---------|{{ if $known }}:------------:|{{ end }}
+{{- range .Levels }}
+| {{ ordinal .Level }} | +{{ .ProficiencyBonus }} | {{ if .Features }}{{ join .Features ", " }}{{ else }}—{{ end }} |{{ if $known }} {{ or .SpellsKnown "—" }} |{{ end }}
+{{- end }}
+{{ "}}" }}
+
+## Class Features
+
+- **Hit Die:** d{{ .HitDie }}
+{{- template "proficiencies" . }}
+{{- with .Spellcasting }}
+- **Spellcasting Ability:** {{ title (print .Ability) }}
+{{- end }}
+
+{{ template "hbTraits" .Traits }}
+{{- range .Subclasses }}
+{{ template "hbSubclass" . }}
+{{- end }}
+{{- end }}
+
+{{- define "hbRace" }}
+## {{ .Name }}
+{{ template "raceDetails" . }}
+
+{{ template "hbTraits" .Traits }}
+{{- end }}
+
+{{- define "hbBackground" }}
+## {{ .Name }}
+{{ template "backgroundDetails" . }}
+
+{{ template "hbTraits" .Traits }}
+{{- end }}
+
+{{- define "hbFeat" }}
+#### {{ .Name }}
+{{ if or .Prereqs .PathPrereqs.Race }}
+*Prerequisite: {{ list .Prereqs }}{{ if and .Prereqs .PathPrereqs.Race }}, {{ end }}{{ names "races" .PathPrereqs.Race }}*
+{{ end }}
+{{ range lines .Description }}{{ . }}
+
+{{ end }}
+{{- end }}
+
+{{- define "hbSpell" }}
+#### {{ .Name }}
+*{{ if .Level }}{{ spellLevel .Level }} {{ .School }}{{ else }}{{ title .School }} cantrip{{ end }}{{ if .Ritual }} (ritual){{ end }}*
+___
+- **Casting Time:** {{ .CastingTime }}
+- **Range:** {{ .Range }}
+- **Components:** {{ components .Components }}
+- **Duration:** {{ .Duration }}
+
+{{ range lines .Description }}{{ . }}
+
+{{ end }}
+{{- end }}
+
+{{- define "hbMonster" }}
+{{ "{{" }}monster,frame
+## {{ .Name }}
+*{{ title (print .Size) }} {{ .Type }}{{ with .Alignment }}, {{ . }}{{ end }}*
+___
+**Armor Class** :: {{ .ArmorClass }}
+{{- with hitPoints . }}
+**Hit Points** :: {{ . }}
+{{- end }}
+**Speed** :: {{ .Speed }}
+___
+|  STR  |  DEX  |  CON  |  INT  |  WIS  |  CHA  |
+|:-----:|:-----:|:-----:|:-----:|:-----:|:-----:|
+|{{ .Str }} ({{ abilityMod .Str }})|{{ .Dex }} ({{ abilityMod .Dex }})|{{ .Con }} ({{ abilityMod .Con }})|{{ .Int }} ({{ abilityMod .Int }})|{{ .Wis }} ({{ abilityMod .Wis }})|{{ .Cha }} ({{ abilityMod .Cha }})|
+___
+{{- with .SavingThrows }}
+**Saving Throws** :: {{ bonuses . }}
+{{- end }}
+{{- with .Skills }}
+**Skills** :: {{ bonuses . }}
+{{- end }}
+{{- with .Props }}
+{{- with .DamageResistance }}
+**Damage Resistances** :: {{ list . }}
+{{- end }}
+{{- with .DamageImmunity }}
+**Damage Immunities** :: {{ list . }}
+{{- end }}
+{{- with .DamageVulnerability }}
+**Damage Vulnerabilities** :: {{ list . }}
+{{- end }}
+{{- with .ConditionImmunity }}
+**Condition Immunities** :: {{ list . }}
+{{- end }}
+{{- with .Language }}
+**Languages** :: {{ list . }}
+{{- end }}
+{{- end }}
+**Challenge** :: {{ challenge .Challenge }}
+___
+{{ template "hbTraits" traits .Traits "" }}
+{{- with traits .Traits "action" }}
+### Actions
+{{ template "hbTraits" . }}
+{{- end }}
+{{- with traits .Traits "legendary-action" }}
+### Legendary Actions
+{{ with $.LegendaryActions }}
+{{ .Description }}
+{{ end }}
+{{ template "hbTraits" . }}
+{{- end }}
+{{ "}}" }}
+
+{{ range lines .Description }}{{ . }}
+
+{{ end }}
+{{- end -}}
+
+# {{ .Title }}
+{{ range .Classes }}
+{{ template "hbClass" . }}
+{{- end }}
+{{- range .Subclasses }}
+
+\page
+
+# {{ name "classes" .Class }} Subclasses
+{{ range .Subclasses }}
+{{ template "hbSubclass" . }}
+{{- end }}
+{{- end }}
+{{- if or .Invocations .Selections }}
+
+\page
+
+# Class Options
+{{- with .Invocations }}
+
+## Eldritch Invocations
+{{ range . }}
+#### {{ .Name }}
+
+{{ range lines .Description }}{{ . }}
+
+{{ end }}
+{{- end }}
+{{- end }}
+{{- range .Selections }}
+
+## {{ .Name }}
+
+{{ template "hbTraits" .Options }}
+{{- end }}
+{{- end }}
+{{- if or .Races .Subraces }}
+
+\page
+
+# Races
+{{ range .Races }}
+{{ template "hbRace" . }}
+{{- range .Subraces }}
+{{ template "hbRace" . }}
+{{- end }}
+{{- end }}
+{{- range .Subraces }}
+{{- range .Subraces }}
+{{ template "hbRace" . }}
+{{- end }}
+{{- end }}
+{{- with .Languages }}
+
+## Languages
+{{ range . }}
+- **{{ .Name }}.** {{ .Description }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- with .Backgrounds }}
+
+\page
+
+# Backgrounds
+{{ range . }}
+{{ template "hbBackground" . }}
+{{- end }}
+{{- end }}
+{{- with .Feats }}
+
+\page
+
+# Feats
+{{ range . }}
+{{ template "hbFeat" . }}
+{{- end }}
+{{- end }}
+{{- with .Spells }}
+
+\page
+
+# Spells
+{{- range . }}
+
+## {{ if .Level }}{{ spellLevel .Level }} Spells{{ else }}Cantrips{{ end }}
+{{ range .Spells }}
+{{ template "hbSpell" . }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- with .Monsters }}
+
+\page
+
+# Monsters
+{{ range . }}
+{{ template "hbMonster" . }}
+{{- end }}
+{{- end }}
+{{- with .Encounters }}
+
+\page
+
+# Encounters
+{{ range . }}
+## {{ .Name }}
+
+{{ range .Creatures }}- {{ .Creature.Num }} × {{ name "monsters" .Creature.Monster }}
+{{ end }}
+{{- end }}
+{{- end }}
+`
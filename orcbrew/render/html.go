@@ -0,0 +1,189 @@
+package render
+
+import (
+	"html/template"
+	"io"
+)
+
+// ExecuteHTML renders the book with an html/template, e.g. HTMLTemplate
+func (b *Book) ExecuteHTML(w io.Writer, text string) error {
+	tmpl, err := template.New("book").Funcs(template.FuncMap(b.Funcs())).Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, b)
+}
+
+// HTMLTemplate is the default template used to render the monsters and spells
+// of a book as an HTML page for printing, with a classic stat block for each
+// monster and a playing card sized card for each spell. The page has no
+// external dependencies.
+const HTMLTemplate = `{{ define "paragraphs" }}
+{{- range lines . }}<p>{{ . }}</p>{{ end }}
+{{- end }}
+
+{{- define "traits" }}
+{{- range . }}
+<div class="trait"><b><i>{{ .Name }}.</i></b> {{ template "paragraphs" .Description }}</div>
+{{- end }}
+{{- end }}
+
+{{- define "monster" }}
+<article class="stat-block" id="monster-{{ .Key }}">
+  <h3>{{ .Name }}</h3>
+  <p class="subtitle">{{ title (print .Size) }} {{ .Type }}{{ with .Alignment }}, {{ . }}{{ end }}</p>
+  <hr>
+  <dl>
+    <dt>Armor Class</dt><dd>{{ .ArmorClass }}</dd>
+    {{- with hitPoints . }}
+    <dt>Hit Points</dt><dd>{{ . }}</dd>
+    {{- end }}
+    {{- with .Speed }}
+    <dt>Speed</dt><dd>{{ . }}</dd>
+    {{- end }}
+  </dl>
+  <hr>
+  <table class="abilities">
+    <tr><th>STR</th><th>DEX</th><th>CON</th><th>INT</th><th>WIS</th><th>CHA</th></tr>
+    <tr>
+      <td>{{ .Str }} ({{ abilityMod .Str }})</td>
+      <td>{{ .Dex }} ({{ abilityMod .Dex }})</td>
+      <td>{{ .Con }} ({{ abilityMod .Con }})</td>
+      <td>{{ .Int }} ({{ abilityMod .Int }})</td>
+      <td>{{ .Wis }} ({{ abilityMod .Wis }})</td>
+      <td>{{ .Cha }} ({{ abilityMod .Cha }})</td>
+    </tr>
+  </table>
+  <hr>
+  <dl>
+    {{- with bonuses .SavingThrows }}
+    <dt>Saving Throws</dt><dd>{{ . }}</dd>
+    {{- end }}
+    {{- with bonuses .Skills }}
+    <dt>Skills</dt><dd>{{ . }}</dd>
+    {{- end }}
+    {{- with .Props }}
+    {{- with list .DamageVulnerability }}
+    <dt>Damage Vulnerabilities</dt><dd>{{ . }}</dd>
+    {{- end }}
+    {{- with list .DamageResistance }}
+    <dt>Damage Resistances</dt><dd>{{ . }}</dd>
+    {{- end }}
+    {{- with list .DamageImmunity }}
+    <dt>Damage Immunities</dt><dd>{{ . }}</dd>
+    {{- end }}
+    {{- with list .ConditionImmunity }}
+    <dt>Condition Immunities</dt><dd>{{ . }}</dd>
+    {{- end }}
+    {{- with list .Language }}
+    <dt>Languages</dt><dd>{{ . }}</dd>
+    {{- end }}
+    {{- end }}
+    <dt>Challenge</dt><dd>{{ challenge .Challenge }}</dd>
+  </dl>
+  <hr>
+  {{- with .Description }}
+  <div class="description">{{ template "paragraphs" . }}</div>
+  {{- end }}
+  {{- template "traits" traits .Traits "" }}
+  {{- with traits .Traits "action" }}
+  <h4>Actions</h4>
+  {{- template "traits" . }}
+  {{- end }}
+  {{- with traits .Traits "legendary-action" }}
+  <h4>Legendary Actions</h4>
+  {{- with $.LegendaryActions }}
+  <div class="description">{{ template "paragraphs" .Description }}</div>
+  {{- end }}
+  {{- template "traits" . }}
+  {{- end }}
+</article>
+{{- end }}
+
+{{- define "spell" }}
+<article class="card" id="spell-{{ .Key }}">
+  <h3>{{ .Name }}</h3>
+  <p class="subtitle">{{ spellLevel .Level }} {{ .School }}{{ if .Ritual }} (ritual){{ end }}</p>
+  <dl>
+    <dt>Casting Time</dt><dd>{{ .CastingTime }}</dd>
+    <dt>Range</dt><dd>{{ .Range }}</dd>
+    <dt>Components</dt><dd>{{ components .Components }}</dd>
+    <dt>Duration</dt><dd>{{ .Duration }}</dd>
+  </dl>
+  <div class="description">{{ template "paragraphs" .Description }}</div>
+  {{- with list .SpellLists }}
+  <p class="classes">{{ . }}</p>
+  {{- end }}
+</article>
+{{- end -}}
+
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+` + htmlStyle + `</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+{{- with .Monsters }}
+<section class="monsters">
+<h2>Monsters</h2>
+{{- range . }}
+{{ template "monster" . }}
+{{- end }}
+</section>
+{{- end }}
+{{- with .Spells }}
+<section class="spells">
+<h2>Spells</h2>
+<div class="cards">
+{{- range . }}
+{{- range .Spells }}
+{{ template "spell" . }}
+{{- end }}
+{{- end }}
+</div>
+</section>
+{{- end }}
+</body>
+</html>
+`
+
+// htmlStyle is the style sheet for stat blocks and spell cards
+const htmlStyle = `body { font-family: Georgia, "Times New Roman", serif; margin: 1em; color: #222; }
+h1, h2 { font-variant: small-caps; }
+p { margin: 0 0 0.4em; }
+dl { margin: 0; }
+dt { display: inline; font-weight: bold; }
+dt::after { content: " "; }
+dd { display: inline; margin: 0; }
+dd::after { content: ""; display: block; }
+
+.stat-block { width: 3.4in; display: inline-block; vertical-align: top; margin: 0 0.2in 0.2in 0; padding: 0.1in 0.15in; background: #fdf1dc; border-top: 4px solid #e69a28; border-bottom: 4px solid #e69a28; font-size: 10pt; break-inside: avoid; page-break-inside: avoid; }
+.stat-block h3 { color: #7a200d; font-size: 16pt; margin: 0; font-variant: small-caps; }
+.stat-block h4 { color: #7a200d; font-variant: small-caps; border-bottom: 1px solid #7a200d; margin: 0.5em 0 0.3em; }
+.stat-block hr { border: 0; height: 2px; background: #922610; margin: 0.4em 0; }
+.stat-block dt, .stat-block table { color: #7a200d; }
+.stat-block .subtitle { font-style: italic; }
+.stat-block .trait { margin-bottom: 0.4em; }
+.stat-block .trait p { display: inline; }
+.abilities { width: 100%; text-align: center; border-collapse: collapse; }
+
+.cards { display: flex; flex-wrap: wrap; gap: 0.1in; }
+.card { box-sizing: border-box; width: 2.5in; height: 3.5in; overflow: hidden; display: flex; flex-direction: column; padding: 0.12in; border: 2px solid #58180d; border-radius: 0.1in; font-size: 7.5pt; break-inside: avoid; page-break-inside: avoid; }
+.card h3 { font-size: 11pt; margin: 0; color: #58180d; }
+.card .subtitle { font-style: italic; }
+.card dl { border-top: 1px solid #58180d; border-bottom: 1px solid #58180d; padding: 0.05in 0; margin-bottom: 0.05in; }
+.card .description { flex: 1; overflow: hidden; }
+.card .classes { margin: 0; padding-top: 0.03in; border-top: 1px solid #58180d; text-align: right; font-style: italic; }
+
+@media print {
+  body { margin: 0; }
+  @page { margin: 0.4in; }
+  section { break-before: page; page-break-before: always; }
+  section:first-of-type { break-before: auto; page-break-before: auto; }
+  .stat-block, .card { -webkit-print-color-adjust: exact; print-color-adjust: exact; }
+}
+`
@@ -0,0 +1,325 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"text/template"
+)
+
+var blankLines = regexp.MustCompile(`\n(\s*\n)+`)
+
+// Execute renders the book with a text/template, e.g. MarkdownTemplate. Runs
+// of blank lines in the output are collapsed into one, so that templates
+// don't need to control whitespace exactly.
+func (b *Book) Execute(w io.Writer, text string) error {
+	tmpl, err := template.New("book").Funcs(b.Funcs()).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, b); err != nil {
+		return err
+	}
+
+	output := blankLines.ReplaceAll(bytes.TrimLeft(buf.Bytes(), "\n"), []byte("\n\n"))
+	_, err = w.Write(output)
+	return err
+}
+
+// MarkdownTemplate is the default template used to render a book as
+// Markdown. Each kind of entity is rendered by a named template, so
+// individual sections can be restyled by redefining them.
+const MarkdownTemplate = partials + `{{ define "traits" }}
+{{- range . }}
+**{{ .Name }}.** {{ range $idx, $line := lines .Description }}{{ if $idx }}
+
+{{ end }}{{ $line }}{{ end }}
+{{ end }}
+{{- end }}
+
+{{- define "subclass" }}
+### {{ .Name }}
+{{ template "proficiencies" . }}
+{{- template "levelFeatures" . }}
+{{ template "traits" .Traits }}
+{{- end }}
+
+{{- define "class" }}
+## {{ .Name }}
+
+- **Hit Die:** d{{ .HitDie }}
+{{- template "proficiencies" . }}
+{{- with .Spellcasting }}
+- **Spellcasting Ability:** {{ title (print .Ability) }}
+{{- end }}
+
+| Level | Proficiency Bonus | Features |
+|-------|-------------------|----------|
+{{- range .Levels }}
+| {{ ordinal .Level }} | +{{ .ProficiencyBonus }} | {{ join .Features ", " }} |
+{{- end }}
+
+{{ template "traits" .Traits }}
+{{- range .Subclasses }}
+{{ template "subclass" . }}
+{{- end }}
+{{- end }}
+
+{{- define "race" }}
+### {{ .Name }}
+{{ template "raceDetails" . }}
+
+{{ template "traits" .Traits }}
+{{- end }}
+
+{{- define "background" }}
+### {{ .Name }}
+{{ template "backgroundDetails" . }}
+
+{{ template "traits" .Traits }}
+{{- end }}
+
+{{- define "feat" }}
+### {{ .Name }}
+{{ if or .Prereqs .PathPrereqs.Race }}
+*Prerequisite: {{ list .Prereqs }}{{ if and .Prereqs .PathPrereqs.Race }}, {{ end }}{{ names "races" .PathPrereqs.Race }}*
+{{ end }}
+{{- with .AbilityIncreases }}
+*Ability Score Increase: {{ list . }}*
+{{ end }}
+{{ range lines .Description }}{{ . }}
+
+{{ end }}
+{{- end }}
+
+{{- define "spell" }}
+#### {{ .Name }}
+
+*{{ spellLevel .Level }} {{ .School }}{{ if .Ritual }} (ritual){{ end }}*
+
+- **Casting Time:** {{ .CastingTime }}
+- **Range:** {{ .Range }}
+- **Components:** {{ components .Components }}
+- **Duration:** {{ .Duration }}
+{{- with keys .SpellLists }}
+- **Spell Lists:** {{ range $idx, $list := . }}{{ if $idx }}, {{ end }}{{ title $list }}{{ end }}
+{{- end }}
+
+{{ range lines .Description }}{{ . }}
+
+{{ end }}
+{{- end }}
+
+{{- define "monster" }}
+### {{ .Name }}
+
+*{{ title (print .Size) }} {{ .Type }}{{ with .Alignment }}, {{ . }}{{ end }}*
+
+- **Armor Class:** {{ .ArmorClass }}
+{{- with hitPoints . }}
+- **Hit Points:** {{ . }}
+{{- end }}
+- **Speed:** {{ .Speed }}
+
+| STR | DEX | CON | INT | WIS | CHA |
+|-----|-----|-----|-----|-----|-----|
+| {{ .Str }} ({{ abilityMod .Str }}) | {{ .Dex }} ({{ abilityMod .Dex }}) | {{ .Con }} ({{ abilityMod .Con }}) | {{ .Int }} ({{ abilityMod .Int }}) | {{ .Wis }} ({{ abilityMod .Wis }}) | {{ .Cha }} ({{ abilityMod .Cha }}) |
+{{ with .SavingThrows }}
+- **Saving Throws:** {{ bonuses . }}
+{{- end }}
+{{- with .Skills }}
+- **Skills:** {{ bonuses . }}
+{{- end }}
+{{- with .Props }}
+{{- with .DamageResistance }}
+- **Damage Resistances:** {{ list . }}
+{{- end }}
+{{- with .DamageImmunity }}
+- **Damage Immunities:** {{ list . }}
+{{- end }}
+{{- with .DamageVulnerability }}
+- **Damage Vulnerabilities:** {{ list . }}
+{{- end }}
+{{- with .ConditionImmunity }}
+- **Condition Immunities:** {{ list . }}
+{{- end }}
+{{- with .Language }}
+- **Languages:** {{ list . }}
+{{- end }}
+{{- end }}
+- **Challenge:** {{ challenge .Challenge }}
+
+{{ range lines .Description }}{{ . }}
+
+{{ end }}
+{{- template "traits" traits .Traits "" }}
+{{- with traits .Traits "action" }}
+#### Actions
+
+{{ template "traits" . }}
+{{- end }}
+{{- with traits .Traits "legendary-action" }}
+#### Legendary Actions
+{{ with $.LegendaryActions }}
+{{ .Description }}
+{{ end }}
+{{ template "traits" . }}
+{{- end }}
+{{- end -}}
+
+# {{ .Title }}
+{{ with .Classes }}
+{{- range . }}
+{{ template "class" . }}
+{{- end }}
+{{- end }}
+{{- range .Subclasses }}
+
+## {{ name "classes" .Class }} Subclasses
+{{ range .Subclasses }}
+{{ template "subclass" . }}
+{{- end }}
+{{- end }}
+{{- if or .Races .Subraces }}
+
+## Races
+{{ range .Races }}
+{{ template "race" . }}
+{{- range .Subraces }}
+{{ template "race" . }}
+{{- end }}
+{{- end }}
+{{- range .Subraces }}
+{{- range .Subraces }}
+{{ template "race" . }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- with .Backgrounds }}
+
+## Backgrounds
+{{ range . }}
+{{ template "background" . }}
+{{- end }}
+{{- end }}
+{{- with .Feats }}
+
+## Feats
+{{ range . }}
+{{ template "feat" . }}
+{{- end }}
+{{- end }}
+{{- with .Invocations }}
+
+## Eldritch Invocations
+{{ range . }}
+### {{ .Name }}
+
+{{ range lines .Description }}{{ . }}
+
+{{ end }}
+{{- end }}
+{{- end }}
+{{- with .Selections }}
+{{ range . }}
+## {{ .Name }}
+{{ template "traits" .Options }}
+{{- end }}
+{{- end }}
+{{- with .Languages }}
+
+## Languages
+{{ range . }}
+- **{{ .Name }}.** {{ .Description }}
+{{- end }}
+{{- end }}
+{{- with .Spells }}
+
+## Spells
+{{- range . }}
+
+{{ if .Level }}### {{ spellLevel .Level }} Spells{{ else }}### Cantrips{{ end }}
+{{ range .Spells }}
+{{ template "spell" . }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- with .Monsters }}
+
+## Monsters
+{{ range . }}
+{{ template "monster" . }}
+{{- end }}
+{{- end }}
+{{- with .Encounters }}
+
+## Encounters
+{{ range . }}
+### {{ .Name }}
+
+{{ range .Creatures }}- {{ .Creature.Num }} × {{ name "monsters" .Creature.Monster }}
+{{ end }}
+{{- end }}
+{{- end }}
+`
+
+// partials are the templates shared by MarkdownTemplate and
+// HomebreweryTemplate, which render the details listed under the heading of
+// an entity
+const partials = `{{- define "proficiencies" }}
+{{- with .Profs }}
+{{- with .Save }}
+- **Saving Throws:** {{ list . }}
+{{- end }}
+{{- with .SkillOptions }}{{ if keys .Options }}
+- **Skills:** {{ with .Choose }}Choose {{ . }} from {{ end }}{{ list .Options }}
+{{- end }}{{ end }}
+{{- with .SkillExpertiseOptions }}{{ if keys .Options }}
+- **Expertise:** {{ with .Choose }}Choose {{ . }} from {{ end }}{{ list .Options }}
+{{- end }}{{ end }}
+{{- end }}
+{{- end }}
+
+{{- define "levelFeatures" }}
+{{- range levelFeatures .LevelSelections .LevelModifiers }}
+- **{{ ordinal .Level }} level:** {{ .Text }}
+{{- end }}
+{{- end }}
+
+{{- define "raceDetails" }}{{ with .Abilities }}
+- **Ability Score Increase:** {{ bonuses . }}
+{{- end }}
+{{- with .Size }}
+- **Size:** {{ title (print .) }}
+{{- end }}
+{{- with .Speed }}
+- **Speed:** {{ . }} ft.
+{{- end }}
+{{- with .Darkvision }}
+- **Darkvision:** {{ . }} ft.
+{{- end }}
+{{- with .Languages }}
+- **Languages:** {{ join . ", " }}
+{{- end }}
+{{- with .Spells }}
+- **Spells:** {{ range $idx, $spell := . }}{{ if $idx }}, {{ end }}{{ name "spells" .Value.Key }}{{ with .Value.Level }} ({{ ordinal . }} level){{ end }}{{ end }}
+{{- end }}
+{{- end }}
+
+{{- define "backgroundDetails" }}{{ with .Profs }}
+{{- with .Skill }}
+- **Skill Proficiencies:** {{ list . }}
+{{- end }}
+{{- with .Tool }}
+- **Tool Proficiencies:** {{ list . }}
+{{- end }}
+{{- end }}
+{{- with .Equipment }}
+- **Equipment:** {{ range $idx, $item := keys . }}{{ if $idx }}, {{ end }}{{ title $item }}{{ with index $.Equipment $item }}{{ if gt . 1 }} ({{ . }}){{ end }}{{ end }}{{ end }}
+{{- end }}
+{{- with .Treasure }}
+- **Treasure:** {{ treasure . }}
+{{- end }}
+{{- end }}`
@@ -0,0 +1,421 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+const exampleFile = "../schema/example.orcbrew"
+
+func exampleBook(t *testing.T) *Book {
+	f, err := orcbrew.ReadFile(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := f.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewBook("Example", source)
+}
+
+func TestNewBook(t *testing.T) {
+	source := schema.OrcbrewSource{
+		Classes: map[string]schema.ClassConfig{
+			"myclass": {Key: "myclass", Name: "MyClass", SubclassLevel: 3, SubclassTitle: "Path",
+				AbilityIncreaseLevels: []int{4},
+				LevelSelections:       []schema.LevelSelection{{Type: "myselection", Num: 2, Level: 2}},
+				Traits:                []schema.LevelTrait{{Name: "Rage", Level: 1}}},
+		},
+		Subclasses: map[string]schema.SubclassConfig{
+			"b": {Key: "b", Name: "Beta", Class: "myclass"},
+			"a": {Key: "a", Name: "Alpha", Class: "myclass"},
+			"c": {Key: "c", Name: "Gamma", Class: "wizard"},
+		},
+		Selections: map[string]schema.SelectionConfig{
+			"myselection": {Key: "myselection", Name: "Maneuvers"},
+		},
+		Spells: map[string]schema.SpellConfig{
+			"fireball":  {Key: "fireball", Name: "Fireball", Level: 3},
+			"light":     {Key: "light", Name: "Light"},
+			"firebolt":  {Key: "firebolt", Name: "Fire Bolt"},
+			"lightning": {Key: "lightning", Name: "Lightning Bolt", Level: 3},
+		},
+	}
+
+	b := NewBook("Test", source)
+
+	if len(b.Classes) != 1 {
+		t.Fatalf("Expected 1 class, got %d", len(b.Classes))
+	}
+	class := b.Classes[0]
+	if len(class.Subclasses) != 2 || class.Subclasses[0].Name != "Alpha" || class.Subclasses[1].Name != "Beta" {
+		t.Errorf("Expected the subclasses of MyClass sorted by name, got %+v", class.Subclasses)
+	}
+	if len(b.Subclasses) != 1 || b.Subclasses[0].Class != "wizard" || len(b.Subclasses[0].Subclasses) != 1 {
+		t.Errorf("Expected a group for the wizard subclass, got %+v", b.Subclasses)
+	}
+
+	if len(class.Levels) != 20 || class.Levels[4].ProficiencyBonus != 3 || class.Levels[16].ProficiencyBonus != 6 {
+		t.Errorf("Expected 20 levels with proficiency bonuses, got %+v", class.Levels)
+	}
+	features := [][]string{class.Levels[0].Features, class.Levels[1].Features, class.Levels[2].Features, class.Levels[3].Features}
+	expected := [][]string{{"Rage"}, {"Maneuvers (2)"}, {"Path"}, {"Ability Score Improvement"}}
+	if diff := deep.Equal(features, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	var spells [][]string
+	for _, level := range b.Spells {
+		var names []string
+		for _, spell := range level.Spells {
+			names = append(names, spell.Name)
+		}
+		spells = append(spells, names)
+	}
+	if diff := deep.Equal(spells, [][]string{{"Fire Bolt", "Light"}, {"Fireball", "Lightning Bolt"}}); diff != nil {
+		t.Error(diff)
+	}
+
+	if name := b.Name("classes", "wizard"); name != "Wizard" {
+		t.Errorf("Expected a title for an unknown class, got %s", name)
+	}
+	if name := b.Name("selections", "myselection"); name != "Maneuvers" {
+		t.Errorf("Expected the selection name, got %s", name)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	tests := []struct {
+		got      string
+		expected string
+	}{
+		{Title("animal-handling"), "Animal Handling"},
+		{Title("wis"), "Wisdom"},
+		{Title("élan-vital"), "Élan Vital"},
+		{Ordinal(1), "1st"},
+		{Ordinal(2), "2nd"},
+		{Ordinal(3), "3rd"},
+		{Ordinal(11), "11th"},
+		{Ordinal(22), "22nd"},
+		{SpellLevelName(0), "Cantrip"},
+		{SpellLevelName(3), "3rd-level"},
+		{AbilityModifier(10), "+0"},
+		{AbilityModifier(15), "+2"},
+		{AbilityModifier(7), "-2"},
+		{HitPoints(schema.MonsterConfig{Con: 12, HitPoints: &schema.HitDieCount{DieCount: 5, Die: 8}}), "27 (5d8 + 5)"},
+		{HitPoints(schema.MonsterConfig{Con: 8, HitPoints: &schema.HitDieCount{DieCount: 2, Die: 6}}), "5 (2d6 - 2)"},
+		{HitPoints(schema.MonsterConfig{Con: 10}), ""},
+		{Challenge(0.25), "1/4"},
+		{Challenge(3), "3"},
+		{Components(&schema.SpellComponents{Verbal: true, Material: true, MaterialComponent: "a feather"}), "V, M (a feather)"},
+		{list(map[string]bool{"dex": true, "str": true, "con": false}), "Dexterity, Strength"},
+		{list([]string{"str", "spellcasting"}), "Strength, Spellcasting"},
+		{bonuses(map[schema.Ability]int{schema.Strength: 2, schema.Charisma: -1}), "Charisma -1, Strength +2"},
+		{Treasure(map[schema.Currency]int{"gp": 10, "sp": 5, "pp": 0}), "5 sp, 10 gp"},
+	}
+
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, test.got)
+		}
+	}
+}
+
+func TestLevelFeatures(t *testing.T) {
+	b := NewBook("Test", schema.OrcbrewSource{
+		Selections: map[string]schema.SelectionConfig{
+			"myselection": {Key: "myselection", Name: "Maneuvers"},
+		},
+	})
+
+	features := b.LevelFeatures(
+		[]schema.LevelSelection{{Type: "myselection", Num: 2, Level: 3}, {Type: "myselection", Num: 1}},
+		schema.LevelModifierList{
+			&schema.ModifierArmorProficiency{Value: schema.LightArmor, Level: 2},
+			&schema.ModifierArmorProficiency{Value: schema.LightArmor, Level: 7},
+		},
+	)
+	var levels []int
+	for _, feature := range features {
+		levels = append(levels, feature.Level)
+	}
+	if diff := deep.Equal(levels, []int{1, 2, 3, 7}); diff != nil {
+		t.Errorf("Expected the features sorted by level: %v", diff)
+	}
+	if features[0].Text != "Maneuvers (1)" {
+		t.Errorf("Expected the selection first, got %+v", features[0])
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	b := exampleBook(t)
+
+	var buf bytes.Buffer
+	if err := b.Execute(&buf, MarkdownTemplate); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	for _, expected := range []string{
+		"# Example\n",
+		"## MyClass\n",
+		"| 4th | +2 | Ability Score Improvement |",
+		"## Barbarian Subclasses\n",
+		"### MySubClass\n",
+		"### MySubRace\n",
+		"### Cantrips\n",
+		"#### MySpell\n",
+		"- **Components:** V, S, M (A pinch of salt)",
+		"| 10 (+0) | 10 (+0) |",
+		"#### Legendary Actions\n",
+		"- 3 × Goblin\n",
+		"- **Skills:** Acrobatics\n",
+		"- **Treasure:** 10 gp\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the output to contain %q", expected)
+		}
+	}
+	if strings.Contains(output, "\n\n\n") {
+		t.Errorf("Expected runs of blank lines to be collapsed")
+	}
+}
+
+func TestHomebrewery(t *testing.T) {
+	b := exampleBook(t)
+
+	var buf bytes.Buffer
+	if err := b.Execute(&buf, HomebreweryTemplate); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	for _, expected := range []string{
+		"# Example\n",
+		"\n\n\\page\n\n# MyClass\n",
+		"{{classTable,frame\n##### The MyClass\n",
+		"| Level | Proficiency Bonus | Features | Spells Known |",
+		"| 2nd | +2 | MySubclassTitle, MyClassTrait, Advantage on saving throws against: Blinded | — |",
+		"| 3rd | +2 | — | 3 |",
+		"| 4th | +2 | Ability Score Improvement | 4 |",
+		"# Barbarian Subclasses\n",
+		"*Necromancy cantrip (ritual)*\n___\n- **Casting Time:** 1 action\n",
+		"{{monster,frame\n## MyMonster\n*Large aberration, neutral*\n___\n**Armor Class** :: 10\n",
+		"|10 (+0)|10 (+0)|10 (+0)|10 (+0)|10 (+0)|10 (+0)|",
+		"### Legendary Actions\n",
+		"***Dragon breath.*** I bathe you in fire!!!\n\n}}\n",
+		"- **Skills:** Acrobatics\n",
+		"- **Treasure:** 10 gp\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the output to contain %q", expected)
+		}
+	}
+	if n := strings.Count(output, "\\page"); n != 10 {
+		t.Errorf("Expected a page break before each of 10 chapters, got %d", n)
+	}
+}
+
+func TestCustomTemplate(t *testing.T) {
+	b := exampleBook(t)
+
+	var buf bytes.Buffer
+	text := `{{ range .Spells }}{{ range .Spells }}{{ .Name }}: {{ spellLevel .Level }}{{ end }}{{ end }}`
+	if err := b.Execute(&buf, text); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "MySpell: Cantrip" {
+		t.Errorf("Unexpected output %q", buf.String())
+	}
+
+	if err := b.Execute(&buf, "{{ .Missing }}"); err == nil {
+		t.Errorf("Expected an error for a field that doesn't exist")
+	}
+}
+
+func TestHTML(t *testing.T) {
+	b := exampleBook(t)
+	b.Monsters[0].Name = "<Mimic>"
+
+	var buf bytes.Buffer
+	if err := b.ExecuteHTML(&buf, HTMLTemplate); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	for _, expected := range []string{
+		`<article class="stat-block" id="monster-mymonster">`,
+		"<h3>&lt;Mimic&gt;</h3>",
+		"<dt>Hit Points</dt><dd>4 (1d8)</dd>",
+		"<dt>Damage Immunities</dt><dd>Acid</dd>",
+		"<h4>Legendary Actions</h4>",
+		`<article class="card" id="spell-myspell">`,
+		"<dt>Components</dt><dd>V, S, M (A pinch of salt)</dd>",
+		"@media print",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the output to contain %q", expected)
+		}
+	}
+	if strings.Contains(output, "http") {
+		t.Errorf("Expected the page to have no external references")
+	}
+}
+
+func TestWriteSite(t *testing.T) {
+	source := schema.OrcbrewSource{
+		Classes: map[string]schema.ClassConfig{
+			"myclass": {Key: "myclass", Name: "MyClass", HitDie: 8},
+		},
+		Subclasses: map[string]schema.SubclassConfig{
+			"mysubclass": {Key: "mysubclass", Name: "MySubclass", Class: "myclass"},
+		},
+		Monsters: map[string]schema.MonsterConfig{
+			"goblin": {Key: "goblin", Name: "Goblin", Description: "A small green menace"},
+		},
+		Encounters: map[string]schema.EncounterConfig{
+			"ambush": {Key: "ambush", Name: "Ambush", Creatures: []schema.EncounterCreature{
+				{Type: "monster", Creature: schema.EncounterCreatureConfig{Num: 3, Monster: "goblin"}},
+			}},
+		},
+		Spells: map[string]schema.SpellConfig{
+			"index": {Key: "index", Name: "Index", School: "divination", SpellLists: map[string]bool{"wizard": true}},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := NewBook("Test", source).WriteSite(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(path string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"index.html", `<a href="monsters/index.html">Monsters</a> (1)`},
+		{"subclasses/mysubclass.html", `<base href="../">`},
+		{"subclasses/mysubclass.html", `<a href="classes/myclass.html">MyClass</a>`},
+		{"classes/myclass.html", `<a href="subclasses/mysubclass.html">MySubclass</a>`},
+		{"classes/myclass.html", "<td>3rd</td><td>+2</td>"},
+		{"encounters/ambush.html", `3 × <a href="monsters/goblin.html">Goblin</a>`},
+		{"monsters/goblin.html", `<a href="encounters/ambush.html">Ambush</a>`},
+		{"spells/index.html", `<tr data-level="0" data-school="divination" data-classes="wizard">`},
+		{"spells/index.html", `<option value="wizard">Wizard</option>`},
+		{"spells/index-2.html", `<article class="card" id="spell-index">`},
+	}
+	for _, test := range tests {
+		if contents := read(test.path); !strings.Contains(contents, test.expected) {
+			t.Errorf("Expected %s to contain %q", test.path, test.expected)
+		}
+	}
+
+	var index []SearchEntry
+	if err := json.Unmarshal([]byte(read("search.json")), &index); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, entry := range index {
+		if entry.Kind == "monsters" && entry.Path == "monsters/goblin.html" && strings.Contains(entry.Text, "green menace") {
+			found = true
+		}
+	}
+	if len(index) != 5 || !found {
+		t.Errorf("Unexpected search index %+v", index)
+	}
+	if !strings.HasPrefix(read("search-index.js"), "var searchIndex = [") {
+		t.Errorf("Expected the search index to be written as a script")
+	}
+}
+
+func TestWriteVault(t *testing.T) {
+	source := schema.OrcbrewSource{
+		Subclasses: map[string]schema.SubclassConfig{
+			"mysubclass": {Key: "mysubclass", Name: "My/Subclass", Class: "barbarian", OptionPack: "Test"},
+		},
+		Monsters: map[string]schema.MonsterConfig{
+			"goblin": {Key: "goblin", Name: "Goblin", Type: "humanoid", Challenge: 0.25, Description: "A small green menace"},
+		},
+		Encounters: map[string]schema.EncounterConfig{
+			"ambush": {Key: "ambush", Name: "Ambush", Creatures: []schema.EncounterCreature{
+				{Type: "monster", Creature: schema.EncounterCreatureConfig{Num: 3, Monster: "goblin"}},
+			}},
+		},
+		Spells: map[string]schema.SpellConfig{
+			"index": {Key: "index", Name: "Index", Level: 2, School: "Divination", SpellLists: map[string]bool{"wizard": true, "bard": true}},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := NewBook("Test", source).WriteVault(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(path string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"Subclasses/My-Subclass.md", "---\nkind: subclasses\nkey: mysubclass\noption-pack: Test\nclass: Barbarian\n"},
+		{"Subclasses/My-Subclass.md", "\n# My/Subclass\n"},
+		{"Spells/Index.md", "level: 2\nschool: divination\n"},
+		{"Spells/Index.md", "spell-lists: [bard, wizard]\ntags: [orcbrew/spells]\n---\n"},
+		{"Monsters/Goblin.md", "cr: 1/4\n"},
+		{"Monsters/Goblin.md", "## Referenced by\n\n- [[Encounters/Ambush|Ambush]]\n"},
+		{"Encounters/Ambush.md", "- 3 × [[Monsters/Goblin|Goblin]]\n"},
+		{"Spells.md", "# Spells\n\n- [[Spells/Index|Index]]\n"},
+	}
+	for _, test := range tests {
+		if contents := read(test.path); !strings.Contains(contents, test.expected) {
+			t.Errorf("Expected %s to contain %q, got:\n%s", test.path, test.expected, contents)
+		}
+	}
+
+	// Writing the vault again leaves the notes alone
+	note := filepath.Join(dir, "Monsters", "Goblin.md")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(note, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewBook("Test", source).WriteVault(dir); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(note); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("Expected %s not to be written again", note)
+	}
+}
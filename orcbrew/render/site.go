@@ -0,0 +1,564 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// site is a book laid out as a static website, with a page for each entity
+type site struct {
+	Book  *Book
+	Kinds []*siteKind // the kinds with at least one entity
+
+	// The distinct values used to filter the spells
+	SpellLevels  []int
+	SpellSchools []string
+	SpellClasses []string
+
+	paths map[string]string // the path of each entity's page, by "kind/key"
+}
+
+type siteKind struct {
+	Kind     string
+	Entities []schema.Entity // sorted by name
+}
+
+// sitePage is what each page template is executed against
+type sitePage struct {
+	*site
+	Title string
+	Root  string // the relative path from the page to the root of the site
+
+	Kind         string
+	Entity       schema.Entity
+	Class        *Class
+	References   []siteLink
+	ReferencedBy []siteLink
+}
+
+type siteLink struct {
+	Name  string
+	Kind  string
+	Key   string
+	Path  string // empty for entities that aren't in the site
+	Field string
+}
+
+// SearchEntry is an entry of the search index written with a site
+type SearchEntry struct {
+	Kind string `json:"kind"`
+	Key  string `json:"key"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Text string `json:"text"` // the descriptions of the entity and its traits
+}
+
+// WriteSite writes the book as a static website in dir: an index page, a
+// page listing each kind of entity and a page for each entity, linking to the
+// entities it refers to and that refer to it. The site is searched in the
+// browser using search.json, which is also written as a script so that the
+// search works when the pages are opened from disk. Nothing is loaded from
+// other sites.
+func (b *Book) WriteSite(dir string) error {
+	s := newSite(b)
+
+	tmpl, err := template.New("html").Funcs(template.FuncMap(b.Funcs())).Funcs(template.FuncMap{
+		"path":        s.path,
+		"description": description,
+		"features":    features,
+	}).Parse(HTMLTemplate)
+	if err == nil {
+		_, err = tmpl.New("site").Parse(siteTemplate)
+	}
+	if err != nil {
+		return err
+	}
+
+	write := func(path string, fn func(w io.Writer) error) error {
+		filename := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+		out, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		err = fn(out)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+	page := func(path string, name string, data *sitePage) error {
+		data.Root = strings.Repeat("../", strings.Count(path, "/"))
+		return write(path, func(w io.Writer) error {
+			return tmpl.ExecuteTemplate(w, name, data)
+		})
+	}
+
+	if err := page("index.html", "home", &sitePage{site: s, Title: b.Title}); err != nil {
+		return err
+	}
+	for _, kind := range s.Kinds {
+		err := page(kind.Kind+"/index.html", "kind", &sitePage{site: s, Title: Title(kind.Kind), Kind: kind.Kind})
+		if err != nil {
+			return err
+		}
+
+		for _, entity := range kind.Entities {
+			data := &sitePage{
+				site:         s,
+				Title:        b.Name(kind.Kind, entity.EntityKey()),
+				Kind:         kind.Kind,
+				Entity:       entity,
+				References:   s.references(entity),
+				ReferencedBy: s.referencedBy(entity),
+			}
+			if kind.Kind == "classes" {
+				data.Class = b.class(entity.EntityKey())
+			}
+			if err := page(s.path(kind.Kind, entity.EntityKey()), "entity", data); err != nil {
+				return err
+			}
+		}
+	}
+
+	index, err := json.MarshalIndent(s.searchIndex(), "", "  ")
+	if err != nil {
+		return err
+	}
+	files := map[string]string{
+		"search.json":     string(index) + "\n",
+		"search-index.js": "var searchIndex = " + string(index) + ";\n",
+		"site.js":         siteScript,
+		"style.css":       htmlStyle + siteStyle,
+	}
+	for path, contents := range files {
+		contents := contents
+		err := write(path, func(w io.Writer) error {
+			_, err := io.WriteString(w, contents)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newSite(b *Book) *site {
+	s := &site{Book: b, paths: make(map[string]string)}
+
+	byKind := make(map[string][]schema.Entity)
+	for _, entity := range b.index.Entities() {
+		byKind[entity.EntityKind()] = append(byKind[entity.EntityKind()], entity)
+	}
+
+	for _, kind := range schema.Kinds {
+		entities := byKind[kind]
+		if len(entities) == 0 {
+			continue
+		}
+		sort.Slice(entities, func(i, j int) bool { return lessByName(entities[i], entities[j]) })
+		s.Kinds = append(s.Kinds, &siteKind{Kind: kind, Entities: entities})
+
+		// Compare case-insensitively, for case-insensitive file systems
+		used := make(map[string]bool)
+		for _, entity := range entities {
+			filename := orcbrew.SafeFilename(entity.EntityKey())
+			for n := 2; used[strings.ToLower(filename)] || filename == "index"; n++ {
+				filename = fmt.Sprintf("%s-%d", orcbrew.SafeFilename(entity.EntityKey()), n)
+			}
+			used[strings.ToLower(filename)] = true
+			s.paths[kind+"/"+entity.EntityKey()] = kind + "/" + filename + ".html"
+		}
+	}
+
+	levels := make(map[int]bool)
+	schools := make(map[string]bool)
+	classes := make(map[string]bool)
+	for _, level := range b.Spells {
+		levels[level.Level] = true
+		for _, spell := range level.Spells {
+			if spell.School != "" {
+				schools[spell.School] = true
+			}
+			for _, class := range Keys(spell.SpellLists) {
+				classes[class] = true
+			}
+		}
+	}
+	for level := range levels {
+		s.SpellLevels = append(s.SpellLevels, level)
+	}
+	sort.Ints(s.SpellLevels)
+	s.SpellSchools = Keys(schools)
+	s.SpellClasses = Keys(classes)
+
+	return s
+}
+
+// path returns the path of an entity's page from the root of the site, or ""
+// when the entity isn't in the site
+func (s *site) path(kind string, key string) string {
+	return s.paths[kind+"/"+key]
+}
+
+func (s *site) references(entity schema.Entity) []siteLink {
+	ptr := reflect.New(reflect.TypeOf(entity))
+	ptr.Elem().Set(reflect.ValueOf(entity))
+
+	var links []siteLink
+	seen := make(map[string]bool)
+	for _, ref := range schema.References(ptr.Interface()) {
+		if seen[ref.String()] {
+			continue
+		}
+		seen[ref.String()] = true
+		links = append(links, siteLink{
+			Name:  s.Book.Name(ref.Kind, ref.Key),
+			Kind:  ref.Kind,
+			Key:   ref.Key,
+			Path:  s.path(ref.Kind, ref.Key),
+			Field: ref.Field,
+		})
+	}
+	return links
+}
+
+func (s *site) referencedBy(entity schema.Entity) []siteLink {
+	var links []siteLink
+	seen := make(map[string]bool)
+	for _, referrer := range s.Book.index.ReferencesTo(entity.EntityKind(), entity.EntityKey()) {
+		kind, key := referrer.Entity.EntityKind(), referrer.Entity.EntityKey()
+		if seen[kind+"/"+key] {
+			continue
+		}
+		seen[kind+"/"+key] = true
+		links = append(links, siteLink{
+			Name:  s.Book.Name(kind, key),
+			Kind:  kind,
+			Key:   key,
+			Path:  s.path(kind, key),
+			Field: referrer.Field,
+		})
+	}
+	return links
+}
+
+func (s *site) searchIndex() []SearchEntry {
+	entries := []SearchEntry{}
+	for _, kind := range s.Kinds {
+		for _, entity := range kind.Entities {
+			text := []string{description(entity)}
+			for _, feature := range features(entity) {
+				text = append(text, feature.Name, feature.Description)
+			}
+
+			entries = append(entries, SearchEntry{
+				Kind: kind.Kind,
+				Key:  entity.EntityKey(),
+				Name: s.Book.Name(kind.Kind, entity.EntityKey()),
+				Path: s.path(kind.Kind, entity.EntityKey()),
+				Text: strings.Join(strings.Fields(strings.Join(text, " ")), " "),
+			})
+		}
+	}
+	return entries
+}
+
+// feature is a named part of an entity, such as a trait or selection option
+type feature struct {
+	Name        string
+	Description string
+}
+
+// description returns the description of an entity, if it has one
+func description(entity interface{}) string {
+	v := reflect.Indirect(reflect.ValueOf(entity))
+	if field := v.FieldByName("Description"); field.IsValid() && field.Kind() == reflect.String {
+		return field.String()
+	}
+	return ""
+}
+
+// features returns the traits of an entity, or the options of a selection
+func features(entity interface{}) []feature {
+	v := reflect.Indirect(reflect.ValueOf(entity))
+
+	var result []feature
+	for _, name := range []string{"Traits", "Options"} {
+		list := v.FieldByName(name)
+		if !list.IsValid() || list.Kind() != reflect.Slice {
+			continue
+		}
+		for idx := 0; idx < list.Len(); idx++ {
+			item := list.Index(idx)
+			result = append(result, feature{
+				Name:        item.FieldByName("Name").String(),
+				Description: item.FieldByName("Description").String(),
+			})
+		}
+	}
+	return result
+}
+
+const siteTemplate = `{{ define "link" }}
+{{- if .Path }}<a href="{{ .Path }}">{{ .Name }}</a>{{ else }}{{ .Name }}{{ end }}
+{{- end }}
+
+{{- define "header" -}}
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+{{- with .Root }}
+<base href="{{ . }}">
+{{- end }}
+<title>{{ .Title }} - {{ .Book.Title }}</title>
+<link rel="stylesheet" href="style.css">
+</head>
+<body>
+<nav>
+  <a href="index.html">{{ .Book.Title }}</a>
+  {{- range .Kinds }}
+  <a href="{{ .Kind }}/index.html">{{ title .Kind }}</a>
+  {{- end }}
+  <input type="search" id="search" placeholder="Search" autocomplete="off">
+  <ul id="results"></ul>
+</nav>
+<main>
+{{- end }}
+
+{{- define "footer" }}
+</main>
+<script src="search-index.js"></script>
+<script src="site.js"></script>
+</body>
+</html>
+{{ end }}
+
+{{- define "home" }}
+{{- template "header" . }}
+<h1>{{ .Book.Title }}</h1>
+<ul class="kinds">
+  {{- range .Kinds }}
+  <li><a href="{{ .Kind }}/index.html">{{ title .Kind }}</a> ({{ len .Entities }})</li>
+  {{- end }}
+</ul>
+{{- template "footer" . }}
+{{- end }}
+
+{{- define "kind" }}
+{{- template "header" . }}
+<h1>{{ .Title }}</h1>
+{{- if eq .Kind "spells" }}
+<form class="filters">
+  <label>Level <select data-filter="level">
+    <option value="">Any</option>
+    {{- range .SpellLevels }}
+    <option value="{{ . }}">{{ spellLevel . }}</option>
+    {{- end }}
+  </select></label>
+  <label>School <select data-filter="school">
+    <option value="">Any</option>
+    {{- range .SpellSchools }}
+    <option value="{{ . }}">{{ title . }}</option>
+    {{- end }}
+  </select></label>
+  <label>Class <select data-filter="classes">
+    <option value="">Any</option>
+    {{- range .SpellClasses }}
+    <option value="{{ . }}">{{ title . }}</option>
+    {{- end }}
+  </select></label>
+</form>
+<table class="spells">
+  <thead><tr><th>Name</th><th>Level</th><th>School</th><th>Classes</th></tr></thead>
+  <tbody>
+  {{- range .Book.Spells }}
+  {{- range .Spells }}
+  <tr data-level="{{ .Level }}" data-school="{{ .School }}" data-classes="{{ join (keys .SpellLists) " " }}">
+    <td><a href="{{ path "spells" .Key }}">{{ .Name }}</a></td>
+    <td>{{ spellLevel .Level }}</td>
+    <td>{{ title .School }}</td>
+    <td>{{ list .SpellLists }}</td>
+  </tr>
+  {{- end }}
+  {{- end }}
+  </tbody>
+</table>
+{{- else }}
+<ul class="entities">
+  {{- range .Kinds }}
+  {{- if eq .Kind $.Kind }}
+  {{- range .Entities }}
+  <li><a href="{{ path .EntityKind .EntityKey }}">{{ name .EntityKind .EntityKey }}</a></li>
+  {{- end }}
+  {{- end }}
+  {{- end }}
+</ul>
+{{- end }}
+{{- template "footer" . }}
+{{- end }}
+
+{{- define "features" }}
+{{- range features . }}
+<div class="trait"><b><i>{{ .Name }}.</i></b> {{ template "paragraphs" .Description }}</div>
+{{- end }}
+{{- end }}
+
+{{- define "class" }}
+<p>Hit Die: d{{ .HitDie }}</p>
+<table class="levels">
+  <thead><tr><th>Level</th><th>Proficiency Bonus</th><th>Features</th></tr></thead>
+  <tbody>
+  {{- range .Levels }}
+  <tr><td>{{ ordinal .Level }}</td><td>+{{ .ProficiencyBonus }}</td><td>{{ join .Features ", " }}</td></tr>
+  {{- end }}
+  </tbody>
+</table>
+{{- end }}
+
+{{- define "encounter" }}
+<ul class="creatures">
+  {{- range .Creatures }}
+  {{- $monster := .Creature.Monster }}
+  <li>{{ .Creature.Num }} × {{ with path "monsters" $monster }}<a href="{{ . }}">{{ name "monsters" $monster }}</a>{{ else }}{{ name "monsters" $monster }}{{ end }}</li>
+  {{- end }}
+</ul>
+{{- end }}
+
+{{- define "entity" }}
+{{- template "header" . }}
+{{- if eq .Kind "spells" }}
+{{ template "spell" .Entity }}
+{{- else if eq .Kind "monsters" }}
+{{ template "monster" .Entity }}
+{{- else }}
+<h1>{{ .Title }}</h1>
+<p class="subtitle">{{ title .Kind }}{{ with .Entity.EntityOptionPack }} from {{ . }}{{ end }}</p>
+{{- with .Class }}
+{{ template "class" . }}
+{{- end }}
+{{- if eq .Kind "encounters" }}
+{{ template "encounter" .Entity }}
+{{- end }}
+{{- with description .Entity }}
+<div class="description">{{ template "paragraphs" . }}</div>
+{{- end }}
+{{- template "features" .Entity }}
+{{- end }}
+{{- with .References }}
+<h2>References</h2>
+<ul class="references">
+  {{- range . }}
+  <li>{{ template "link" . }} <span class="kind">{{ title .Kind }}, {{ .Field }}</span></li>
+  {{- end }}
+</ul>
+{{- end }}
+{{- with .ReferencedBy }}
+<h2>Referenced By</h2>
+<ul class="references">
+  {{- range . }}
+  <li>{{ template "link" . }} <span class="kind">{{ title .Kind }}, {{ .Field }}</span></li>
+  {{- end }}
+</ul>
+{{- end }}
+{{- template "footer" . }}
+{{- end }}
+`
+
+const siteStyle = `
+nav { display: flex; flex-wrap: wrap; gap: 0.5em 1em; align-items: center; position: relative; padding-bottom: 0.5em; border-bottom: 2px solid #922610; }
+nav a { color: #58180d; }
+#search { margin-left: auto; }
+#results { display: none; position: absolute; right: 0; top: 2em; z-index: 1; margin: 0; padding: 0.5em 1em; list-style: none; background: #fff; border: 1px solid #922610; max-height: 60vh; overflow-y: auto; }
+#results.open { display: block; }
+#results .kind, .references .kind { color: #777; font-size: 0.85em; }
+main .card { height: auto; width: 4in; font-size: 10pt; }
+main .card h3 { font-size: 16pt; }
+table.spells, table.levels { border-collapse: collapse; }
+table.spells td, table.spells th, table.levels td, table.levels th { padding: 0.2em 0.6em; border-bottom: 1px solid #ddd; text-align: left; }
+.filters { margin-bottom: 1em; }
+.subtitle { font-style: italic; }
+@media print {
+  nav { display: none; }
+}
+`
+
+const siteScript = `(function () {
+  "use strict";
+
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+
+  function search() {
+    var query = input.value.trim().toLowerCase();
+    results.innerHTML = "";
+    if (query === "") {
+      results.className = "";
+      return;
+    }
+
+    var matches = searchIndex.filter(function (entry) {
+      return entry.name.toLowerCase().indexOf(query) >= 0 ||
+        entry.text.toLowerCase().indexOf(query) >= 0;
+    });
+    matches.sort(function (a, b) {
+      var inA = a.name.toLowerCase().indexOf(query) >= 0;
+      var inB = b.name.toLowerCase().indexOf(query) >= 0;
+      return inA === inB ? a.name.localeCompare(b.name) : (inA ? -1 : 1);
+    });
+
+    matches.slice(0, 50).forEach(function (entry) {
+      var item = document.createElement("li");
+      var link = document.createElement("a");
+      link.href = entry.path;
+      link.textContent = entry.name;
+      var kind = document.createElement("span");
+      kind.className = "kind";
+      kind.textContent = " " + entry.kind;
+      item.appendChild(link);
+      item.appendChild(kind);
+      results.appendChild(item);
+    });
+    if (matches.length === 0) {
+      var none = document.createElement("li");
+      none.textContent = "No results";
+      results.appendChild(none);
+    }
+    results.className = "open";
+  }
+
+  if (input && typeof searchIndex !== "undefined") {
+    input.addEventListener("input", search);
+  }
+
+  var filters = document.querySelectorAll("[data-filter]");
+  function filter() {
+    var rows = document.querySelectorAll("table.spells tbody tr");
+    Array.prototype.forEach.call(rows, function (row) {
+      var visible = Array.prototype.every.call(filters, function (select) {
+        var values = (row.getAttribute("data-" + select.getAttribute("data-filter")) || "").split(" ");
+        return select.value === "" || values.indexOf(select.value) >= 0;
+      });
+      row.style.display = visible ? "" : "none";
+    });
+  }
+  Array.prototype.forEach.call(filters, function (select) {
+    select.addEventListener("change", filter);
+  });
+})();
+`
@@ -0,0 +1,291 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// vault is a book laid out as an Obsidian vault, with a note for each entity
+type vault struct {
+	*site
+	notes map[string]string // the path of each entity's note without .md, by "kind/key"
+}
+
+// vaultNote is what the note template is executed against
+type vaultNote struct {
+	Kind         string
+	Entity       schema.Entity
+	Class        *Class
+	References   []siteLink
+	ReferencedBy []siteLink
+}
+
+// WriteVault writes the book as an Obsidian vault in dir: a note for each
+// entity in a folder for its kind, with YAML front matter holding its kind,
+// key, option pack and the fields useful for searching such as a spell's
+// level, and a note for each kind listing its entities. References between
+// entities are written as wiki-links, so they show in Obsidian's graph and
+// backlinks.
+//
+// The notes are the same each time a book is written, and a note is only
+// written when its contents change, so that syncing and version control only
+// see notes that changed. Notes for entities that have since been removed
+// aren't deleted.
+func (b *Book) WriteVault(dir string) error {
+	v := &vault{site: newSite(b), notes: make(map[string]string)}
+	for _, kind := range v.Kinds {
+		used := make(map[string]bool)
+		for _, entity := range kind.Entities {
+			name := noteName(b.Name(kind.Kind, entity.EntityKey()))
+			if name == "" {
+				name = entity.EntityKey()
+			}
+			filename := name
+			for n := 2; used[strings.ToLower(filename)]; n++ {
+				filename = fmt.Sprintf("%s %d", name, n)
+			}
+			used[strings.ToLower(filename)] = true
+			v.notes[kind.Kind+"/"+entity.EntityKey()] = Title(kind.Kind) + "/" + filename
+		}
+	}
+
+	funcs := b.Funcs()
+	funcs["name"] = v.link
+	funcs["names"] = func(kind string, set interface{}) string {
+		var links []string
+		for _, key := range Keys(set) {
+			links = append(links, v.link(kind, key))
+		}
+		return strings.Join(links, ", ")
+	}
+	funcs["description"] = description
+	funcs["features"] = features
+
+	tmpl, err := template.New("markdown").Funcs(funcs).Parse(MarkdownTemplate)
+	if err == nil {
+		_, err = tmpl.New("note").Parse(noteTemplate)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, kind := range v.Kinds {
+		var index bytes.Buffer
+		fmt.Fprintf(&index, "# %s\n\n", Title(kind.Kind))
+
+		for _, entity := range kind.Entities {
+			note := &vaultNote{
+				Kind:         kind.Kind,
+				Entity:       entity,
+				References:   v.links(v.references(entity)),
+				ReferencedBy: v.links(v.referencedBy(entity)),
+			}
+			if kind.Kind == "classes" {
+				// Subclasses have their own notes, which link to the class
+				class := *b.class(entity.EntityKey())
+				class.Subclasses = nil
+				note.Class = &class
+			}
+
+			var body bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&body, "note", note); err != nil {
+				return err
+			}
+
+			front, err := frontMatter(v.properties(entity))
+			if err != nil {
+				return err
+			}
+
+			path := v.notes[kind.Kind+"/"+entity.EntityKey()]
+			contents := front + "# " + b.Name(kind.Kind, entity.EntityKey()) + "\n\n" + noteBody(body.Bytes())
+			if err := writeIfChanged(filepath.Join(dir, filepath.FromSlash(path)+".md"), contents); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(&index, "- %s\n", v.link(kind.Kind, entity.EntityKey()))
+		}
+
+		if err := writeIfChanged(filepath.Join(dir, Title(kind.Kind)+".md"), index.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// link returns a wiki-link to an entity's note, or its name when it has none
+func (v *vault) link(kind string, key string) string {
+	name := v.Book.Name(kind, key)
+	if path, ok := v.notes[kind+"/"+key]; ok {
+		return "[[" + path + "|" + name + "]]"
+	}
+	return name
+}
+
+// links sets the path of each link to the wiki-link for the entity
+func (v *vault) links(links []siteLink) []siteLink {
+	for idx := range links {
+		links[idx].Path = v.link(links[idx].Kind, links[idx].Key)
+	}
+	return links
+}
+
+// property is a front matter field
+type property struct {
+	Name  string
+	Value interface{}
+}
+
+// properties returns the front matter of an entity's note
+func (v *vault) properties(entity schema.Entity) []property {
+	props := []property{
+		{"kind", entity.EntityKind()},
+		{"key", entity.EntityKey()},
+		{"option-pack", entity.EntityOptionPack()},
+	}
+	add := func(name string, value interface{}) {
+		if value != nil && !reflect.ValueOf(value).IsZero() {
+			props = append(props, property{name, value})
+		}
+	}
+
+	switch e := entity.(type) {
+	case schema.SpellConfig:
+		props = append(props, property{"level", e.Level})
+		add("school", strings.ToLower(e.School))
+		add("ritual", e.Ritual)
+		add("casting-time", e.CastingTime)
+		add("range", e.Range)
+		add("duration", e.Duration)
+		add("concentration", strings.Contains(strings.ToLower(e.Duration), "concentration"))
+		add("spell-lists", Keys(e.SpellLists))
+	case schema.MonsterConfig:
+		add("size", string(e.Size))
+		add("type", e.Type)
+		add("alignment", e.Alignment)
+		add("cr", Challenge(e.Challenge))
+		add("armor-class", e.ArmorClass)
+		add("hit-points", HitPoints(e))
+	case schema.ClassConfig:
+		add("hit-die", e.HitDie)
+		add("subclass-level", e.SubclassLevel)
+		if e.Spellcasting != nil {
+			add("spellcasting-ability", string(e.Spellcasting.Ability))
+		}
+	case schema.SubclassConfig:
+		add("class", v.link("classes", e.Class))
+	case schema.RaceConfig:
+		add("size", string(e.Size))
+		add("speed", e.Speed)
+		add("darkvision", e.Darkvision)
+	case schema.SubraceConfig:
+		add("race", v.link("races", e.Race))
+		add("darkvision", e.Darkvision)
+	case schema.FeatConfig:
+		add("prereqs", e.Prereqs)
+	}
+
+	props = append(props, property{"tags", []string{"orcbrew/" + entity.EntityKind()}})
+	return props
+}
+
+// frontMatter returns the YAML front matter for the properties of a note
+func frontMatter(props []property) (string, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, prop := range props {
+		var value yaml.Node
+		if err := value.Encode(prop.Value); err != nil {
+			return "", err
+		}
+		if value.Kind == yaml.SequenceNode {
+			value.Style = yaml.FlowStyle
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: prop.Name}, &value)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return "", err
+	}
+	return "---\n" + buf.String() + "---\n\n", nil
+}
+
+var headingLine = regexp.MustCompile(`^#+ [^\n]*\n`)
+
+// noteBody tidies the output of an entity's template for a note: the
+// template's heading is dropped, as the note has its own, and runs of blank
+// lines are collapsed
+func noteBody(body []byte) string {
+	body = bytes.TrimLeft(body, "\n")
+	body = headingLine.ReplaceAll(body, nil)
+	body = blankLines.ReplaceAll(bytes.TrimLeft(body, "\n"), []byte("\n\n"))
+	return strings.TrimRight(string(body), "\n") + "\n"
+}
+
+// unsafeNoteChars are the characters Obsidian doesn't allow in note names or
+// that would break a wiki-link
+var unsafeNoteChars = regexp.MustCompile(`[*"\\/<>:|?#^\[\]]+`)
+
+// noteName returns the name of an entity's note, without extension
+func noteName(name string) string {
+	return strings.Trim(unsafeNoteChars.ReplaceAllString(name, "-"), " .-")
+}
+
+// writeIfChanged writes contents to filename unless it already holds them,
+// creating the directory if needed
+func writeIfChanged(filename string, contents string) error {
+	if existing, err := ioutil.ReadFile(filename); err == nil && string(existing) == contents {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, []byte(contents), 0644)
+}
+
+// noteTemplate renders the body of an entity's note, using the templates of
+// MarkdownTemplate where there is one for the kind
+const noteTemplate = `
+{{- if eq .Kind "classes" }}{{ template "class" .Class }}
+{{- else if eq .Kind "subclasses" }}{{ template "subclass" .Entity }}
+{{- else if or (eq .Kind "races") (eq .Kind "subraces") }}{{ template "race" .Entity }}
+{{- else if eq .Kind "backgrounds" }}{{ template "background" .Entity }}
+{{- else if eq .Kind "feats" }}{{ template "feat" .Entity }}
+{{- else if eq .Kind "spells" }}{{ template "spell" .Entity }}
+{{- else if eq .Kind "monsters" }}{{ template "monster" .Entity }}
+{{- else if eq .Kind "encounters" }}
+{{ range .Entity.Creatures }}- {{ .Creature.Num }} × {{ name "monsters" .Creature.Monster }}
+{{ end }}
+{{- else }}
+{{ range lines (description .Entity) }}{{ . }}
+
+{{ end }}
+{{ template "traits" features .Entity }}
+{{- end }}
+{{- with .References }}
+
+## References
+
+{{ range . }}- {{ .Path }} ({{ .Field }})
+{{ end }}
+{{- end }}
+{{- with .ReferencedBy }}
+
+## Referenced by
+
+{{ range . }}- {{ .Path }}
+{{ end }}
+{{- end }}
+`
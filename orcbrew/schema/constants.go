@@ -1,3 +1,6 @@
+// Code generated by go generate; DO NOT EDIT.
+// This file was generated from spec.json by internal/gen_schema.
+
 package schema
 
 // Ability is a type alias for abilities
@@ -13,6 +16,18 @@ const (
 	Charisma     Ability = "cha"
 )
 
+// Values returns every valid value for Ability
+func (Ability) Values() []string {
+	return []string{
+		string(Strength),
+		string(Dexterity),
+		string(Constitution),
+		string(Intelligence),
+		string(Wisdom),
+		string(Charisma),
+	}
+}
+
 // Size is a type alias for entity size
 type Size string
 
@@ -26,6 +41,18 @@ const (
 	Gargantuan Size = "gargantuan"
 )
 
+// Values returns every valid value for Size
+func (Size) Values() []string {
+	return []string{
+		string(Tiny),
+		string(Small),
+		string(Medium),
+		string(Large),
+		string(Huge),
+		string(Gargantuan),
+	}
+}
+
 // Skill is a type alias for skill proficiencies
 type Skill string
 
@@ -51,6 +78,30 @@ const (
 	Survival       Skill = "survival"
 )
 
+// Values returns every valid value for Skill
+func (Skill) Values() []string {
+	return []string{
+		string(Acrobatics),
+		string(AnimalHandling),
+		string(Arcana),
+		string(Athletics),
+		string(Deception),
+		string(History),
+		string(Insight),
+		string(Intimidation),
+		string(Investigation),
+		string(Medicine),
+		string(Nature),
+		string(Perception),
+		string(Performance),
+		string(Persuasion),
+		string(Religion),
+		string(SleightOfHand),
+		string(Stealth),
+		string(Survival),
+	}
+}
+
 // Damage is a type alias for damage types
 type Damage string
 
@@ -71,6 +122,25 @@ const (
 	Traps       Damage = "traps"
 )
 
+// Values returns every valid value for Damage
+func (Damage) Values() []string {
+	return []string{
+		string(Acid),
+		string(Bludgeoning),
+		string(Cold),
+		string(Fire),
+		string(Lightning),
+		string(Necrotic),
+		string(Piercing),
+		string(Poison),
+		string(Psychic),
+		string(Radiant),
+		string(Slashing),
+		string(Thunder),
+		string(Traps),
+	}
+}
+
 // Condition is a type alias for character conditions
 type Condition string
 
@@ -92,6 +162,26 @@ const (
 	Unconscious   Condition = "unconscious"
 )
 
+// Values returns every valid value for Condition
+func (Condition) Values() []string {
+	return []string{
+		string(Blinded),
+		string(Charmed),
+		string(Deafened),
+		string(Frightened),
+		string(Grapped),
+		string(Incapacitated),
+		string(Invisible),
+		string(Paralyzed),
+		string(Petrified),
+		string(Poisoned),
+		string(Prone),
+		string(Restrained),
+		string(Stunned),
+		string(Unconscious),
+	}
+}
+
 // Armor is a type alias for various armor classes, including shields
 type Armor string
 
@@ -104,6 +194,17 @@ const (
 	Unarmored   Armor = "unarmored"
 )
 
+// Values returns every valid value for Armor
+func (Armor) Values() []string {
+	return []string{
+		string(LightArmor),
+		string(MediumArmor),
+		string(HeavyArmor),
+		string(Shields),
+		string(Unarmored),
+	}
+}
+
 // Weapon is a type alias for classes of weapons
 type Weapon string
 
@@ -113,6 +214,14 @@ const (
 	Martial Weapon = "martial"
 )
 
+// Values returns every valid value for Weapon
+func (Weapon) Values() []string {
+	return []string{
+		string(Simple),
+		string(Martial),
+	}
+}
+
 // MonsterTraitAction is a type alias to define the valid values for the
 // "type" field of monster traits
 type MonsterTraitAction string
@@ -123,6 +232,14 @@ const (
 	MonsterTraitActionLegendaryAction MonsterTraitAction = "legendary-action"
 )
 
+// Values returns every valid value for MonsterTraitAction
+func (MonsterTraitAction) Values() []string {
+	return []string{
+		string(MonsterTraitActionAction),
+		string(MonsterTraitActionLegendaryAction),
+	}
+}
+
 // Currency is a currency abbreviation
 type Currency string
 
@@ -134,3 +251,14 @@ const (
 	Gold     Currency = "gp"
 	Platinum Currency = "pp"
 )
+
+// Values returns every valid value for Currency
+func (Currency) Values() []string {
+	return []string{
+		string(Copper),
+		string(Silver),
+		string(Electrum),
+		string(Gold),
+		string(Platinum),
+	}
+}
@@ -0,0 +1,296 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EntityDiff describes how an entity differs between two versions of a set
+// of option packs
+type EntityDiff struct {
+	Pack    string        `json:"pack"`
+	Kind    string        `json:"kind"`
+	Key     string        `json:"key"`
+	Name    string        `json:"name,omitempty"`
+	Status  string        `json:"status"` // "added", "removed" or "changed"
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange describes a change to one field of an entity. Fields holding
+// lists report the elements that were added or removed rather than the
+// whole list.
+type FieldChange struct {
+	Field string      `json:"field"` // the path to the field, e.g. "components/verbal"
+	Op    string      `json:"op"`    // "added", "removed" or "changed"
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// Diff compares two versions of a set of option packs entity by entity,
+// returning the entities that differ sorted by pack, kind and key
+func Diff(oldAll OrcbrewExportAll, newAll OrcbrewExportAll) ([]EntityDiff, error) {
+	packs := make(map[string]bool)
+	for pack := range oldAll {
+		packs[pack] = true
+	}
+	for pack := range newAll {
+		packs[pack] = true
+	}
+
+	var names []string
+	for pack := range packs {
+		names = append(names, pack)
+	}
+	sort.Strings(names)
+
+	var diffs []EntityDiff
+	for _, pack := range names {
+		oldSource, newSource := oldAll[pack], newAll[pack]
+
+		for _, kind := range Kinds {
+			oldEntities, newEntities := oldSource.kindMap(kind), newSource.kindMap(kind)
+
+			keys := sortedKeys(oldEntities)
+			for _, key := range sortedKeys(newEntities) {
+				if !oldEntities.MapIndex(reflect.ValueOf(key)).IsValid() {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				oldEntity := oldEntities.MapIndex(reflect.ValueOf(key))
+				newEntity := newEntities.MapIndex(reflect.ValueOf(key))
+
+				diff := EntityDiff{Pack: pack, Kind: kind, Key: key}
+				switch {
+				case !oldEntity.IsValid():
+					diff.Status = "added"
+					diff.Name = newEntity.FieldByName("Name").String()
+				case !newEntity.IsValid():
+					diff.Status = "removed"
+					diff.Name = oldEntity.FieldByName("Name").String()
+				default:
+					diff.Status = "changed"
+					diff.Name = newEntity.FieldByName("Name").String()
+
+					changes, err := diffEntities(oldEntity.Interface(), newEntity.Interface())
+					if err != nil {
+						return nil, fmt.Errorf("%s/%s/%s: %s", pack, kind, key, err)
+					}
+					if len(changes) == 0 {
+						continue
+					}
+					diff.Changes = changes
+				}
+				diffs = append(diffs, diff)
+			}
+		}
+	}
+
+	return diffs, nil
+}
+
+func diffEntities(oldEntity interface{}, newEntity interface{}) ([]FieldChange, error) {
+	oldValue, err := toJSONValue(oldEntity)
+	if err != nil {
+		return nil, err
+	}
+	newValue, err := toJSONValue(newEntity)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FieldChange
+	diffValues("", oldValue, newValue, &changes)
+	return changes, nil
+}
+
+func toJSONValue(v interface{}) (interface{}, error) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	err = json.Unmarshal(jsonBytes, &value)
+	return value, err
+}
+
+func diffValues(path string, oldValue interface{}, newValue interface{}, changes *[]FieldChange) {
+	if reflect.DeepEqual(oldValue, newValue) {
+		return
+	}
+
+	switch v := oldValue.(type) {
+	case map[string]interface{}:
+		newMap, ok := newValue.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make(map[string]bool)
+		for key := range v {
+			keys[key] = true
+		}
+		for key := range newMap {
+			keys[key] = true
+		}
+
+		var sorted []string
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sortKeys(sorted)
+
+		for _, key := range sorted {
+			fieldPath := joinPath(path, key)
+			oldField, inOld := v[key]
+			newField, inNew := newMap[key]
+			switch {
+			case !inOld:
+				*changes = append(*changes, FieldChange{Field: fieldPath, Op: "added", New: newField})
+			case !inNew:
+				*changes = append(*changes, FieldChange{Field: fieldPath, Op: "removed", Old: oldField})
+			default:
+				diffValues(fieldPath, oldField, newField, changes)
+			}
+		}
+		return
+	case []interface{}:
+		newList, ok := newValue.([]interface{})
+		if !ok {
+			break
+		}
+		diffLists(path, v, newList, changes)
+		return
+	}
+
+	*changes = append(*changes, FieldChange{Field: path, Op: "changed", Old: oldValue, New: newValue})
+}
+
+// diffLists reports the elements added to and removed from a list. Elements
+// with the same name in both lists are compared field by field instead.
+func diffLists(path string, oldList []interface{}, newList []interface{}, changes *[]FieldChange) {
+	remaining := make(map[string]int)
+	for _, elem := range newList {
+		remaining[canonical(elem)]++
+	}
+
+	var removed []interface{}
+	for _, elem := range oldList {
+		key := canonical(elem)
+		if remaining[key] > 0 {
+			remaining[key]--
+		} else {
+			removed = append(removed, elem)
+		}
+	}
+
+	var added []interface{}
+	for _, elem := range newList {
+		key := canonical(elem)
+		if remaining[key] > 0 {
+			remaining[key]--
+			added = append(added, elem)
+		}
+	}
+
+	if len(removed) == 0 && len(added) == 0 {
+		// Only the order of the elements changed
+		*changes = append(*changes, FieldChange{Field: path, Op: "changed", Old: oldList, New: newList})
+		return
+	}
+
+	for _, elem := range removed {
+		name := elementName(elem)
+		paired := false
+		for idx, other := range added {
+			if name != "" && elementName(other) == name {
+				diffValues(joinPath(path, name), elem, other, changes)
+				added = append(added[:idx], added[idx+1:]...)
+				paired = true
+				break
+			}
+		}
+
+		if !paired {
+			*changes = append(*changes, FieldChange{Field: path, Op: "removed", Old: elem})
+		}
+	}
+
+	for _, elem := range added {
+		*changes = append(*changes, FieldChange{Field: path, Op: "added", New: elem})
+	}
+}
+
+func canonical(v interface{}) string {
+	jsonBytes, _ := json.Marshal(v)
+	return string(jsonBytes)
+}
+
+func elementName(v interface{}) string {
+	if m, ok := v.(map[string]interface{}); ok {
+		if name, ok := m["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+func joinPath(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "/" + field
+}
+
+func sortKeys(keys []string) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+// FormatValue returns a short, human readable form of a value from a
+// FieldChange. Level modifiers are written as "<type> <value> @<level>" and
+// other objects by their name or key when they have one.
+func FormatValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "(none)"
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case map[string]interface{}:
+		if name, ok := value["name"].(string); ok {
+			return name
+		}
+		if modifierType, ok := value["type"].(string); ok {
+			parts := []string{modifierType}
+			if value["value"] != nil {
+				parts = append(parts, FormatValue(value["value"]))
+			}
+			if value["level"] != nil {
+				parts = append(parts, "@"+FormatValue(value["level"]))
+			}
+			return strings.Join(parts, " ")
+		}
+		if key, ok := value["key"].(string); ok {
+			return key
+		}
+	}
+
+	return canonical(v)
+}
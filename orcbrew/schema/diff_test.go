@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestDiff(t *testing.T) {
+	old := OrcbrewExportAll{
+		"Test": OrcbrewSource{
+			Spells: map[string]SpellConfig{
+				"myspell":  SpellConfig{Key: "myspell", OptionPack: "Test", Name: "MySpell", Level: 0},
+				"oldspell": SpellConfig{Key: "oldspell", OptionPack: "Test", Name: "OldSpell"},
+			},
+			Classes: map[string]ClassConfig{
+				"myclass": ClassConfig{Key: "myclass", OptionPack: "Test", Name: "MyClass",
+					LevelModifiers: LevelModifierList{&ModifierArmorProficiency{Value: LightArmor}},
+					Traits:         []LevelTrait{{Name: "Rage", Description: "Get angry"}}},
+			},
+		},
+	}
+
+	new := OrcbrewExportAll{
+		"Test": OrcbrewSource{
+			Spells: map[string]SpellConfig{
+				"myspell":  SpellConfig{Key: "myspell", OptionPack: "Test", Name: "MySpell", Level: 1},
+				"newspell": SpellConfig{Key: "newspell", OptionPack: "Test", Name: "NewSpell"},
+			},
+			Classes: map[string]ClassConfig{
+				"myclass": ClassConfig{Key: "myclass", OptionPack: "Test", Name: "MyClass",
+					LevelModifiers: LevelModifierList{
+						&ModifierArmorProficiency{Value: LightArmor},
+						&ModifierSkillProficiency{Level: 3, Value: Athletics},
+					},
+					Traits: []LevelTrait{{Name: "Rage", Description: "Get very angry"}}},
+			},
+		},
+	}
+
+	diffs, err := Diff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []EntityDiff{
+		{Pack: "Test", Kind: "classes", Key: "myclass", Name: "MyClass", Status: "changed", Changes: []FieldChange{
+			{Field: "level-modifiers", Op: "added", New: map[string]interface{}{"type": "skill-prof", "value": "athletics", "level": 3.0}},
+			{Field: "traits/Rage/description", Op: "changed", Old: "Get angry", New: "Get very angry"},
+		}},
+		{Pack: "Test", Kind: "spells", Key: "myspell", Name: "MySpell", Status: "changed", Changes: []FieldChange{
+			{Field: "level", Op: "changed", Old: 0.0, New: 1.0},
+		}},
+		{Pack: "Test", Kind: "spells", Key: "newspell", Name: "NewSpell", Status: "added"},
+		{Pack: "Test", Kind: "spells", Key: "oldspell", Name: "OldSpell", Status: "removed"},
+	}
+	if diff := deep.Equal(diffs, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	if diffs, _ := Diff(old, old); len(diffs) != 0 {
+		t.Errorf("Expected no differences, got %v", diffs)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "(none)"},
+		{3.0, "3"},
+		{0.5, "0.5"},
+		{true, "true"},
+		{map[string]interface{}{"type": "skill-prof", "value": "athletics", "level": 3.0}, "skill-prof athletics @3"},
+		{map[string]interface{}{"name": "Rage", "description": "Get angry"}, "Rage"},
+		{[]interface{}{"a", "b"}, `["a","b"]`},
+	}
+
+	for _, test := range tests {
+		if actual := FormatValue(test.value); actual != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, actual)
+		}
+	}
+}
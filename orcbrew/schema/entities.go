@@ -0,0 +1,259 @@
+// Code generated by go generate; DO NOT EDIT.
+// This file was generated from spec.json by internal/gen_schema.
+
+package schema
+
+// EntityKind returns "languages"
+func (LanguageConfig) EntityKind() string {
+	return "languages"
+}
+
+// EntityKey returns the key of the entity
+func (e LanguageConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e LanguageConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e LanguageConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "classes"
+func (ClassConfig) EntityKind() string {
+	return "classes"
+}
+
+// EntityKey returns the key of the entity
+func (e ClassConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e ClassConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e ClassConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "subclasses"
+func (SubclassConfig) EntityKind() string {
+	return "subclasses"
+}
+
+// EntityKey returns the key of the entity
+func (e SubclassConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e SubclassConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e SubclassConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "monsters"
+func (MonsterConfig) EntityKind() string {
+	return "monsters"
+}
+
+// EntityKey returns the key of the entity
+func (e MonsterConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e MonsterConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e MonsterConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "feats"
+func (FeatConfig) EntityKind() string {
+	return "feats"
+}
+
+// EntityKey returns the key of the entity
+func (e FeatConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e FeatConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e FeatConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "backgrounds"
+func (BackgroundConfig) EntityKind() string {
+	return "backgrounds"
+}
+
+// EntityKey returns the key of the entity
+func (e BackgroundConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e BackgroundConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e BackgroundConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "invocations"
+func (InvocationConfig) EntityKind() string {
+	return "invocations"
+}
+
+// EntityKey returns the key of the entity
+func (e InvocationConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e InvocationConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e InvocationConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "subraces"
+func (SubraceConfig) EntityKind() string {
+	return "subraces"
+}
+
+// EntityKey returns the key of the entity
+func (e SubraceConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e SubraceConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e SubraceConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "spells"
+func (SpellConfig) EntityKind() string {
+	return "spells"
+}
+
+// EntityKey returns the key of the entity
+func (e SpellConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e SpellConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e SpellConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "encounters"
+func (EncounterConfig) EntityKind() string {
+	return "encounters"
+}
+
+// EntityKey returns the key of the entity
+func (e EncounterConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e EncounterConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e EncounterConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "selections"
+func (SelectionConfig) EntityKind() string {
+	return "selections"
+}
+
+// EntityKey returns the key of the entity
+func (e SelectionConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e SelectionConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e SelectionConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+// EntityKind returns "races"
+func (RaceConfig) EntityKind() string {
+	return "races"
+}
+
+// EntityKey returns the key of the entity
+func (e RaceConfig) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e RaceConfig) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e RaceConfig) EntityOptionPack() string {
+	return e.OptionPack
+}
+
+var (
+	_ Entity = LanguageConfig{}
+	_ Entity = ClassConfig{}
+	_ Entity = SubclassConfig{}
+	_ Entity = MonsterConfig{}
+	_ Entity = FeatConfig{}
+	_ Entity = BackgroundConfig{}
+	_ Entity = InvocationConfig{}
+	_ Entity = SubraceConfig{}
+	_ Entity = SpellConfig{}
+	_ Entity = EncounterConfig{}
+	_ Entity = SelectionConfig{}
+	_ Entity = RaceConfig{}
+)
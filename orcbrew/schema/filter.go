@@ -0,0 +1,268 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Filter selects a subset of the entities in a source. Each non-empty
+// criterion must match for an entity to be selected.
+type Filter struct {
+	Kinds      []string    // the kinds of entity to select, e.g. "spells"
+	Keys       []string    // glob patterns (see path.Match) matched against entity keys
+	Packs      []string    // the option packs to select entities from
+	Predicates []Predicate // conditions on entity fields
+
+	// When set, entities referenced by selected entities are also selected
+	// (e.g. the selections unlocked by a subclass), as long as they are
+	// defined in the same source, so the result is self-contained
+	IncludeReferences bool
+}
+
+// Predicate is a condition on a field of an entity, such as "level<=3"
+type Predicate struct {
+	Field string // the JSON name of the field, with "." separating nested fields
+	Op    string // one of =, !=, <, <=, >, >=
+	Value string
+}
+
+var predicateOps = []string{"<=", ">=", "!=", "=", "<", ">"}
+
+// ParsePredicate parses a condition of the form <field><op><value>, e.g.
+// "school=necromancy" or "challenge>=5"
+func ParsePredicate(s string) (Predicate, error) {
+	idx := strings.IndexAny(s, "<>=!")
+	if idx <= 0 {
+		return Predicate{}, fmt.Errorf("Invalid predicate %q, expected <field><op><value>", s)
+	}
+
+	for _, op := range predicateOps {
+		if strings.HasPrefix(s[idx:], op) {
+			return Predicate{
+				Field: strings.TrimSpace(s[:idx]),
+				Op:    op,
+				Value: strings.TrimSpace(s[idx+len(op):]),
+			}, nil
+		}
+	}
+
+	return Predicate{}, fmt.Errorf("Invalid operator in predicate %q", s)
+}
+
+func (p Predicate) String() string {
+	return p.Field + p.Op + p.Value
+}
+
+// Match returns whether the predicate holds for an entity. For fields that
+// hold sets (maps to booleans) or lists, = and != test for membership.
+func (p Predicate) Match(entity interface{}) (bool, error) {
+	jsonBytes, err := json.Marshal(entity)
+	if err != nil {
+		return false, err
+	}
+
+	var value interface{}
+	err = json.Unmarshal(jsonBytes, &value)
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range strings.Split(p.Field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			value = nil
+			break
+		}
+		value = object[name]
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return p.Op == "!=", nil
+	case float64:
+		n, err := strconv.ParseFloat(p.Value, 64)
+		if err != nil {
+			return false, fmt.Errorf("%s: %s is a number", p, p.Field)
+		}
+		return compare(p.Op, v-n), nil
+	case string:
+		return compare(p.Op, float64(strings.Compare(v, p.Value))), nil
+	case bool:
+		b, err := strconv.ParseBool(p.Value)
+		if err != nil || (p.Op != "=" && p.Op != "!=") {
+			return false, fmt.Errorf("%s: %s is a boolean", p, p.Field)
+		}
+		return (v == b) == (p.Op == "="), nil
+	case map[string]interface{}:
+		if p.Op != "=" && p.Op != "!=" {
+			return false, fmt.Errorf("%s: %s is a set, only = and != are supported", p, p.Field)
+		}
+		member, ok := v[p.Value]
+		return (ok && member != false && member != nil) == (p.Op == "="), nil
+	case []interface{}:
+		if p.Op != "=" && p.Op != "!=" {
+			return false, fmt.Errorf("%s: %s is a list, only = and != are supported", p, p.Field)
+		}
+		found := false
+		for _, elem := range v {
+			if fmt.Sprint(elem) == p.Value {
+				found = true
+			}
+		}
+		return found == (p.Op == "="), nil
+	}
+
+	return false, fmt.Errorf("%s: can't compare %s", p, p.Field)
+}
+
+func compare(op string, diff float64) bool {
+	switch op {
+	case "=":
+		return diff == 0
+	case "!=":
+		return diff != 0
+	case "<":
+		return diff < 0
+	case "<=":
+		return diff <= 0
+	case ">":
+		return diff > 0
+	case ">=":
+		return diff >= 0
+	}
+	return false
+}
+
+// Source returns a new source containing the entities selected by the filter
+func (f Filter) Source(src OrcbrewSource) (OrcbrewSource, error) {
+	selected := make(map[Reference]bool)
+	var pending []Reference
+
+	for _, kind := range Kinds {
+		if len(f.Kinds) > 0 && !containsString(f.Kinds, kind) {
+			continue
+		}
+
+		entities := src.kindMap(kind)
+		for _, key := range sortedKeys(entities) {
+			entity := entities.MapIndex(reflect.ValueOf(key)).Interface()
+
+			ok, err := f.match(key, entity)
+			if err != nil {
+				return OrcbrewSource{}, fmt.Errorf("%s/%s: %s", kind, key, err)
+			}
+			if ok {
+				ref := Reference{Kind: kind, Key: key}
+				selected[ref] = true
+				pending = append(pending, ref)
+			}
+		}
+	}
+
+	// Follow references to entities defined in the same source
+	for f.IncludeReferences && len(pending) > 0 {
+		ref := pending[0]
+		pending = pending[1:]
+
+		for _, target := range References(src.entityPointer(ref.Kind, ref.Key)) {
+			target.Field = ""
+			if !selected[target] && src.entityPointer(target.Kind, target.Key) != nil {
+				selected[target] = true
+				pending = append(pending, target)
+			}
+		}
+	}
+
+	var result OrcbrewSource
+	for ref := range selected {
+		entity := src.kindMap(ref.Kind).MapIndex(reflect.ValueOf(ref.Key))
+		setEntity(result.kindMap(ref.Kind), ref.Key, entity)
+	}
+
+	return result, nil
+}
+
+// ExportAll applies the filter to each option pack, dropping packs that have
+// no selected entities
+func (f Filter) ExportAll(all OrcbrewExportAll) (OrcbrewExportAll, error) {
+	result := make(OrcbrewExportAll)
+	for pack, source := range all {
+		if len(f.Packs) > 0 && !containsString(f.Packs, pack) {
+			continue
+		}
+
+		filtered, err := f.Source(source)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", pack, err)
+		}
+
+		if !reflect.DeepEqual(filtered, OrcbrewSource{}) {
+			result[pack] = filtered
+		}
+	}
+
+	return result, nil
+}
+
+func (f Filter) match(key string, entity interface{}) (bool, error) {
+	if len(f.Keys) > 0 {
+		matched := false
+		for _, pattern := range f.Keys {
+			ok, err := path.Match(pattern, key)
+			if err != nil {
+				return false, err
+			}
+			matched = matched || ok
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	if len(f.Packs) > 0 {
+		pack := reflect.ValueOf(entity).FieldByName("OptionPack").String()
+		if !containsString(f.Packs, pack) {
+			return false, nil
+		}
+	}
+
+	for _, predicate := range f.Predicates {
+		ok, err := predicate.Match(entity)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// entityPointer returns a pointer to a copy of the entity with the given kind
+// and key, or nil if there is no such entity
+func (s *OrcbrewSource) entityPointer(kind string, key string) interface{} {
+	entities := s.kindMap(kind)
+	if !entities.IsValid() {
+		return nil
+	}
+
+	entity := entities.MapIndex(reflect.ValueOf(key))
+	if !entity.IsValid() {
+		return nil
+	}
+
+	ptr := reflect.New(entity.Type())
+	ptr.Elem().Set(entity)
+	return ptr.Interface()
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
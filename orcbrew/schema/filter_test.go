@@ -0,0 +1,166 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestParsePredicate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Predicate
+	}{
+		{"level<=3", Predicate{Field: "level", Op: "<=", Value: "3"}},
+		{"school=necromancy", Predicate{Field: "school", Op: "=", Value: "necromancy"}},
+		{"challenge >= 5", Predicate{Field: "challenge", Op: ">=", Value: "5"}},
+		{"components.verbal!=true", Predicate{Field: "components.verbal", Op: "!=", Value: "true"}},
+	}
+
+	for _, test := range tests {
+		predicate, err := ParsePredicate(test.input)
+		if err != nil {
+			t.Errorf("%s: %s", test.input, err)
+			continue
+		}
+		if diff := deep.Equal(predicate, test.expected); diff != nil {
+			t.Errorf("%s: %s", test.input, diff)
+		}
+	}
+
+	for _, input := range []string{"level", "=3"} {
+		if _, err := ParsePredicate(input); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
+
+func TestPredicateMatch(t *testing.T) {
+	source, _ := LoadSourceFile(t, "example.json")
+	spell := source.Spells["myspell"]
+
+	tests := []struct {
+		predicate string
+		expected  bool
+	}{
+		{"level<=3", true},
+		{"level>3", false},
+		{"school=" + spell.School, true},
+		{"school!=" + spell.School, false},
+		{"components.verbal=true", spell.Components.Verbal},
+		{"spell-lists=wizard", spell.SpellLists["wizard"]},
+		{"missing-field=1", false},
+		{"missing-field!=1", true},
+	}
+
+	for _, test := range tests {
+		predicate, err := ParsePredicate(test.predicate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		matched, err := predicate.Match(spell)
+		if err != nil {
+			t.Errorf("%s: %s", test.predicate, err)
+		} else if matched != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.predicate, test.expected, matched)
+		}
+	}
+
+	predicate, _ := ParsePredicate("level=abc")
+	if _, err := predicate.Match(spell); err == nil {
+		t.Errorf("Expected an error comparing a number to abc")
+	}
+}
+
+func TestFilterSource(t *testing.T) {
+	source, _ := LoadSourceFile(t, "example.json")
+
+	filter := Filter{Kinds: []string{"classes"}, Keys: []string{"my*"}}
+	result, err := filter.Source(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Classes) != 1 || result.Classes["myclass"].Key != "myclass" {
+		t.Errorf("Expected only myclass, got %v", result.Classes)
+	}
+	if result.Spells != nil {
+		t.Errorf("Expected no spells, got %v", result.Spells)
+	}
+
+	predicate, _ := ParsePredicate("challenge>=5")
+	filter = Filter{Predicates: []Predicate{predicate}}
+	result, err = filter.Source(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Monsters) != 0 {
+		t.Errorf("Expected no monsters with challenge >= 5, got %v", result.Monsters)
+	}
+
+	filter = Filter{Packs: []string{"Other"}}
+	result, _ = filter.Source(source)
+	if diff := deep.Equal(result, OrcbrewSource{}); diff != nil {
+		t.Errorf("Expected nothing from pack Other: %s", diff)
+	}
+}
+
+func TestFilterIncludeReferences(t *testing.T) {
+	source, _ := LoadSourceFile(t, "example.json")
+
+	filter := Filter{Kinds: []string{"subclasses"}, IncludeReferences: true}
+	result, err := filter.Source(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := result.Subclasses["mysubclass"]; !ok {
+		t.Errorf("Expected mysubclass to be selected")
+	}
+	if _, ok := result.Selections["my-selection-thingy"]; !ok {
+		t.Errorf("Expected the selection referenced by mysubclass to be included")
+	}
+	if len(result.Classes) != 0 {
+		t.Errorf("Expected the built-in class barbarian not to add classes, got %v", result.Classes)
+	}
+}
+
+func TestFilterExportAll(t *testing.T) {
+	inputs := mergeInputs()
+
+	filter := Filter{Packs: []string{"Other"}}
+	result, err := filter.ExportAll(inputs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(result, OrcbrewExportAll{"Other": inputs[1]["Other"]}); diff != nil {
+		t.Error(diff)
+	}
+
+	filter = Filter{Kinds: []string{"feats"}}
+	result, _ = filter.ExportAll(inputs[1])
+	if len(result) != 1 || len(result["Test"].Feats) != 1 {
+		t.Errorf("Expected only the Test pack with one feat, got %v", result)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	source, _ := LoadSourceFile(t, "example.json")
+
+	subclass := source.Subclasses["mysubclass"]
+	expected := []Reference{
+		{Kind: "classes", Key: "barbarian", Field: "class"},
+		{Kind: "selections", Key: "my-selection-thingy", Field: "level-selections/0/type"},
+	}
+	if diff := deep.Equal(References(&subclass), expected); diff != nil {
+		t.Error(diff)
+	}
+
+	encounter := source.Encounters["goblin-ambush"]
+	expected = []Reference{
+		{Kind: "monsters", Key: "goblin", Field: "creatures/0/creature/monster"},
+	}
+	if diff := deep.Equal(References(&encounter), expected); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,39 @@
+package schema
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema/internal/codegen"
+)
+
+// Fails when constants.go, modifiers.go or entities.go no longer match spec.json
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	spec, err := codegen.LoadSpec("spec.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated := map[string]func() ([]byte, error){
+		"constants.go": spec.RenderConstants,
+		"modifiers.go": spec.RenderModifiers,
+		"entities.go":  spec.RenderEntities,
+	}
+
+	for filename, render := range generated {
+		expected, err := render()
+		if err != nil {
+			t.Fatalf("Error generating %s: %s", filename, err)
+		}
+
+		actual, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("Error reading %s: %s", filename, err)
+		}
+
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("%s is stale, run go generate", filename)
+		}
+	}
+}
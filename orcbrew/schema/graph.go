@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Graph describes how the entities in a set of option packs refer to each
+// other, including references to built-in content that isn't defined in
+// any of the packs
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Node is an entity in a Graph
+type Node struct {
+	ID      string `json:"id"` // kind/key, e.g. "classes/barbarian"
+	Kind    string `json:"kind"`
+	Key     string `json:"key"`
+	Name    string `json:"name,omitempty"`
+	Pack    string `json:"pack,omitempty"`    // the option pack defining the entity
+	BuiltIn bool   `json:"builtIn,omitempty"` // whether it's referenced but not defined
+}
+
+// Edge is a reference from one entity to another in a Graph
+type Edge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"` // e.g. "subclass of", see relations
+	Field    string `json:"field"`    // the first field making the reference
+}
+
+// relations describes each reference by the field it's made from, or the
+// top level field it's in
+var relations = map[string]string{
+	"class":                      "subclass of",
+	"race":                       "subrace of",
+	"level-selections":           "unlocks",
+	"level-modifiers":            "grants",
+	"spellcasting":               "grants",
+	"spellcasting/spell-list-kw": "uses spell list of",
+	"cleric-spells":              "grants",
+	"spells":                     "grants",
+	"spell-lists":                "on spell list of",
+	"path-prereqs":               "requires",
+	"creatures":                  "includes",
+}
+
+// relation returns the relation of a reference made from a field
+func relation(field string) string {
+	parts := strings.Split(field, "/")
+	if len(parts) > 1 {
+		if relation, ok := relations[parts[0]+"/"+parts[1]]; ok {
+			return relation
+		}
+	}
+	return relations[parts[0]]
+}
+
+// NewGraph returns the graph of references between the entities in all
+func NewGraph(all OrcbrewExportAll) *Graph {
+	g := &Graph{}
+	nodes := make(map[string]bool)
+	edges := make(map[string]bool)
+
+	for _, pack := range sortedPacks(all) {
+		source := all[pack]
+		for _, kind := range Kinds {
+			entities := source.kindMap(kind)
+			for _, key := range sortedKeys(entities) {
+				id := kind + "/" + key
+				if !nodes[id] {
+					nodes[id] = true
+					name := entities.MapIndex(reflect.ValueOf(key)).FieldByName("Name").String()
+					g.Nodes = append(g.Nodes, Node{ID: id, Kind: kind, Key: key, Name: name, Pack: pack})
+				}
+			}
+		}
+	}
+
+	for _, pack := range sortedPacks(all) {
+		source := all[pack]
+		for _, kind := range Kinds {
+			for _, key := range sortedKeys(source.kindMap(kind)) {
+				from := kind + "/" + key
+				for _, ref := range References(source.entityPointer(kind, key)) {
+					to := ref.String()
+					if !nodes[to] {
+						nodes[to] = true
+						g.Nodes = append(g.Nodes, Node{ID: to, Kind: ref.Kind, Key: ref.Key, BuiltIn: true})
+					}
+
+					relation := relation(ref.Field)
+					edge := from + " " + to + " " + relation
+					if !edges[edge] {
+						edges[edge] = true
+						g.Edges = append(g.Edges, Edge{From: from, To: to, Relation: relation, Field: ref.Field})
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	return g
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, with entities
+// grouped by kind and built-in content drawn with dashed outlines
+func (g *Graph) WriteDOT(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+
+	fmt.Fprintf(buf, "digraph orcbrew {\n")
+	fmt.Fprintf(buf, "  rankdir=LR;\n")
+	fmt.Fprintf(buf, "  node [shape=box];\n")
+
+	var kind string
+	for _, node := range g.Nodes {
+		if node.Kind != kind {
+			if kind != "" {
+				fmt.Fprintf(buf, "  }\n")
+			}
+			kind = node.Kind
+			fmt.Fprintf(buf, "  subgraph %s {\n", strconv.Quote("cluster_"+kind))
+			fmt.Fprintf(buf, "    label=%s;\n", strconv.Quote(kind))
+		}
+
+		label := node.Name
+		if label == "" {
+			label = node.Key
+		}
+		style := ""
+		if node.BuiltIn {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(buf, "    %s [label=%s%s];\n", strconv.Quote(node.ID), strconv.Quote(label), style)
+	}
+	if kind != "" {
+		fmt.Fprintf(buf, "  }\n")
+	}
+
+	for _, edge := range g.Edges {
+		fmt.Fprintf(buf, "  %s -> %s [label=%s];\n", strconv.Quote(edge.From), strconv.Quote(edge.To), strconv.Quote(edge.Relation))
+	}
+	fmt.Fprintf(buf, "}\n")
+
+	return buf.Flush()
+}
@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestNewGraph(t *testing.T) {
+	g := NewGraph(renameInput())
+
+	expectedNodes := []Node{
+		{ID: "classes/myclass", Kind: "classes", Key: "myclass", Name: "MyClass", Pack: "Test"},
+		{ID: "feats/myfeat", Kind: "feats", Key: "myfeat", Pack: "Test"},
+		{ID: "races/elf", Kind: "races", Key: "elf", BuiltIn: true},
+		{ID: "races/myrace", Kind: "races", Key: "myrace", Name: "MyRace", Pack: "Test"},
+		{ID: "selections/myselection", Kind: "selections", Key: "myselection", Name: "MySelection", Pack: "Test"},
+		{ID: "subclasses/mysubclass", Kind: "subclasses", Key: "mysubclass", Pack: "Test"},
+		{ID: "subclasses/othersubclass", Kind: "subclasses", Key: "othersubclass", Pack: "Other"},
+	}
+	if diff := deep.Equal(g.Nodes, expectedNodes); diff != nil {
+		t.Error(diff)
+	}
+
+	expectedEdges := []Edge{
+		{From: "subclasses/othersubclass", To: "classes/myclass", Relation: "subclass of", Field: "class"},
+		{From: "subclasses/mysubclass", To: "classes/myclass", Relation: "subclass of", Field: "class"},
+		{From: "subclasses/mysubclass", To: "selections/myselection", Relation: "unlocks", Field: "level-selections/0/type"},
+		{From: "feats/myfeat", To: "races/elf", Relation: "requires", Field: "path-prereqs/race/elf"},
+		{From: "feats/myfeat", To: "races/myrace", Relation: "requires", Field: "path-prereqs/race/myrace"},
+	}
+	if diff := deep.Equal(g.Edges, expectedEdges); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestGraphSpellLists(t *testing.T) {
+	all := renameInput()
+	source := all["Test"]
+	source.Spells = map[string]SpellConfig{
+		"myspell": SpellConfig{Key: "myspell", OptionPack: "Test", SpellLists: map[string]bool{"myclass": true}},
+	}
+	source.Classes["myclass"] = ClassConfig{Key: "myclass", OptionPack: "Test", Name: "MyClass",
+		Spellcasting: &SpellcastingConfig{SpellListKw: "wizard", SpellList: map[int][]string{0: {"myspell"}}}}
+	all["Test"] = source
+
+	var edges []Edge
+	for _, edge := range NewGraph(all).Edges {
+		if edge.Relation == "" {
+			t.Errorf("Expected a relation for %v", edge)
+		}
+		if strings.HasPrefix(edge.Field, "spell") {
+			edges = append(edges, edge)
+		}
+	}
+
+	expected := []Edge{
+		{From: "classes/myclass", To: "classes/wizard", Relation: "uses spell list of", Field: "spellcasting/spell-list-kw"},
+		{From: "classes/myclass", To: "spells/myspell", Relation: "grants", Field: "spellcasting/spell-list/0/0"},
+		{From: "spells/myspell", To: "classes/myclass", Relation: "on spell list of", Field: "spell-lists/myclass"},
+	}
+	if diff := deep.Equal(edges, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewGraph(renameInput()).WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dot := buf.String()
+	for _, expected := range []string{
+		`subgraph "cluster_races" {`,
+		`"races/elf" [label="elf", style=dashed];`,
+		`"subclasses/mysubclass" -> "classes/myclass" [label="subclass of"];`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("Expected %s in:\n%s", expected, dot)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Entity is implemented by each of the entity types in an OrcbrewSource. The
+// methods are prefixed with Entity as the types already have fields named
+// Key, Name and OptionPack.
+type Entity interface {
+	EntityKind() string       // the kind of entity, e.g. "spells"
+	EntityKey() string        // the key of the entity
+	EntityName() string       // the name of the entity
+	EntityOptionPack() string // the option pack the entity belongs to
+}
+
+// Referrer is an entity that refers to another entity, see ReferencesTo
+type Referrer struct {
+	Entity Entity
+	Field  string // where the reference is made, e.g. "class"
+}
+
+// Index provides lookups by key and name, and reverse references, over the
+// entities in one or more sources
+type Index struct {
+	entities     []Entity
+	byKey        map[string]Entity
+	byName       map[string][]Entity
+	byPack       map[string][]Entity
+	referencedBy map[string][]Referrer
+}
+
+// NewIndex returns an index of the entities in the given sources. When
+// several sources define the same kind and key, Lookup returns the first.
+func NewIndex(sources ...OrcbrewSource) *Index {
+	idx := &Index{
+		byKey:        make(map[string]Entity),
+		byName:       make(map[string][]Entity),
+		byPack:       make(map[string][]Entity),
+		referencedBy: make(map[string][]Referrer),
+	}
+
+	for _, source := range sources {
+		idx.Add(source)
+	}
+	return idx
+}
+
+// NewIndexExportAll returns an index of the entities in every option pack,
+// see NewIndex
+func NewIndexExportAll(all OrcbrewExportAll) *Index {
+	var sources []OrcbrewSource
+	for _, pack := range sortedPacks(all) {
+		sources = append(sources, all[pack])
+	}
+	return NewIndex(sources...)
+}
+
+// Add adds the entities in a source to the index
+func (idx *Index) Add(source OrcbrewSource) {
+	for _, kind := range Kinds {
+		entities := source.kindMap(kind)
+		for _, key := range sortedKeys(entities) {
+			entity := entities.MapIndex(reflect.ValueOf(key)).Interface().(Entity)
+			id := kind + "/" + key
+
+			idx.entities = append(idx.entities, entity)
+			if _, ok := idx.byKey[id]; !ok {
+				idx.byKey[id] = entity
+			}
+
+			name := strings.ToLower(entity.EntityName())
+			idx.byName[name] = append(idx.byName[name], entity)
+
+			pack := entity.EntityOptionPack()
+			idx.byPack[pack] = append(idx.byPack[pack], entity)
+
+			for _, ref := range References(source.entityPointer(kind, key)) {
+				referrer := Referrer{Entity: entity, Field: ref.Field}
+				idx.referencedBy[ref.String()] = append(idx.referencedBy[ref.String()], referrer)
+			}
+		}
+	}
+}
+
+// Entities returns every entity in the index
+func (idx *Index) Entities() []Entity {
+	return idx.entities
+}
+
+// Lookup returns the entity of the given kind with the given key
+func (idx *Index) Lookup(kind string, key string) (Entity, bool) {
+	entity, ok := idx.byKey[kind+"/"+key]
+	return entity, ok
+}
+
+// ByName returns the entities with the given name, ignoring case
+func (idx *Index) ByName(name string) []Entity {
+	return idx.byName[strings.ToLower(name)]
+}
+
+// ReferencesTo returns the entities that refer to the entity of the given
+// kind and key, which needn't be defined in the index (e.g. a built-in class)
+func (idx *Index) ReferencesTo(kind string, key string) []Referrer {
+	return idx.referencedBy[kind+"/"+key]
+}
+
+// Packs returns the sorted names of the option packs of the entities
+func (idx *Index) Packs() []string {
+	var packs []string
+	for pack := range idx.byPack {
+		packs = append(packs, pack)
+	}
+	sort.Strings(packs)
+	return packs
+}
+
+// Pack returns the entities belonging to the given option pack
+func (idx *Index) Pack(name string) []Entity {
+	return idx.byPack[name]
+}
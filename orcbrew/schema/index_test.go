@@ -0,0 +1,63 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestEntity(t *testing.T) {
+	var entity Entity = SpellConfig{Key: "myspell", Name: "MySpell", OptionPack: "Test"}
+
+	actual := []string{entity.EntityKind(), entity.EntityKey(), entity.EntityName(), entity.EntityOptionPack()}
+	if diff := deep.Equal(actual, []string{"spells", "myspell", "MySpell", "Test"}); diff != nil {
+		t.Error(diff)
+	}
+
+	// Every kind has an entity type
+	var source OrcbrewSource
+	for _, kind := range Kinds {
+		entityType := source.kindMap(kind).Type().Elem()
+		entity, ok := reflect.Zero(entityType).Interface().(Entity)
+		if !ok || entity.EntityKind() != kind {
+			t.Errorf("%s is not an Entity of kind %s", entityType, kind)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	idx := NewIndexExportAll(renameInput())
+
+	entity, ok := idx.Lookup("classes", "myclass")
+	if !ok || entity.EntityName() != "MyClass" {
+		t.Errorf("Expected to find classes/myclass, got %v", entity)
+	}
+	if _, ok := idx.Lookup("spells", "myclass"); ok {
+		t.Errorf("Expected no spells/myclass")
+	}
+
+	byName := idx.ByName("myrace")
+	if len(byName) != 1 || byName[0].EntityKey() != "myrace" {
+		t.Errorf("Expected to find MyRace by name, got %v", byName)
+	}
+
+	var referrers []string
+	for _, referrer := range idx.ReferencesTo("classes", "myclass") {
+		referrers = append(referrers, referrer.Entity.EntityKey()+" "+referrer.Field)
+	}
+	if diff := deep.Equal(referrers, []string{"othersubclass class", "mysubclass class"}); diff != nil {
+		t.Error(diff)
+	}
+
+	if refs := idx.ReferencesTo("races", "elf"); len(refs) != 1 || refs[0].Entity.EntityKey() != "myfeat" {
+		t.Errorf("Expected myfeat to refer to the built-in elf race, got %v", refs)
+	}
+
+	if diff := deep.Equal(idx.Packs(), []string{"Other", "Test"}); diff != nil {
+		t.Error(diff)
+	}
+	if len(idx.Pack("Test")) != 5 || len(idx.Entities()) != 6 {
+		t.Errorf("Expected 5 entities in Test and 6 in total, got %d and %d", len(idx.Pack("Test")), len(idx.Entities()))
+	}
+}
@@ -0,0 +1,316 @@
+// Package codegen renders the generated parts of the schema package
+// (constants.go, modifiers.go and entities.go) from the declarative spec in
+// spec.json.
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
+
+// Spec is the declarative description of the enum types, level modifiers and
+// entity types that make up the generated part of the schema package.
+type Spec struct {
+	Enums     []Enum     `json:"enums"`
+	Modifiers []Modifier `json:"modifiers"`
+	Entities  []Entity   `json:"entities"`
+}
+
+// Enum describes a string type along with its symbolic constants
+type Enum struct {
+	Type     string      `json:"type"`     // the name of the Go type
+	Doc      string      `json:"doc"`      // doc comment for the type
+	ConstDoc string      `json:"constDoc"` // doc comment for the const block
+	Values   []EnumValue `json:"values"`
+}
+
+// EnumValue is a single symbolic constant of an Enum
+type EnumValue struct {
+	Name  string `json:"name"`  // the name of the Go constant
+	Value string `json:"value"` // the value used in .orcbrew files
+}
+
+// Modifier describes a single level modifier variant
+type Modifier struct {
+	Key       string `json:"key"`   // the value of the "type" field in .orcbrew files
+	TypeName  string `json:"type"`  // the name of the Go type
+	ValueType string `json:"value"` // the Go type of the "value" field
+}
+
+// Entity describes one of the kinds of entity in an OrcbrewSource
+type Entity struct {
+	Kind     string `json:"kind"` // the name of the kind in .orcbrew files, e.g. "spells"
+	TypeName string `json:"type"` // the name of the Go type, e.g. "SpellConfig"
+}
+
+// LoadSpec reads and validates a spec from the given file
+func LoadSpec(filename string) (*Spec, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec Spec
+	err = json.Unmarshal(b, &spec)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %s", filename, err)
+	}
+
+	err = spec.validate()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid spec %s: %s", filename, err)
+	}
+
+	return &spec, nil
+}
+
+func (s *Spec) validate() error {
+	names := make(map[string]bool)
+	declare := func(name string) error {
+		if name == "" {
+			return fmt.Errorf("empty identifier")
+		}
+		if names[name] {
+			return fmt.Errorf("%s is declared more than once", name)
+		}
+		names[name] = true
+		return nil
+	}
+
+	for _, enum := range s.Enums {
+		if err := declare(enum.Type); err != nil {
+			return err
+		}
+		for _, value := range enum.Values {
+			if err := declare(value.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	keys := make(map[string]bool)
+	for _, modifier := range s.Modifiers {
+		if err := declare(modifier.TypeName); err != nil {
+			return err
+		}
+		if modifier.Key == "" || keys[modifier.Key] {
+			return fmt.Errorf("modifier key %q is empty or duplicated", modifier.Key)
+		}
+		keys[modifier.Key] = true
+	}
+
+	kinds := make(map[string]bool)
+	for _, entity := range s.Entities {
+		if entity.TypeName == "" || kinds[entity.TypeName] {
+			return fmt.Errorf("entity type %q is empty or duplicated", entity.TypeName)
+		}
+		if entity.Kind == "" || kinds[entity.Kind] {
+			return fmt.Errorf("entity kind %q is empty or duplicated", entity.Kind)
+		}
+		kinds[entity.TypeName] = true
+		kinds[entity.Kind] = true
+	}
+
+	return nil
+}
+
+// RenderConstants renders the source for constants.go
+func (s *Spec) RenderConstants() ([]byte, error) {
+	return render(constantsTemplate, s)
+}
+
+// RenderModifiers renders the source for modifiers.go
+func (s *Spec) RenderModifiers() ([]byte, error) {
+	return render(modifiersTemplate, s)
+}
+
+// RenderEntities renders the source for entities.go
+func (s *Spec) RenderEntities() ([]byte, error) {
+	return render(entitiesTemplate, s)
+}
+
+func render(tmpl *template.Template, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Generated invalid Go source: %s\n%s", err, buf.String())
+	}
+
+	return src, nil
+}
+
+var funcs = template.FuncMap{
+	"comment": func(text string) string {
+		return "// " + strings.Replace(text, "\n", "\n// ", -1)
+	},
+}
+
+const header = `// Code generated by go generate; DO NOT EDIT.
+// This file was generated from spec.json by internal/gen_schema.
+
+package schema
+`
+
+var constantsTemplate = template.Must(template.New("constants").Funcs(funcs).Parse(header + `
+{{ range .Enums }}
+{{ comment .Doc }}
+type {{ .Type }} string
+
+{{ comment .ConstDoc }}
+const (
+{{- $type := .Type }}
+{{- range .Values }}
+	{{ .Name }} {{ $type }} = "{{ .Value }}"
+{{- end }}
+)
+
+// Values returns every valid value for {{ .Type }}
+func ({{ .Type }}) Values() []string {
+	return []string{
+{{- range .Values }}
+		string({{ .Name }}),
+{{- end }}
+	}
+}
+{{ end }}
+`))
+
+var modifiersTemplate = template.Must(template.New("modifiers").Funcs(funcs).Parse(header + `
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type levelModifierType string
+
+type LevelModifier interface {
+	Type() levelModifierType
+}
+
+type LevelModifierList []LevelModifier
+
+// LevelModifierKeys lists the type key of every LevelModifier variant
+var LevelModifierKeys = []string{
+{{- range .Modifiers }}
+	"{{ .Key }}",
+{{- end }}
+}
+
+// NewLevelModifier returns an empty LevelModifier for the given type key
+func NewLevelModifier(key string) (LevelModifier, error) {
+	switch key {
+{{- range .Modifiers }}
+	case "{{ .Key }}":
+		return &{{ .TypeName }}{}, nil
+{{- end }}
+	}
+
+	return nil, fmt.Errorf("Got unknown type: %s", key)
+}
+
+func (list *LevelModifierList) UnmarshalJSON(b []byte) error {
+	var rawList []*json.RawMessage
+	err := json.Unmarshal(b, &rawList)
+	if err != nil {
+		return err
+	}
+
+	if len(rawList) == 0 {
+		*list = make([]LevelModifier, 0)
+	}
+
+	var m map[string]interface{}
+	for _, rawMessage := range rawList {
+		err = json.Unmarshal(*rawMessage, &m)
+		if err != nil {
+			return err
+		}
+
+		entryType, ok := m["type"].(string)
+		if !ok {
+			return fmt.Errorf("Value type in map was not a string")
+		}
+
+		var entry LevelModifier
+		entry, err = NewLevelModifier(entryType)
+		if err != nil {
+			return err
+		}
+
+		err = json.Unmarshal(*rawMessage, &entry)
+		if err != nil {
+			return err
+		}
+
+		*list = append(*list, entry)
+	}
+
+	return nil
+}
+{{ range .Modifiers }}
+type {{ .TypeName }} struct {
+	Level int
+	Value {{ .ValueType }}
+}
+
+func (m *{{ .TypeName }}) Type() levelModifierType {
+	return "{{ .Key }}"
+}
+
+func (m *{{ .TypeName }}) MarshalJSON() (b []byte, e error) {
+	var valueMap = map[string]interface{}{
+		"type":  m.Type(),
+		"value": m.Value,
+	}
+
+	if m.Level != 0 {
+		valueMap["level"] = m.Level
+	}
+	return json.Marshal(valueMap)
+}
+{{ end }}
+var (
+{{- range .Modifiers }}
+	_ LevelModifier = &{{ .TypeName }}{}
+{{- end }}
+)
+`))
+
+var entitiesTemplate = template.Must(template.New("entities").Funcs(funcs).Parse(header + `
+{{ range .Entities }}
+// EntityKind returns "{{ .Kind }}"
+func ({{ .TypeName }}) EntityKind() string {
+	return "{{ .Kind }}"
+}
+
+// EntityKey returns the key of the entity
+func (e {{ .TypeName }}) EntityKey() string {
+	return e.Key
+}
+
+// EntityName returns the name of the entity
+func (e {{ .TypeName }}) EntityName() string {
+	return e.Name
+}
+
+// EntityOptionPack returns the option pack the entity belongs to
+func (e {{ .TypeName }}) EntityOptionPack() string {
+	return e.OptionPack
+}
+{{ end }}
+var (
+{{- range .Entities }}
+	_ Entity = {{ .TypeName }}{}
+{{- end }}
+)
+`))
@@ -0,0 +1,59 @@
+//go:build ignore
+// +build ignore
+
+// This program generates constants.go, modifiers.go and entities.go from
+// spec.json. It can be invoked by running go generate
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema/internal/codegen"
+)
+
+var specFilename = flag.String("spec", "", "The spec file describing enums, modifiers and entities")
+var constantsFilename = flag.String("constants", "", "The file to use for constants output")
+var modifiersFilename = flag.String("modifiers", "", "The file to use for modifiers output")
+var entitiesFilename = flag.String("entities", "", "The file to use for entities output")
+
+func main() {
+	flag.Parse()
+
+	if *specFilename == "" || *constantsFilename == "" || *modifiersFilename == "" || *entitiesFilename == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS]\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	spec, err := codegen.LoadSpec(*specFilename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading spec: %s\n", err)
+		os.Exit(2)
+	}
+
+	outputs := []struct {
+		filename string
+		render   func() ([]byte, error)
+	}{
+		{*constantsFilename, spec.RenderConstants},
+		{*modifiersFilename, spec.RenderModifiers},
+		{*entitiesFilename, spec.RenderEntities},
+	}
+
+	for _, output := range outputs {
+		src, err := output.render()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating %s: %s\n", output.filename, err)
+			os.Exit(2)
+		}
+
+		err = ioutil.WriteFile(output.filename, src, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", output.filename, err)
+			os.Exit(2)
+		}
+	}
+}
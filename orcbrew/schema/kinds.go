@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds lists the kinds of entity that can be defined in an OrcbrewSource,
+// using the names they have in .orcbrew files (e.g. "spells")
+var Kinds = sourceKinds()
+
+var sourceType = reflect.TypeOf(OrcbrewSource{})
+
+func sourceKinds() []string {
+	var kinds []string
+	for i := 0; i < sourceType.NumField(); i++ {
+		kinds = append(kinds, kindName(sourceType.Field(i)))
+	}
+	return kinds
+}
+
+func kindName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+// IsKind returns whether the given name is one of Kinds
+func IsKind(name string) bool {
+	for _, kind := range Kinds {
+		if kind == name {
+			return true
+		}
+	}
+	return false
+}
+
+// KindType returns the type of the entities of a kind, e.g. SpellConfig for
+// "spells", or nil if kind is unknown
+func KindType(kind string) reflect.Type {
+	for i := 0; i < sourceType.NumField(); i++ {
+		if kindName(sourceType.Field(i)) == kind {
+			return sourceType.Field(i).Type.Elem()
+		}
+	}
+	return nil
+}
+
+// kindMap returns the (settable) map holding the entities of the given kind,
+// or an invalid value if kind is unknown
+func (s *OrcbrewSource) kindMap(kind string) reflect.Value {
+	source := reflect.ValueOf(s).Elem()
+	for i := 0; i < sourceType.NumField(); i++ {
+		if kindName(sourceType.Field(i)) == kind {
+			return source.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// sortedKeys returns the keys of an entity map in sorted order
+func sortedKeys(entities reflect.Value) []string {
+	var keys []string
+	for _, key := range entities.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// setEntity stores an entity in the map for its kind, creating the map if it
+// doesn't exist yet
+func setEntity(entities reflect.Value, key string, entity reflect.Value) {
+	if entities.IsNil() {
+		entities.Set(reflect.MakeMap(entities.Type()))
+	}
+	entities.SetMapIndex(reflect.ValueOf(key), entity)
+}
+
+// OptionPacks returns the sorted, distinct option pack names used by the
+// entities in the source
+func (s OrcbrewSource) OptionPacks() []string {
+	seen := make(map[string]bool)
+	var packs []string
+
+	for _, kind := range Kinds {
+		entities := s.kindMap(kind)
+		for _, key := range entities.MapKeys() {
+			pack := entities.MapIndex(key).FieldByName("OptionPack").String()
+			if pack != "" && !seen[pack] {
+				seen[pack] = true
+				packs = append(packs, pack)
+			}
+		}
+	}
+
+	sort.Strings(packs)
+	return packs
+}
@@ -0,0 +1,176 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ConflictPolicy decides what happens when two sources being merged define
+// the same entity key in the same option pack
+type ConflictPolicy int
+
+// Symbolic constants for conflict policies
+const (
+	ConflictError     ConflictPolicy = iota // report the conflicts and fail the merge
+	ConflictFirstWins                       // keep the entity that was seen first
+	ConflictLastWins                        // replace it with the entity seen last
+	ConflictRename                          // keep both, renaming the later entity
+)
+
+// Conflict describes an entity key that was defined by more than one of the
+// inputs to a merge, and how it was resolved
+type Conflict struct {
+	Pack  string // the option pack containing the entity
+	Kind  string // the kind of entity, e.g. "spells"
+	Key   string // the key defined more than once
+	Input int    // the index of the input that redefined the key
+
+	Resolution string // what was done about it: "unresolved", "kept first", "replaced" or "renamed"
+	RenamedTo  string // the new key, when Resolution is "renamed"
+}
+
+func (c Conflict) String() string {
+	if c.RenamedTo != "" {
+		return fmt.Sprintf("%s/%s/%s: %s to %s", c.Pack, c.Kind, c.Key, c.Resolution, c.RenamedTo)
+	}
+	return fmt.Sprintf("%s/%s/%s: %s", c.Pack, c.Kind, c.Key, c.Resolution)
+}
+
+// ConflictErr is returned by Merge when the ConflictError policy is used and
+// at least one conflict was found
+type ConflictErr struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictErr) Error() string {
+	return fmt.Sprintf("%d conflicting entities", len(e.Conflicts))
+}
+
+// MergeOptions configures how sources are merged
+type MergeOptions struct {
+	Policy ConflictPolicy
+
+	// RenameSuffix separates a renamed key from its counter, so that with
+	// the default of "-" a conflicting "myspell" becomes "myspell-2"
+	RenameSuffix string
+}
+
+// Merge combines several Export All values into one. Sources for the same
+// option pack are merged entity by entity, and entities that are defined
+// identically by several inputs are not considered conflicts.
+//
+// Renaming only changes the entity's map key and Key field, references to
+// the old key from other entities are left as they are.
+func Merge(options MergeOptions, inputs ...OrcbrewExportAll) (OrcbrewExportAll, []Conflict, error) {
+	result := make(OrcbrewExportAll)
+	var conflicts []Conflict
+
+	for idx, input := range inputs {
+		for pack, source := range input {
+			merged := result[pack]
+			conflicts = append(conflicts, mergeInto(&merged, source, pack, idx, options)...)
+			result[pack] = merged
+		}
+	}
+
+	sortConflicts(conflicts)
+	if options.Policy == ConflictError && len(conflicts) > 0 {
+		return nil, conflicts, &ConflictErr{Conflicts: conflicts}
+	}
+
+	return result, conflicts, nil
+}
+
+// MergeSources combines several sources into one, see Merge
+func MergeSources(options MergeOptions, inputs ...OrcbrewSource) (OrcbrewSource, []Conflict, error) {
+	var result OrcbrewSource
+	var conflicts []Conflict
+
+	for idx, input := range inputs {
+		conflicts = append(conflicts, mergeInto(&result, input, "", idx, options)...)
+	}
+
+	sortConflicts(conflicts)
+	if options.Policy == ConflictError && len(conflicts) > 0 {
+		return OrcbrewSource{}, conflicts, &ConflictErr{Conflicts: conflicts}
+	}
+
+	return result, conflicts, nil
+}
+
+func mergeInto(dst *OrcbrewSource, src OrcbrewSource, pack string, input int, options MergeOptions) []Conflict {
+	var conflicts []Conflict
+
+	for _, kind := range Kinds {
+		dstEntities := dst.kindMap(kind)
+		srcEntities := src.kindMap(kind)
+
+		for _, key := range sortedKeys(srcEntities) {
+			entity := srcEntities.MapIndex(reflect.ValueOf(key))
+
+			existing := dstEntities.MapIndex(reflect.ValueOf(key))
+			if !existing.IsValid() {
+				setEntity(dstEntities, key, entity)
+				continue
+			}
+
+			if reflect.DeepEqual(existing.Interface(), entity.Interface()) {
+				continue
+			}
+
+			conflict := Conflict{Pack: pack, Kind: kind, Key: key, Input: input}
+			switch options.Policy {
+			case ConflictError:
+				conflict.Resolution = "unresolved"
+			case ConflictFirstWins:
+				conflict.Resolution = "kept first"
+			case ConflictLastWins:
+				conflict.Resolution = "replaced"
+				setEntity(dstEntities, key, entity)
+			case ConflictRename:
+				conflict.Resolution = "renamed"
+				conflict.RenamedTo = freeKey(dstEntities, key, options.RenameSuffix)
+
+				renamed := reflect.New(entity.Type()).Elem()
+				renamed.Set(entity)
+				renamed.FieldByName("Key").SetString(conflict.RenamedTo)
+				setEntity(dstEntities, conflict.RenamedTo, renamed)
+			}
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	return conflicts
+}
+
+// freeKey returns the first key of the form <key><suffix><n> not already in
+// use, starting with n = 2
+func freeKey(entities reflect.Value, key string, suffix string) string {
+	if suffix == "" {
+		suffix = "-"
+	}
+
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s%s%d", key, suffix, n)
+		if !entities.MapIndex(reflect.ValueOf(candidate)).IsValid() {
+			return candidate
+		}
+	}
+}
+
+func sortConflicts(conflicts []Conflict) {
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		a, b := conflicts[i], conflicts[j]
+		if a.Pack != b.Pack {
+			return a.Pack < b.Pack
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Input < b.Input
+	})
+}
@@ -0,0 +1,210 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Merge3Conflict describes an entity that was changed in incompatible ways
+// by both sides of a three-way merge
+type Merge3Conflict struct {
+	Pack string // the option pack containing the entity
+	Kind string // the kind of entity, e.g. "spells"
+	Key  string // the key of the entity
+
+	// The fields changed differently by both sides, e.g. "level". Empty when
+	// one side deleted the entity and the other changed it.
+	Fields []string
+
+	// The entity with every non-conflicting change merged, taking our or
+	// their side of the conflicting fields. Nil when that side deleted it.
+	Ours   interface{}
+	Theirs interface{}
+}
+
+func (c Merge3Conflict) String() string {
+	if len(c.Fields) == 0 {
+		return fmt.Sprintf("%s/%s/%s: deleted on one side and changed on the other", c.Pack, c.Kind, c.Key)
+	}
+	return fmt.Sprintf("%s/%s/%s: both sides changed %v", c.Pack, c.Kind, c.Key, c.Fields)
+}
+
+// Merge3 performs a three-way merge of two versions of a set of option packs
+// that were both derived from base. Entities are merged field by field, so
+// changes to different fields of the same entity don't conflict. Lists are
+// merged as a whole.
+//
+// Conflicting entities are returned with our side of the conflicting fields
+// in the result, as well as in the list of conflicts.
+func Merge3(base OrcbrewExportAll, ours OrcbrewExportAll, theirs OrcbrewExportAll) (OrcbrewExportAll, []Merge3Conflict, error) {
+	result := make(OrcbrewExportAll)
+	var conflicts []Merge3Conflict
+
+	packs := make(map[string]bool)
+	for _, all := range []OrcbrewExportAll{base, ours, theirs} {
+		for pack := range all {
+			packs[pack] = true
+		}
+	}
+
+	for _, pack := range sortedSet(packs) {
+		baseSource, oursSource, theirsSource := base[pack], ours[pack], theirs[pack]
+		var merged OrcbrewSource
+
+		for _, kind := range Kinds {
+			entities := []reflect.Value{baseSource.kindMap(kind), oursSource.kindMap(kind), theirsSource.kindMap(kind)}
+
+			keys := make(map[string]bool)
+			for _, m := range entities {
+				for _, key := range sortedKeys(m) {
+					keys[key] = true
+				}
+			}
+
+			for _, key := range sortedSet(keys) {
+				var values [3]interface{}
+				for idx, m := range entities {
+					if entity := m.MapIndex(reflect.ValueOf(key)); entity.IsValid() {
+						values[idx] = entity.Interface()
+					}
+				}
+
+				entityType := merged.kindMap(kind).Type().Elem()
+				ourSide, theirSide, fields, err := merge3Entity(entityType, values[0], values[1], values[2])
+				if err != nil {
+					return nil, nil, fmt.Errorf("%s/%s/%s: %s", pack, kind, key, err)
+				}
+
+				if ourSide != nil {
+					setEntity(merged.kindMap(kind), key, reflect.ValueOf(ourSide))
+				}
+
+				conflicted := len(fields) > 0 || !reflect.DeepEqual(ourSide, theirSide)
+				if conflicted {
+					conflicts = append(conflicts, Merge3Conflict{
+						Pack: pack, Kind: kind, Key: key,
+						Fields: fields,
+						Ours:   ourSide, Theirs: theirSide,
+					})
+				}
+			}
+		}
+
+		if !reflect.DeepEqual(merged, OrcbrewSource{}) {
+			result[pack] = merged
+		}
+	}
+
+	return result, conflicts, nil
+}
+
+// merge3Entity merges one entity, returning the merged entity with our and
+// their side of any conflicting fields
+func merge3Entity(entityType reflect.Type, base, ours, theirs interface{}) (interface{}, interface{}, []string, error) {
+	switch {
+	case reflect.DeepEqual(ours, theirs):
+		return ours, theirs, nil, nil
+	case reflect.DeepEqual(base, ours):
+		return theirs, theirs, nil, nil
+	case reflect.DeepEqual(base, theirs):
+		return ours, ours, nil, nil
+	case ours == nil || theirs == nil:
+		// Deleted on one side, changed on the other
+		return ours, theirs, nil, nil
+	}
+
+	var values [3]interface{}
+	for idx, entity := range []interface{}{base, ours, theirs} {
+		if entity == nil {
+			values[idx] = map[string]interface{}{}
+			continue
+		}
+		value, err := toJSONValue(entity)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		values[idx] = value
+	}
+
+	var fields []string
+	ourValue := merge3Values("", values[0], values[1], values[2], true, &fields)
+	theirValue := merge3Values("", values[0], values[1], values[2], false, nil)
+
+	ourSide, err := fromJSONValue(entityType, ourValue)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	theirSide, err := fromJSONValue(entityType, theirValue)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return ourSide, theirSide, fields, nil
+}
+
+// merge3Values merges decoded JSON values, recording the paths of conflicting
+// fields and resolving them to our side when preferOurs is set
+func merge3Values(path string, base, ours, theirs interface{}, preferOurs bool, conflicts *[]string) interface{} {
+	switch {
+	case reflect.DeepEqual(ours, theirs):
+		return ours
+	case reflect.DeepEqual(base, ours):
+		return theirs
+	case reflect.DeepEqual(base, theirs):
+		return ours
+	}
+
+	oursMap, oursOK := ours.(map[string]interface{})
+	theirsMap, theirsOK := theirs.(map[string]interface{})
+	baseMap, baseOK := base.(map[string]interface{})
+	if oursOK && theirsOK && (baseOK || base == nil) {
+		keys := make(map[string]bool)
+		for _, m := range []map[string]interface{}{baseMap, oursMap, theirsMap} {
+			for key := range m {
+				keys[key] = true
+			}
+		}
+
+		merged := make(map[string]interface{})
+		for _, key := range sortedSet(keys) {
+			value := merge3Values(joinPath(path, key), baseMap[key], oursMap[key], theirsMap[key], preferOurs, conflicts)
+			if value != nil {
+				merged[key] = value
+			}
+		}
+		return merged
+	}
+
+	if conflicts != nil {
+		*conflicts = append(*conflicts, path)
+	}
+	if preferOurs {
+		return ours
+	}
+	return theirs
+}
+
+func fromJSONValue(entityType reflect.Type, value interface{}) (interface{}, error) {
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := reflect.New(entityType)
+	err = json.Unmarshal(jsonBytes, entity.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return entity.Elem().Interface(), nil
+}
+
+func sortedSet(set map[string]bool) []string {
+	var values []string
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func merge3Base() OrcbrewExportAll {
+	return OrcbrewExportAll{
+		"Test": OrcbrewSource{
+			Spells: map[string]SpellConfig{
+				"myspell":    SpellConfig{Key: "myspell", OptionPack: "Test", Name: "MySpell", Level: 1, School: "evocation"},
+				"otherspell": SpellConfig{Key: "otherspell", OptionPack: "Test", Name: "OtherSpell", Level: 2},
+			},
+		},
+	}
+}
+
+func TestMerge3Clean(t *testing.T) {
+	base, ours, theirs := merge3Base(), merge3Base(), merge3Base()
+
+	// Different fields of the same spell
+	spell := ours["Test"].Spells["myspell"]
+	spell.Level = 2
+	ours["Test"].Spells["myspell"] = spell
+
+	spell = theirs["Test"].Spells["myspell"]
+	spell.School = "necromancy"
+	theirs["Test"].Spells["myspell"] = spell
+
+	// A deletion and an addition
+	delete(theirs["Test"].Spells, "otherspell")
+	ours["Test"].Spells["newspell"] = SpellConfig{Key: "newspell", OptionPack: "Test", Name: "NewSpell"}
+
+	merged, conflicts, err := Merge3(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+
+	expected := OrcbrewExportAll{
+		"Test": OrcbrewSource{
+			Spells: map[string]SpellConfig{
+				"myspell":  SpellConfig{Key: "myspell", OptionPack: "Test", Name: "MySpell", Level: 2, School: "necromancy"},
+				"newspell": SpellConfig{Key: "newspell", OptionPack: "Test", Name: "NewSpell"},
+			},
+		},
+	}
+	if diff := deep.Equal(merged, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestMerge3Conflicts(t *testing.T) {
+	base, ours, theirs := merge3Base(), merge3Base(), merge3Base()
+
+	spell := ours["Test"].Spells["myspell"]
+	spell.Level = 2
+	spell.Name = "Renamed"
+	ours["Test"].Spells["myspell"] = spell
+
+	spell = theirs["Test"].Spells["myspell"]
+	spell.Level = 3
+	theirs["Test"].Spells["myspell"] = spell
+
+	delete(ours["Test"].Spells, "otherspell")
+	spell = theirs["Test"].Spells["otherspell"]
+	spell.Level = 9
+	theirs["Test"].Spells["otherspell"] = spell
+
+	merged, conflicts, err := Merge3(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Merge3Conflict{
+		{
+			Pack: "Test", Kind: "spells", Key: "myspell",
+			Fields: []string{"level"},
+			Ours:   SpellConfig{Key: "myspell", OptionPack: "Test", Name: "Renamed", Level: 2, School: "evocation"},
+			Theirs: SpellConfig{Key: "myspell", OptionPack: "Test", Name: "Renamed", Level: 3, School: "evocation"},
+		},
+		{
+			Pack: "Test", Kind: "spells", Key: "otherspell",
+			Theirs: SpellConfig{Key: "otherspell", OptionPack: "Test", Name: "OtherSpell", Level: 9},
+		},
+	}
+	if diff := deep.Equal(conflicts, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	if diff := deep.Equal(merged["Test"].Spells["myspell"], conflicts[0].Ours); diff != nil {
+		t.Errorf("Expected our side in the result: %s", diff)
+	}
+	if _, ok := merged["Test"].Spells["otherspell"]; ok {
+		t.Errorf("Expected our deletion in the result")
+	}
+}
@@ -0,0 +1,114 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func mergeInputs() []OrcbrewExportAll {
+	first := OrcbrewExportAll{
+		"Test": OrcbrewSource{
+			Spells: map[string]SpellConfig{
+				"myspell":   SpellConfig{Key: "myspell", OptionPack: "Test", Name: "MySpell", Level: 0},
+				"samespell": SpellConfig{Key: "samespell", OptionPack: "Test", Name: "Same"},
+			},
+		},
+	}
+
+	second := OrcbrewExportAll{
+		"Test": OrcbrewSource{
+			Spells: map[string]SpellConfig{
+				"myspell":   SpellConfig{Key: "myspell", OptionPack: "Test", Name: "MySpell", Level: 1},
+				"samespell": SpellConfig{Key: "samespell", OptionPack: "Test", Name: "Same"},
+			},
+			Feats: map[string]FeatConfig{
+				"myfeat": FeatConfig{Key: "myfeat", OptionPack: "Test", Name: "MyFeat"},
+			},
+		},
+		"Other": OrcbrewSource{
+			Spells: map[string]SpellConfig{
+				"myspell": SpellConfig{Key: "myspell", OptionPack: "Other", Name: "MySpell", Level: 5},
+			},
+		},
+	}
+
+	return []OrcbrewExportAll{first, second}
+}
+
+func TestMergePolicies(t *testing.T) {
+	tests := []struct {
+		policy     ConflictPolicy
+		level      int
+		resolution string
+	}{
+		{ConflictFirstWins, 0, "kept first"},
+		{ConflictLastWins, 1, "replaced"},
+		{ConflictRename, 0, "renamed"},
+	}
+
+	for _, test := range tests {
+		result, conflicts, err := Merge(MergeOptions{Policy: test.policy}, mergeInputs()...)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", test.resolution, err)
+		}
+
+		expected := []Conflict{
+			Conflict{Pack: "Test", Kind: "spells", Key: "myspell", Input: 1, Resolution: test.resolution},
+		}
+		if test.policy == ConflictRename {
+			expected[0].RenamedTo = "myspell-2"
+		}
+
+		if diff := deep.Equal(conflicts, expected); diff != nil {
+			t.Error(diff)
+		}
+
+		if level := result["Test"].Spells["myspell"].Level; level != test.level {
+			t.Errorf("Expected level %d with %s, got %d", test.level, test.resolution, level)
+		}
+
+		if _, ok := result["Test"].Feats["myfeat"]; !ok {
+			t.Errorf("Expected myfeat to be merged with %s", test.resolution)
+		}
+
+		if level := result["Other"].Spells["myspell"].Level; level != 5 {
+			t.Errorf("Expected packs to be merged separately with %s", test.resolution)
+		}
+	}
+}
+
+func TestMergeRename(t *testing.T) {
+	result, _, err := Merge(MergeOptions{Policy: ConflictRename, RenameSuffix: "_"}, mergeInputs()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renamed, ok := result["Test"].Spells["myspell_2"]
+	if !ok {
+		t.Fatalf("Expected myspell_2, got %v", result["Test"].Spells)
+	}
+
+	if renamed.Key != "myspell_2" || renamed.Level != 1 {
+		t.Errorf("Expected renamed entity to have new key and level 1, got %+v", renamed)
+	}
+}
+
+func TestMergeError(t *testing.T) {
+	_, conflicts, err := Merge(MergeOptions{Policy: ConflictError}, mergeInputs()...)
+	if err == nil {
+		t.Fatal("Expected merge to fail")
+	}
+
+	if _, ok := err.(*ConflictErr); !ok || len(conflicts) != 1 {
+		t.Errorf("Expected a ConflictErr with one conflict, got %v and %v", err, conflicts)
+	}
+}
+
+func TestOptionPacks(t *testing.T) {
+	source, _ := LoadSourceFile(t, "example.json")
+
+	if diff := deep.Equal(source.OptionPacks(), []string{"Test"}); diff != nil {
+		t.Error(diff)
+	}
+}
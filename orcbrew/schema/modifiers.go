@@ -1,6 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// This file was generated by robots at
-// 2018-08-29 22:59:55.80974 &#43;0200 CEST m=&#43;0.001870473
+// This file was generated from spec.json by internal/gen_schema.
 
 package schema
 
@@ -11,12 +10,60 @@ import (
 
 type levelModifierType string
 
-type LevelModifier interface{
+type LevelModifier interface {
 	Type() levelModifierType
 }
 
 type LevelModifierList []LevelModifier
 
+// LevelModifierKeys lists the type key of every LevelModifier variant
+var LevelModifierKeys = []string{
+	"armor-prof",
+	"damage-immunity",
+	"damage-resistance",
+	"flying-speed",
+	"flying-speed-equals-walking-speed",
+	"num-attacks",
+	"saving-throw-advantage",
+	"skill-prof",
+	"spell",
+	"swimming-speed",
+	"tool-prof",
+	"weapon-prof",
+}
+
+// NewLevelModifier returns an empty LevelModifier for the given type key
+func NewLevelModifier(key string) (LevelModifier, error) {
+	switch key {
+	case "armor-prof":
+		return &ModifierArmorProficiency{}, nil
+	case "damage-immunity":
+		return &ModifierDamageImmunity{}, nil
+	case "damage-resistance":
+		return &ModifierDamageResistance{}, nil
+	case "flying-speed":
+		return &ModifierFlyingSpeed{}, nil
+	case "flying-speed-equals-walking-speed":
+		return &ModifierFlyingSpeedEqualsWalkingSpeed{}, nil
+	case "num-attacks":
+		return &ModifierExtraAttacks{}, nil
+	case "saving-throw-advantage":
+		return &ModifierSavingThrowAdvantage{}, nil
+	case "skill-prof":
+		return &ModifierSkillProficiency{}, nil
+	case "spell":
+		return &ModifierSpell{}, nil
+	case "swimming-speed":
+		return &ModifierSwimmingSpeed{}, nil
+	case "tool-prof":
+		return &ModifierToolProficiency{}, nil
+	case "weapon-prof":
+		return &ModifierWeaponProficiency{}, nil
+	}
+
+	return nil, fmt.Errorf("Got unknown type: %s", key)
+}
+
 func (list *LevelModifierList) UnmarshalJSON(b []byte) error {
 	var rawList []*json.RawMessage
 	err := json.Unmarshal(b, &rawList)
@@ -41,60 +88,12 @@ func (list *LevelModifierList) UnmarshalJSON(b []byte) error {
 		}
 
 		var entry LevelModifier
-		switch entryType {
-
-		case "armor-prof":
-			entry = &ModifierArmorProficiency{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "damage-immunity":
-			entry = &ModifierDamageImmunity{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "damage-resistance":
-			entry = &ModifierDamageResistance{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "flying-speed":
-			entry = &ModifierFlyingSpeed{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "flying-speed-equals-walking-speed":
-			entry = &ModifierFlyingSpeedEqualsWalkingSpeed{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "num-attacks":
-			entry = &ModifierExtraAttacks{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "saving-throw-advantage":
-			entry = &ModifierSavingThrowAdvantage{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "skill-prof":
-			entry = &ModifierSkillProficiency{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "spell":
-			entry = &ModifierSpell{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "swimming-speed":
-			entry = &ModifierSwimmingSpeed{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "tool-prof":
-			entry = &ModifierToolProficiency{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		case "weapon-prof":
-			entry = &ModifierWeaponProficiency{}
-			err = json.Unmarshal(*rawMessage, &entry)
-
-		default:
-			return fmt.Errorf("Got unknown type: %s", entryType)
+		entry, err = NewLevelModifier(entryType)
+		if err != nil {
+			return err
 		}
 
+		err = json.Unmarshal(*rawMessage, &entry)
 		if err != nil {
 			return err
 		}
@@ -105,7 +104,6 @@ func (list *LevelModifierList) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-
 type ModifierArmorProficiency struct {
 	Level int
 	Value Armor
@@ -117,7 +115,7 @@ func (m *ModifierArmorProficiency) Type() levelModifierType {
 
 func (m *ModifierArmorProficiency) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -138,7 +136,7 @@ func (m *ModifierDamageImmunity) Type() levelModifierType {
 
 func (m *ModifierDamageImmunity) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -159,7 +157,7 @@ func (m *ModifierDamageResistance) Type() levelModifierType {
 
 func (m *ModifierDamageResistance) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -180,7 +178,7 @@ func (m *ModifierFlyingSpeed) Type() levelModifierType {
 
 func (m *ModifierFlyingSpeed) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -201,7 +199,7 @@ func (m *ModifierFlyingSpeedEqualsWalkingSpeed) Type() levelModifierType {
 
 func (m *ModifierFlyingSpeedEqualsWalkingSpeed) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -222,7 +220,7 @@ func (m *ModifierExtraAttacks) Type() levelModifierType {
 
 func (m *ModifierExtraAttacks) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -243,7 +241,7 @@ func (m *ModifierSavingThrowAdvantage) Type() levelModifierType {
 
 func (m *ModifierSavingThrowAdvantage) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -264,7 +262,7 @@ func (m *ModifierSkillProficiency) Type() levelModifierType {
 
 func (m *ModifierSkillProficiency) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -285,7 +283,7 @@ func (m *ModifierSpell) Type() levelModifierType {
 
 func (m *ModifierSpell) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -306,7 +304,7 @@ func (m *ModifierSwimmingSpeed) Type() levelModifierType {
 
 func (m *ModifierSwimmingSpeed) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -327,7 +325,7 @@ func (m *ModifierToolProficiency) Type() levelModifierType {
 
 func (m *ModifierToolProficiency) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -348,7 +346,7 @@ func (m *ModifierWeaponProficiency) Type() levelModifierType {
 
 func (m *ModifierWeaponProficiency) MarshalJSON() (b []byte, e error) {
 	var valueMap = map[string]interface{}{
-		"type": m.Type(),
+		"type":  m.Type(),
 		"value": m.Value,
 	}
 
@@ -358,30 +356,17 @@ func (m *ModifierWeaponProficiency) MarshalJSON() (b []byte, e error) {
 	return json.Marshal(valueMap)
 }
 
-
-
-
-var _ LevelModifier = &ModifierArmorProficiency{}
-
-var _ LevelModifier = &ModifierDamageImmunity{}
-
-var _ LevelModifier = &ModifierDamageResistance{}
-
-var _ LevelModifier = &ModifierFlyingSpeed{}
-
-var _ LevelModifier = &ModifierFlyingSpeedEqualsWalkingSpeed{}
-
-var _ LevelModifier = &ModifierExtraAttacks{}
-
-var _ LevelModifier = &ModifierSavingThrowAdvantage{}
-
-var _ LevelModifier = &ModifierSkillProficiency{}
-
-var _ LevelModifier = &ModifierSpell{}
-
-var _ LevelModifier = &ModifierSwimmingSpeed{}
-
-var _ LevelModifier = &ModifierToolProficiency{}
-
-var _ LevelModifier = &ModifierWeaponProficiency{}
-
+var (
+	_ LevelModifier = &ModifierArmorProficiency{}
+	_ LevelModifier = &ModifierDamageImmunity{}
+	_ LevelModifier = &ModifierDamageResistance{}
+	_ LevelModifier = &ModifierFlyingSpeed{}
+	_ LevelModifier = &ModifierFlyingSpeedEqualsWalkingSpeed{}
+	_ LevelModifier = &ModifierExtraAttacks{}
+	_ LevelModifier = &ModifierSavingThrowAdvantage{}
+	_ LevelModifier = &ModifierSkillProficiency{}
+	_ LevelModifier = &ModifierSpell{}
+	_ LevelModifier = &ModifierSwimmingSpeed{}
+	_ LevelModifier = &ModifierToolProficiency{}
+	_ LevelModifier = &ModifierWeaponProficiency{}
+)
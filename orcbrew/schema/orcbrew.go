@@ -1,6 +1,6 @@
 package schema
 
-//go:generate go run internal/gen_modifiers/main.go -output modifiers.go
+//go:generate go run internal/gen_schema/main.go -spec spec.json -constants constants.go -modifiers modifiers.go -entities entities.go
 
 // OrcbrewExportAll is a map from source name to OrcbrewSource, used by the
 // "Export All" functionality in Orcbrew
@@ -66,7 +66,7 @@ type SubclassConfig struct {
 	ClericSpells map[string]map[string]string `json:"cleric-spells,omitempty"`
 
 	// The spellcasting configuration for this class (if any)
-	Spellcasting *SpellcastingConfig `json:",omitempty"`
+	Spellcasting *SpellcastingConfig `json:"spellcasting,omitempty"`
 
 	LevelModifiers  LevelModifierList `json:"level-modifiers"`            // modifiers that apply to the class (by level)
 	LevelSelections []LevelSelection  `json:"level-selections,omitempty"` // options/selections available upon taking the class (by level)
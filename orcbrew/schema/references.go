@@ -0,0 +1,159 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Reference is a reference from an entity to another entity, which may be
+// defined in the same source or be built-in content in OrcPub
+type Reference struct {
+	Kind  string // the kind of the referenced entity, e.g. "classes"
+	Key   string // the key of the referenced entity
+	Field string // where the reference is made, e.g. "level-selections/0/type"
+}
+
+func (r Reference) String() string {
+	return fmt.Sprintf("%s/%s", r.Kind, r.Key)
+}
+
+// References returns the references made by an entity, which must be a
+// pointer to one of the *Config types (e.g. *SubclassConfig)
+func References(entity interface{}) []Reference {
+	var refs []Reference
+	walkReferences(entity, func(ref Reference, rename func(string)) {
+		refs = append(refs, ref)
+	})
+	return refs
+}
+
+// walkReferences calls fn for every reference made by an entity, along with
+// a function that changes the referenced key in place
+func walkReferences(entity interface{}, fn func(ref Reference, rename func(string))) {
+	ref := func(kind string, field string, key *string) {
+		if *key != "" {
+			fn(Reference{Kind: kind, Key: *key, Field: field}, func(newKey string) { *key = newKey })
+		}
+	}
+
+	selections := func(list []LevelSelection) {
+		for idx := range list {
+			ref("selections", fmt.Sprintf("level-selections/%d/type", idx), &list[idx].Type)
+		}
+	}
+
+	modifiers := func(list LevelModifierList) {
+		for idx, modifier := range list {
+			if spell, ok := modifier.(*ModifierSpell); ok {
+				ref("spells", fmt.Sprintf("level-modifiers/%d/value/key", idx), &spell.Value.Key)
+			}
+		}
+	}
+
+	spellcasting := func(config *SpellcastingConfig) {
+		if config == nil {
+			return
+		}
+		ref("classes", "spellcasting/spell-list-kw", &config.SpellListKw)
+		for _, level := range sortedLevels(config.SpellList) {
+			spells := config.SpellList[level]
+			for idx := range spells {
+				ref("spells", fmt.Sprintf("spellcasting/spell-list/%d/%d", level, idx), &spells[idx])
+			}
+		}
+	}
+
+	raceSpells := func(list []RaceSpellConfig) {
+		for idx := range list {
+			ref("spells", fmt.Sprintf("spells/%d/value/key", idx), &list[idx].Value.Key)
+		}
+	}
+
+	switch e := entity.(type) {
+	case *ClassConfig:
+		modifiers(e.LevelModifiers)
+		selections(e.LevelSelections)
+		spellcasting(e.Spellcasting)
+	case *SubclassConfig:
+		ref("classes", "class", &e.Class)
+		modifiers(e.LevelModifiers)
+		selections(e.LevelSelections)
+		spellcasting(e.Spellcasting)
+		for _, level := range sortedStrings(e.ClericSpells) {
+			spells := e.ClericSpells[level]
+			for _, idx := range sortedStrings(spells) {
+				idx := idx
+				fn(Reference{Kind: "spells", Key: spells[idx], Field: fmt.Sprintf("cleric-spells/%s/%s", level, idx)}, func(newKey string) {
+					spells[idx] = newKey
+				})
+			}
+		}
+	case *FeatConfig:
+		for _, race := range sortedStrings(e.PathPrereqs.Race) {
+			allowed := e.PathPrereqs.Race[race]
+			fn(Reference{Kind: "races", Key: race, Field: "path-prereqs/race/" + race}, func(newKey string) {
+				delete(e.PathPrereqs.Race, race)
+				e.PathPrereqs.Race[newKey] = allowed
+			})
+		}
+	case *SpellConfig:
+		for _, class := range sortedStrings(e.SpellLists) {
+			class := class
+			included := e.SpellLists[class]
+			fn(Reference{Kind: "classes", Key: class, Field: "spell-lists/" + class}, func(newKey string) {
+				delete(e.SpellLists, class)
+				e.SpellLists[newKey] = included
+			})
+		}
+	case *SubraceConfig:
+		ref("races", "race", &e.Race)
+		raceSpells(e.Spells)
+	case *RaceConfig:
+		raceSpells(e.Spells)
+	case *EncounterConfig:
+		for idx := range e.Creatures {
+			if e.Creatures[idx].Type == "monster" || e.Creatures[idx].Type == "" {
+				ref("monsters", fmt.Sprintf("creatures/%d/creature/monster", idx), &e.Creatures[idx].Creature.Monster)
+			}
+		}
+	}
+}
+
+func sortedLevels(m map[int][]string) []int {
+	var levels []int
+	for level := range m {
+		levels = append(levels, level)
+	}
+	sort.Ints(levels)
+	return levels
+}
+
+// sortedStrings returns the keys of a map with string keys, in sorted order
+func sortedStrings(m interface{}) []string {
+	var keys []string
+	switch v := m.(type) {
+	case map[string]bool:
+		for key := range v {
+			keys = append(keys, key)
+		}
+	case map[string]string:
+		for key := range v {
+			keys = append(keys, key)
+		}
+	case map[string]map[string]string:
+		for key := range v {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Rewrite describes a single value changed by RenameKey or RenamePack
+type Rewrite struct {
+	Pack  string // the option pack containing the entity
+	Kind  string // the kind of entity that was changed, e.g. "subclasses"
+	Key   string // the key of the entity that was changed, after renaming
+	Field string // the field that was changed, e.g. "class"
+	Old   string
+	New   string
+}
+
+func (r Rewrite) String() string {
+	return fmt.Sprintf("%s/%s/%s %s: %s -> %s", r.Pack, r.Kind, r.Key, r.Field, r.Old, r.New)
+}
+
+// RenameKey changes the key of the entities of the given kind with key
+// oldKey, in every option pack, and rewrites all references to them from
+// other entities. The option packs are modified in place.
+func RenameKey(all OrcbrewExportAll, kind string, oldKey string, newKey string) ([]Rewrite, error) {
+	if !IsKind(kind) {
+		return nil, fmt.Errorf("Unknown kind %s", kind)
+	}
+	if newKey == "" {
+		return nil, fmt.Errorf("The new key can't be empty")
+	}
+
+	found := false
+	for _, pack := range sortedPacks(all) {
+		source := all[pack]
+		entities := source.kindMap(kind)
+		if entities.MapIndex(reflect.ValueOf(oldKey)).IsValid() {
+			found = true
+			if entities.MapIndex(reflect.ValueOf(newKey)).IsValid() {
+				return nil, fmt.Errorf("%s/%s/%s already exists", pack, kind, newKey)
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("No %s with key %s", kind, oldKey)
+	}
+
+	var rewrites []Rewrite
+	for _, pack := range sortedPacks(all) {
+		source := all[pack]
+		entities := source.kindMap(kind)
+
+		if entity := entities.MapIndex(reflect.ValueOf(oldKey)); entity.IsValid() {
+			renamed := reflect.New(entity.Type()).Elem()
+			renamed.Set(entity)
+			renamed.FieldByName("Key").SetString(newKey)
+
+			entities.SetMapIndex(reflect.ValueOf(oldKey), reflect.Value{})
+			entities.SetMapIndex(reflect.ValueOf(newKey), renamed)
+			rewrites = append(rewrites, Rewrite{Pack: pack, Kind: kind, Key: newKey, Field: "key", Old: oldKey, New: newKey})
+		}
+
+		rewrites = append(rewrites, rewriteReferences(&source, pack, func(ref Reference) bool {
+			return ref.Kind == kind && ref.Key == oldKey
+		}, newKey)...)
+		all[pack] = source
+	}
+
+	return rewrites, nil
+}
+
+// RenamePack moves the entities of option pack oldName to newName, updating
+// their OptionPack fields. The option packs are modified in place.
+func RenamePack(all OrcbrewExportAll, oldName string, newName string) ([]Rewrite, error) {
+	source, ok := all[oldName]
+	if !ok {
+		return nil, fmt.Errorf("No option pack named %s", oldName)
+	}
+	if newName == "" {
+		return nil, fmt.Errorf("The new option pack name can't be empty")
+	}
+	if _, ok := all[newName]; ok && newName != oldName {
+		return nil, fmt.Errorf("Option pack %s already exists", newName)
+	}
+
+	var rewrites []Rewrite
+	for _, kind := range Kinds {
+		entities := source.kindMap(kind)
+		for _, key := range sortedKeys(entities) {
+			entity := entities.MapIndex(reflect.ValueOf(key))
+			pack := entity.FieldByName("OptionPack").String()
+			if pack != oldName {
+				continue
+			}
+
+			renamed := reflect.New(entity.Type()).Elem()
+			renamed.Set(entity)
+			renamed.FieldByName("OptionPack").SetString(newName)
+			entities.SetMapIndex(reflect.ValueOf(key), renamed)
+			rewrites = append(rewrites, Rewrite{Pack: newName, Kind: kind, Key: key, Field: "option-pack", Old: oldName, New: newName})
+		}
+	}
+
+	delete(all, oldName)
+	all[newName] = source
+
+	return rewrites, nil
+}
+
+// rewriteReferences changes the references in source selected by match to
+// newKey, returning the locations that were rewritten
+func rewriteReferences(source *OrcbrewSource, pack string, match func(ref Reference) bool, newKey string) []Rewrite {
+	var rewrites []Rewrite
+	var entity Entity
+
+	Walk(source, Visitor{
+		Entity: func(path string, e Entity) {
+			entity = e
+		},
+		Reference: func(path string, ref Reference, rename func(string)) {
+			if match(ref) {
+				rename(newKey)
+				rewrites = append(rewrites, Rewrite{Pack: pack, Kind: entity.EntityKind(), Key: entity.EntityKey(), Field: ref.Field, Old: ref.Key, New: newKey})
+			}
+		},
+	})
+
+	return rewrites
+}
+
+func sortedPacks(all OrcbrewExportAll) []string {
+	var packs []string
+	for pack := range all {
+		packs = append(packs, pack)
+	}
+	sort.Strings(packs)
+	return packs
+}
@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func renameInput() OrcbrewExportAll {
+	return OrcbrewExportAll{
+		"Test": OrcbrewSource{
+			Classes: map[string]ClassConfig{
+				"myclass": ClassConfig{Key: "myclass", OptionPack: "Test", Name: "MyClass"},
+			},
+			Subclasses: map[string]SubclassConfig{
+				"mysubclass": SubclassConfig{Key: "mysubclass", OptionPack: "Test", Class: "myclass",
+					LevelSelections: []LevelSelection{{Type: "myselection", Num: 1, Level: 3}}},
+			},
+			Selections: map[string]SelectionConfig{
+				"myselection": SelectionConfig{Key: "myselection", OptionPack: "Test", Name: "MySelection"},
+			},
+			Races: map[string]RaceConfig{
+				"myrace": RaceConfig{Key: "myrace", OptionPack: "Test", Name: "MyRace"},
+			},
+			Feats: map[string]FeatConfig{
+				"myfeat": FeatConfig{Key: "myfeat", OptionPack: "Test",
+					PathPrereqs: FeatPathPrereqs{Race: map[string]bool{"myrace": true, "elf": true}}},
+			},
+		},
+		"Other": OrcbrewSource{
+			Subclasses: map[string]SubclassConfig{
+				"othersubclass": SubclassConfig{Key: "othersubclass", OptionPack: "Other", Class: "myclass"},
+			},
+		},
+	}
+}
+
+func TestRenameKey(t *testing.T) {
+	all := renameInput()
+
+	rewrites, err := RenameKey(all, "classes", "myclass", "newclass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Rewrite{
+		{Pack: "Other", Kind: "subclasses", Key: "othersubclass", Field: "class", Old: "myclass", New: "newclass"},
+		{Pack: "Test", Kind: "classes", Key: "newclass", Field: "key", Old: "myclass", New: "newclass"},
+		{Pack: "Test", Kind: "subclasses", Key: "mysubclass", Field: "class", Old: "myclass", New: "newclass"},
+	}
+	if diff := deep.Equal(rewrites, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	if _, ok := all["Test"].Classes["myclass"]; ok {
+		t.Errorf("Expected myclass to be removed")
+	}
+	if all["Test"].Classes["newclass"].Key != "newclass" {
+		t.Errorf("Expected the Key field of newclass to be updated")
+	}
+	if all["Test"].Subclasses["mysubclass"].Class != "newclass" || all["Other"].Subclasses["othersubclass"].Class != "newclass" {
+		t.Errorf("Expected references to myclass to be updated")
+	}
+}
+
+func TestRenameKeyReferences(t *testing.T) {
+	all := renameInput()
+
+	_, err := RenameKey(all, "selections", "myselection", "newselection")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all["Test"].Subclasses["mysubclass"].LevelSelections[0].Type != "newselection" {
+		t.Errorf("Expected the level selection to be updated")
+	}
+
+	rewrites, err := RenameKey(all, "races", "myrace", "newrace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rewrites) != 2 || rewrites[1].Field != "path-prereqs/race/myrace" {
+		t.Errorf("Unexpected rewrites %v", rewrites)
+	}
+	if diff := deep.Equal(all["Test"].Feats["myfeat"].PathPrereqs.Race, map[string]bool{"newrace": true, "elf": true}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestRenameKeySpellLists(t *testing.T) {
+	all := renameInput()
+	source := all["Test"]
+	source.Spells = map[string]SpellConfig{
+		"myspell": SpellConfig{Key: "myspell", OptionPack: "Test", SpellLists: map[string]bool{"myclass": true, "wizard": true}},
+	}
+	source.Subclasses["mysubclass"] = SubclassConfig{Key: "mysubclass", OptionPack: "Test", Class: "myclass",
+		Spellcasting: &SpellcastingConfig{SpellListKw: "myclass"}}
+	all["Test"] = source
+
+	rewrites, err := RenameKey(all, "classes", "myclass", "newclass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Rewrite{
+		{Pack: "Other", Kind: "subclasses", Key: "othersubclass", Field: "class", Old: "myclass", New: "newclass"},
+		{Pack: "Test", Kind: "classes", Key: "newclass", Field: "key", Old: "myclass", New: "newclass"},
+		{Pack: "Test", Kind: "subclasses", Key: "mysubclass", Field: "class", Old: "myclass", New: "newclass"},
+		{Pack: "Test", Kind: "subclasses", Key: "mysubclass", Field: "spellcasting/spell-list-kw", Old: "myclass", New: "newclass"},
+		{Pack: "Test", Kind: "spells", Key: "myspell", Field: "spell-lists/myclass", Old: "myclass", New: "newclass"},
+	}
+	if diff := deep.Equal(rewrites, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	if diff := deep.Equal(all["Test"].Spells["myspell"].SpellLists, map[string]bool{"newclass": true, "wizard": true}); diff != nil {
+		t.Error(diff)
+	}
+	if kw := all["Test"].Subclasses["mysubclass"].Spellcasting.SpellListKw; kw != "newclass" {
+		t.Errorf("Expected spell-list-kw to be newclass, got %s", kw)
+	}
+}
+
+func TestRenameKeyErrors(t *testing.T) {
+	all := renameInput()
+
+	if _, err := RenameKey(all, "classes", "missing", "newclass"); err == nil {
+		t.Errorf("Expected an error renaming a missing key")
+	}
+	if _, err := RenameKey(all, "widgets", "myclass", "newclass"); err == nil {
+		t.Errorf("Expected an error renaming an unknown kind")
+	}
+
+	all["Test"].Classes["newclass"] = ClassConfig{Key: "newclass"}
+	if _, err := RenameKey(all, "classes", "myclass", "newclass"); err == nil {
+		t.Errorf("Expected an error renaming to an existing key")
+	}
+}
+
+func TestRenamePack(t *testing.T) {
+	all := renameInput()
+
+	rewrites, err := RenamePack(all, "Test", "Renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rewrites) != 5 {
+		t.Errorf("Expected 5 rewrites, got %v", rewrites)
+	}
+
+	if _, ok := all["Test"]; ok {
+		t.Errorf("Expected Test to be removed")
+	}
+	if diff := deep.Equal(all["Renamed"].OptionPacks(), []string{"Renamed"}); diff != nil {
+		t.Error(diff)
+	}
+
+	if _, err := RenamePack(all, "Renamed", "Other"); err == nil {
+		t.Errorf("Expected an error renaming to an existing pack")
+	}
+	if _, err := RenamePack(all, "Missing", "New"); err == nil {
+		t.Errorf("Expected an error renaming a missing pack")
+	}
+}
@@ -0,0 +1,605 @@
+package schema
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TableColumns are the default columns of the table for each kind. Kinds
+// that aren't listed default to key and name.
+var TableColumns = map[string][]string{
+	"spells": {"key", "name", "level", "school", "ritual", "concentration", "components",
+		"casting-time", "range", "duration", "spell-lists", "description"},
+	"monsters": {"key", "name", "size", "type", "alignment", "armor-class", "hit-points", "speed",
+		"str", "dex", "con", "int", "wis", "cha", "saving-throws", "skills",
+		"props.damage-vulnerability", "props.damage-resistance", "props.damage-immunity",
+		"props.condition-immunity", "props.language", "challenge", "description"},
+	"feats":       {"key", "name", "prereqs", "path-prereqs.race", "ability-increases", "description"},
+	"races":       {"key", "name", "abilities", "size", "speed", "darkvision", "languages"},
+	"backgrounds": {"key", "name", "profs.skill", "profs.tool", "equipment", "treasure"},
+	"classes": {"key", "name", "hit-die", "subclass-level", "subclass-title", "ability-increase-levels",
+		"spellcasting.ability", "profs.save"},
+}
+
+// derivedColumns are columns computed from other fields, which are ignored
+// when a table is read
+var derivedColumns = map[string]map[string]func(entity reflect.Value) string{
+	"spells": {
+		// OrcPub has no concentration field, it's part of the duration
+		"concentration": func(entity reflect.Value) string {
+			duration := strings.ToLower(entity.FieldByName("Duration").String())
+			return strconv.FormatBool(strings.HasPrefix(duration, "concentration"))
+		},
+	},
+}
+
+// TableColumnNames returns the columns that can be used in a table of the
+// given kind: the fields of the entity, using "." for the fields of nested
+// objects, and any derived columns
+func TableColumnNames(kind string) ([]string, error) {
+	entityType, err := tableEntityType(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	var add func(t reflect.Type, prefix string)
+	add = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			name := prefix + kindName(t.Field(i))
+			fieldType := t.Field(i).Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+
+			if fieldType.Kind() == reflect.Struct && !tableScalarTypes[fieldType] {
+				add(fieldType, name+".")
+			} else {
+				columns = append(columns, name)
+			}
+		}
+	}
+	add(entityType, "")
+
+	var derived []string
+	for name := range derivedColumns[kind] {
+		derived = append(derived, name)
+	}
+	sort.Strings(derived)
+	return append(columns, derived...), nil
+}
+
+// WriteTable writes the entities of a kind in every option pack as rows of a
+// table, after a header row naming the columns. Columns are the JSON names of
+// fields, with "." separating the fields of nested objects, e.g.
+// "props.language". Sets and lists are written as comma separated values,
+// and more complex fields as JSON.
+func WriteTable(w *csv.Writer, all OrcbrewExportAll, kind string, columns []string) error {
+	if _, err := tableEntityType(kind); err != nil {
+		return err
+	}
+	if err := checkTableColumns(kind, columns); err != nil {
+		return err
+	}
+
+	if err := w.Write(columns); err != nil {
+		return err
+	}
+
+	for _, pack := range sortedPacks(all) {
+		source := all[pack]
+		entities := source.kindMap(kind)
+		for _, key := range sortedKeys(entities) {
+			entity := entities.MapIndex(reflect.ValueOf(key))
+
+			row := make([]string, len(columns))
+			for idx, column := range columns {
+				if derived, ok := derivedColumns[kind][column]; ok {
+					row[idx] = derived(entity)
+					continue
+				}
+
+				field, _ := tableField(entity, column, false)
+				cell, err := formatCell(field)
+				if err != nil {
+					return fmt.Errorf("%s/%s/%s %s: %s", pack, kind, key, column, err)
+				}
+				row[idx] = cell
+			}
+
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// ReadTable updates the entities of a kind from a table in the format written
+// by WriteTable, which must have a key column. Only the columns in the table
+// are changed. Rows are matched to entities by key, and by option pack when
+// the table has an option-pack column. Rows for keys that don't exist add a
+// new entity, which needs an option-pack column unless there's only one
+// option pack. Entities without a row are left alone. The option packs are
+// modified in place, and each changed cell is returned.
+func ReadTable(r *csv.Reader, all OrcbrewExportAll, kind string) ([]Rewrite, error) {
+	entityType, err := tableEntityType(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("The table is empty")
+	} else if err != nil {
+		return nil, err
+	}
+	if err := checkTableColumns(kind, header); err != nil {
+		return nil, err
+	}
+
+	keyColumn, packColumn := -1, -1
+	for idx, column := range header {
+		switch column {
+		case "key":
+			keyColumn = idx
+		case "option-pack":
+			packColumn = idx
+		}
+	}
+	if keyColumn < 0 {
+		return nil, fmt.Errorf("The table has no key column")
+	}
+
+	var rewrites []Rewrite
+	for line := 2; ; line++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		key := strings.TrimSpace(row[keyColumn])
+		if key == "" {
+			return nil, fmt.Errorf("Row %d has no key", line)
+		}
+
+		packName := ""
+		if packColumn >= 0 {
+			packName = strings.TrimSpace(row[packColumn])
+		}
+		pack, err := tableRowPack(all, kind, key, packName)
+		if err != nil {
+			return nil, fmt.Errorf("Row %d: %s", line, err)
+		}
+
+		source := all[pack]
+		entities := source.kindMap(kind)
+		entity := reflect.New(entityType).Elem()
+		if existing := entities.MapIndex(reflect.ValueOf(key)); existing.IsValid() {
+			entity.Set(existing)
+		} else {
+			entity.FieldByName("Key").SetString(key)
+			entity.FieldByName("OptionPack").SetString(pack)
+			rewrites = append(rewrites, Rewrite{Pack: pack, Kind: kind, Key: key, Field: "key", New: key})
+		}
+
+		for idx, column := range header {
+			if idx == keyColumn || idx == packColumn || derivedColumns[kind][column] != nil {
+				continue
+			}
+
+			field, _ := tableField(entity, column, false)
+			old, err := formatCell(field)
+			if err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(row[idx]) == old {
+				continue
+			}
+
+			field, _ = tableField(entity, column, true)
+			if err := parseCell(field, row[idx]); err != nil {
+				return nil, fmt.Errorf("Row %d, %s: %s", line, column, err)
+			}
+			value, err := formatCell(field)
+			if err != nil {
+				return nil, err
+			}
+
+			if value != old {
+				rewrites = append(rewrites, Rewrite{Pack: pack, Kind: kind, Key: key, Field: column, Old: old, New: value})
+			}
+		}
+
+		setEntity(entities, key, entity)
+		all[pack] = source
+	}
+
+	return rewrites, nil
+}
+
+// tableRowPack returns the option pack a row of a table belongs to
+func tableRowPack(all OrcbrewExportAll, kind string, key string, pack string) (string, error) {
+	if pack != "" {
+		if _, ok := all[pack]; !ok {
+			return "", fmt.Errorf("No option pack named %s", pack)
+		}
+		return pack, nil
+	}
+
+	var found []string
+	for _, name := range sortedPacks(all) {
+		source := all[name]
+		if source.kindMap(kind).MapIndex(reflect.ValueOf(key)).IsValid() {
+			found = append(found, name)
+		}
+	}
+
+	switch {
+	case len(found) == 1:
+		return found[0], nil
+	case len(found) > 1:
+		return "", fmt.Errorf("%s/%s is in several option packs, add an option-pack column", kind, key)
+	case len(all) == 1:
+		return sortedPacks(all)[0], nil
+	}
+	return "", fmt.Errorf("%s/%s doesn't exist, add an option-pack column to choose the pack to add it to", kind, key)
+}
+
+func tableEntityType(kind string) (reflect.Type, error) {
+	entityType := KindType(kind)
+	if entityType == nil {
+		return nil, fmt.Errorf("Unknown kind %s", kind)
+	}
+	return entityType, nil
+}
+
+func checkTableColumns(kind string, columns []string) error {
+	entityType, _ := tableEntityType(kind)
+	for _, column := range columns {
+		if derivedColumns[kind][column] != nil {
+			continue
+		}
+		if _, ok := tableField(reflect.New(entityType).Elem(), column, true); !ok {
+			return fmt.Errorf("Unknown column %s for %s", column, kind)
+		}
+	}
+	return nil
+}
+
+// tableScalarTypes are structs that are written as a single value
+var tableScalarTypes = map[reflect.Type]bool{
+	reflect.TypeOf(SpellComponents{}): true,
+	reflect.TypeOf(HitDieCount{}):     true,
+}
+
+// tableField returns the field of an entity for a column, allocating nil
+// pointers along the way when create is set. Without create, the returned
+// value is invalid when a pointer on the way is nil.
+func tableField(entity reflect.Value, column string, create bool) (reflect.Value, bool) {
+	v := entity
+	for _, name := range strings.Split(column, ".") {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !create {
+					return reflect.Value{}, true
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct || tableScalarTypes[v.Type()] {
+			return reflect.Value{}, false
+		}
+
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if kindName(v.Type().Field(i)) == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+func formatCell(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "", nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case SpellComponents:
+		return formatComponents(value), nil
+	case HitDieCount:
+		if value.DieCount == 0 && value.Die == 0 {
+			return "", nil
+		}
+		return fmt.Sprintf("%dd%d", value.DieCount, value.Die), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Map:
+		if isScalarKind(v.Type().Key().Kind()) && v.Type().Elem().Kind() == reflect.Bool {
+			var members []string
+			for _, key := range v.MapKeys() {
+				if v.MapIndex(key).Bool() {
+					members = append(members, fmt.Sprint(key.Interface()))
+				}
+			}
+			sort.Strings(members)
+			return strings.Join(members, ", "), nil
+		}
+		if isScalarKind(v.Type().Key().Kind()) && isScalarKind(v.Type().Elem().Kind()) {
+			var pairs []string
+			for _, key := range v.MapKeys() {
+				pairs = append(pairs, fmt.Sprintf("%v: %v", key.Interface(), v.MapIndex(key).Interface()))
+			}
+			sort.Strings(pairs)
+			return strings.Join(pairs, ", "), nil
+		}
+	case reflect.Slice:
+		if isScalarKind(v.Type().Elem().Kind()) {
+			var items []string
+			for idx := 0; idx < v.Len(); idx++ {
+				items = append(items, fmt.Sprint(v.Index(idx).Interface()))
+			}
+			return strings.Join(items, ", "), nil
+		}
+	}
+
+	jsonBytes, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+	if cell := string(jsonBytes); cell != "null" && cell != "[]" && cell != "{}" {
+		return cell, nil
+	}
+	return "", nil
+}
+
+func parseCell(v reflect.Value, cell string) error {
+	cell = strings.TrimSpace(cell)
+
+	if v.Kind() == reflect.Ptr {
+		if cell == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	switch v.Interface().(type) {
+	case SpellComponents:
+		components, err := parseComponents(cell)
+		if err == nil {
+			v.Set(reflect.ValueOf(components))
+		}
+		return err
+	case HitDieCount:
+		var dice HitDieCount
+		if cell != "" {
+			if _, err := fmt.Sscanf(cell, "%dd%d", &dice.DieCount, &dice.Die); err != nil {
+				return fmt.Errorf("Expected dice such as 2d8, got %q", cell)
+			}
+		}
+		v.Set(reflect.ValueOf(dice))
+		return nil
+	}
+
+	if isScalarKind(v.Kind()) {
+		return parseScalar(v, cell)
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if isScalarKind(v.Type().Key().Kind()) && isScalarKind(v.Type().Elem().Kind()) {
+			if cell == "" {
+				v.Set(reflect.Zero(v.Type()))
+				return nil
+			}
+
+			m := reflect.MakeMap(v.Type())
+			for _, item := range splitList(cell) {
+				key, value := item, "true"
+				if v.Type().Elem().Kind() != reflect.Bool {
+					idx := strings.LastIndex(item, ":")
+					if idx < 0 {
+						return fmt.Errorf("Expected key: value, got %q", item)
+					}
+					key, value = strings.TrimSpace(item[:idx]), strings.TrimSpace(item[idx+1:])
+				}
+
+				k := reflect.New(v.Type().Key()).Elem()
+				if err := parseScalar(k, key); err != nil {
+					return err
+				}
+				e := reflect.New(v.Type().Elem()).Elem()
+				if err := parseScalar(e, value); err != nil {
+					return err
+				}
+				m.SetMapIndex(k, e)
+			}
+			v.Set(m)
+			return nil
+		}
+	case reflect.Slice:
+		if isScalarKind(v.Type().Elem().Kind()) {
+			if cell == "" {
+				v.Set(reflect.Zero(v.Type()))
+				return nil
+			}
+
+			list := reflect.MakeSlice(v.Type(), 0, 0)
+			for _, item := range splitList(cell) {
+				e := reflect.New(v.Type().Elem()).Elem()
+				if err := parseScalar(e, item); err != nil {
+					return err
+				}
+				list = reflect.Append(list, e)
+			}
+			v.Set(list)
+			return nil
+		}
+	}
+
+	if cell == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	ptr := reflect.New(v.Type())
+	if err := json.Unmarshal([]byte(cell), ptr.Interface()); err != nil {
+		return fmt.Errorf("Expected JSON: %s", err)
+	}
+	v.Set(ptr.Elem())
+	return nil
+}
+
+func isScalarKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+// parseScalar parses a string, number or boolean. Numbers may be written as
+// fractions, as in a challenge rating of 1/4.
+func parseScalar(v reflect.Value, cell string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(cell)
+	case reflect.Bool:
+		switch strings.ToLower(cell) {
+		case "", "false", "no", "n", "0":
+			v.SetBool(false)
+		case "true", "yes", "y", "x", "1":
+			v.SetBool(true)
+		default:
+			return fmt.Errorf("Expected true or false, got %q", cell)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if cell == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(cell, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Expected a whole number, got %q", cell)
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		if cell == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		if parts := strings.Split(cell, "/"); len(parts) == 2 {
+			numerator, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+			denominator, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if err1 == nil && err2 == nil && denominator != 0 {
+				v.SetFloat(numerator / denominator)
+				return nil
+			}
+		}
+		n, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			return fmt.Errorf("Expected a number, got %q", cell)
+		}
+		v.SetFloat(n)
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, ignoring commas in parentheses
+func splitList(cell string) []string {
+	var items []string
+	depth, start := 0, 0
+	for idx, r := range cell + "," {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth <= 0 {
+				if item := strings.TrimSpace(cell[start:idx]); item != "" {
+					items = append(items, item)
+				}
+				start = idx + 1
+			}
+		}
+	}
+	return items
+}
+
+// formatComponents writes spell components as they're usually printed, e.g.
+// "V, S, M (a pinch of salt)"
+func formatComponents(components SpellComponents) string {
+	var parts []string
+	if components.Verbal {
+		parts = append(parts, "V")
+	}
+	if components.Somatic {
+		parts = append(parts, "S")
+	}
+	if components.Material {
+		if components.MaterialComponent != "" {
+			parts = append(parts, "M ("+components.MaterialComponent+")")
+		} else {
+			parts = append(parts, "M")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func parseComponents(cell string) (SpellComponents, error) {
+	var components SpellComponents
+	for _, part := range splitList(cell) {
+		switch {
+		case strings.EqualFold(part, "V"):
+			components.Verbal = true
+		case strings.EqualFold(part, "S"):
+			components.Somatic = true
+		case strings.EqualFold(part, "M"):
+			components.Material = true
+		case strings.HasPrefix(strings.ToUpper(part), "M") && strings.HasSuffix(part, ")") && strings.Contains(part, "("):
+			components.Material = true
+			components.MaterialComponent = strings.TrimSpace(part[strings.Index(part, "(")+1 : len(part)-1])
+		default:
+			return components, fmt.Errorf("Unknown spell component %q, expected V, S or M (material)", part)
+		}
+	}
+	return components, nil
+}
@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func tableInput() OrcbrewExportAll {
+	return OrcbrewExportAll{
+		"Test": OrcbrewSource{
+			Spells: map[string]SpellConfig{
+				"myspell": SpellConfig{Key: "myspell", OptionPack: "Test", Name: "MySpell", Level: 1,
+					School: "evocation", Duration: "Concentration, up to 1 minute",
+					Description: "A spell.\n\nWith \"quotes\", commas and two paragraphs.",
+					Components:  &SpellComponents{Verbal: true, Material: true, MaterialComponent: "a pinch of salt, or sugar"},
+					SpellLists:  map[string]bool{"wizard": true, "bard": true, "cleric": false}},
+			},
+			Monsters: map[string]MonsterConfig{
+				"goblin": MonsterConfig{Key: "goblin", OptionPack: "Test", Name: "Goblin", Challenge: 0.25,
+					HitPoints:    &HitDieCount{DieCount: 2, Die: 6},
+					SavingThrows: map[Ability]int{Dexterity: 4}},
+			},
+		},
+		"Other": OrcbrewSource{
+			Spells: map[string]SpellConfig{
+				"otherspell": SpellConfig{Key: "otherspell", OptionPack: "Other", Name: "OtherSpell"},
+			},
+		},
+	}
+}
+
+func writeTable(t *testing.T, all OrcbrewExportAll, kind string, columns []string) string {
+	var buf bytes.Buffer
+	if err := WriteTable(csv.NewWriter(&buf), all, kind, columns); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestWriteTable(t *testing.T) {
+	output := writeTable(t, tableInput(), "spells", []string{"key", "name", "concentration", "components", "spell-lists", "description"})
+
+	expected := `key,name,concentration,components,spell-lists,description
+otherspell,OtherSpell,false,,,
+myspell,MySpell,true,"V, M (a pinch of salt, or sugar)","bard, wizard","A spell.
+
+With ""quotes"", commas and two paragraphs."
+`
+	if output != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output)
+	}
+
+	output = writeTable(t, tableInput(), "monsters", []string{"key", "hit-points", "saving-throws", "challenge", "props.language"})
+	expected = "key,hit-points,saving-throws,challenge,props.language\ngoblin,2d6,dex: 4,0.25,\n"
+	if output != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTable(csv.NewWriter(&buf), tableInput(), "spells", []string{"key", "levle"}); err == nil {
+		t.Errorf("Expected an error for an unknown column")
+	}
+}
+
+func TestReadTableRoundTrip(t *testing.T) {
+	for kind, columns := range TableColumns {
+		all := tableInput()
+		output := writeTable(t, all, kind, columns)
+
+		rewrites, err := ReadTable(csv.NewReader(strings.NewReader(output)), all, kind)
+		if err != nil {
+			t.Fatalf("%s: %s", kind, err)
+		}
+		if len(rewrites) != 0 {
+			t.Errorf("%s: expected no changes, got %v", kind, rewrites)
+		}
+		if diff := deep.Equal(all, tableInput()); diff != nil {
+			t.Errorf("%s: %v", kind, diff)
+		}
+	}
+}
+
+func TestReadTable(t *testing.T) {
+	all := tableInput()
+	table := `key,level,components,spell-lists,ritual,concentration
+myspell,2,"V, S","wizard, sorcerer",yes,false
+newspell,0,,,,
+`
+
+	_, err := ReadTable(csv.NewReader(strings.NewReader(table)), all, "spells")
+	if err == nil || !strings.Contains(err.Error(), "add an option-pack column") {
+		t.Errorf("Expected an error for a new spell without an option pack, got %v", err)
+	}
+
+	all = tableInput()
+	table = strings.Replace(table, "concentration\n", "concentration,option-pack\n", 1)
+	table = strings.Replace(table, "false\n", "false,\n", 1)
+	table = strings.Replace(table, ",,,,\n", ",,,,,Other\n", 1)
+	rewrites, err := ReadTable(csv.NewReader(strings.NewReader(table)), all, "spells")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Rewrite{
+		{Pack: "Test", Kind: "spells", Key: "myspell", Field: "level", Old: "1", New: "2"},
+		{Pack: "Test", Kind: "spells", Key: "myspell", Field: "components", Old: "V, M (a pinch of salt, or sugar)", New: "V, S"},
+		{Pack: "Test", Kind: "spells", Key: "myspell", Field: "spell-lists", Old: "bard, wizard", New: "sorcerer, wizard"},
+		{Pack: "Test", Kind: "spells", Key: "myspell", Field: "ritual", Old: "false", New: "true"},
+		{Pack: "Other", Kind: "spells", Key: "newspell", Field: "key", New: "newspell"},
+	}
+	if diff := deep.Equal(rewrites, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	spell := all["Test"].Spells["myspell"]
+	if spell.Level != 2 || !spell.Ritual || *spell.Components != (SpellComponents{Verbal: true, Somatic: true}) {
+		t.Errorf("Unexpected spell %+v", spell)
+	}
+	if diff := deep.Equal(spell.SpellLists, map[string]bool{"wizard": true, "sorcerer": true}); diff != nil {
+		t.Error(diff)
+	}
+	if spell.Duration != "Concentration, up to 1 minute" {
+		t.Errorf("Expected the concentration column to be ignored")
+	}
+	if spell := all["Other"].Spells["newspell"]; spell.Key != "newspell" || spell.OptionPack != "Other" {
+		t.Errorf("Expected newspell to be added to Other, got %+v", spell)
+	}
+}
+
+func TestReadTableMonsters(t *testing.T) {
+	all := tableInput()
+	table := "key\thit-points\tchallenge\tsaving-throws\nGoblin\t3d6\t1/2\tdex: 4, wis: -1\n"
+	table = strings.Replace(table, "Goblin", "goblin", 1)
+
+	r := csv.NewReader(strings.NewReader(table))
+	r.Comma = '\t'
+	if _, err := ReadTable(r, all, "monsters"); err != nil {
+		t.Fatal(err)
+	}
+
+	monster := all["Test"].Monsters["goblin"]
+	if *monster.HitPoints != (HitDieCount{DieCount: 3, Die: 6}) || monster.Challenge != 0.5 {
+		t.Errorf("Unexpected monster %+v", monster)
+	}
+	if diff := deep.Equal(monster.SavingThrows, map[Ability]int{Dexterity: 4, Wisdom: -1}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestReadTableErrors(t *testing.T) {
+	tests := []struct {
+		table    string
+		expected string
+	}{
+		{"name\nMySpell\n", "no key column"},
+		{"key,levle\nmyspell,1\n", "Unknown column levle"},
+		{"key,level\nmyspell,one\n", "Row 2, level: Expected a whole number"},
+		{"key,components\nmyspell,\"V, X\"\n", "Unknown spell component"},
+		{"key,option-pack\nmyspell,Missing\n", "No option pack named Missing"},
+		{"", "empty"},
+	}
+
+	for _, test := range tests {
+		_, err := ReadTable(csv.NewReader(strings.NewReader(test.table)), tableInput(), "spells")
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%q: expected an error containing %q, got %v", test.table, test.expected, err)
+		}
+	}
+}
+
+func TestTableColumnNames(t *testing.T) {
+	columns, err := TableColumnNames("spells")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range []string{"key", "components", "spell-lists", "concentration"} {
+		if !containsString(columns, column) {
+			t.Errorf("Expected %s in %v", column, columns)
+		}
+	}
+
+	columns, _ = TableColumnNames("monsters")
+	if !containsString(columns, "props.damage-immunity") || !containsString(columns, "hit-points") {
+		t.Errorf("Expected nested and dice columns in %v", columns)
+	}
+
+	if _, err := TableColumnNames("widgets"); err == nil {
+		t.Errorf("Expected an error for an unknown kind")
+	}
+}
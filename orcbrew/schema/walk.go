@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Visitor holds the functions called by Walk for each part of a source.
+// Functions that are nil are skipped. Each is given the path to the part,
+// e.g. "classes/myclass/traits/0", and can change it in place.
+type Visitor struct {
+	// Entity is called with a pointer to each entity, e.g. a *SpellConfig.
+	// Changing the entity's Key doesn't change the key it's stored under.
+	Entity func(path string, entity Entity)
+
+	// Trait is called with the name and description of each trait of a
+	// class, subclass, race, subrace, background or monster
+	Trait func(path string, name *string, description *string)
+
+	// Modifier is called for each level modifier of a class or subclass,
+	// which can be replaced by assigning to *modifier
+	Modifier func(path string, modifier *LevelModifier)
+
+	// Selection is called for each level selection of a class or subclass
+	Selection func(path string, selection *LevelSelection)
+
+	// Option is called for each option of a selection
+	Option func(path string, option *SelectionOption)
+
+	// Reference is called for each reference to another entity, such as a
+	// subclass's class or the spells granted by a race, along with a function
+	// that changes the referenced key
+	Reference func(path string, ref Reference, rename func(key string))
+}
+
+// Walk visits every entity in a source in order of kind and key, followed by
+// its traits, modifiers, selections, options and references
+func Walk(src *OrcbrewSource, v Visitor) {
+	for _, kind := range Kinds {
+		entities := src.kindMap(kind)
+		for _, key := range sortedKeys(entities) {
+			path := kind + "/" + key
+			entity := src.entityPointer(kind, key)
+			walkEntity(path, entity, v)
+			entities.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(entity).Elem())
+		}
+	}
+}
+
+func walkEntity(path string, entity interface{}, v Visitor) {
+	value := reflect.ValueOf(entity).Elem()
+
+	if v.Entity != nil {
+		v.Entity(path, entity.(Entity))
+	}
+
+	if traits := value.FieldByName("Traits"); v.Trait != nil && traits.IsValid() {
+		for idx := 0; idx < traits.Len(); idx++ {
+			trait := traits.Index(idx)
+			v.Trait(fmt.Sprintf("%s/traits/%d", path, idx),
+				trait.FieldByName("Name").Addr().Interface().(*string),
+				trait.FieldByName("Description").Addr().Interface().(*string))
+		}
+	}
+
+	if modifiers := value.FieldByName("LevelModifiers"); v.Modifier != nil && modifiers.IsValid() {
+		list := modifiers.Interface().(LevelModifierList)
+		for idx := range list {
+			v.Modifier(fmt.Sprintf("%s/level-modifiers/%d", path, idx), &list[idx])
+		}
+	}
+
+	if selections := value.FieldByName("LevelSelections"); v.Selection != nil && selections.IsValid() {
+		list := selections.Interface().([]LevelSelection)
+		for idx := range list {
+			v.Selection(fmt.Sprintf("%s/level-selections/%d", path, idx), &list[idx])
+		}
+	}
+
+	if selection, ok := entity.(*SelectionConfig); ok && v.Option != nil {
+		for idx := range selection.Options {
+			v.Option(fmt.Sprintf("%s/options/%d", path, idx), &selection.Options[idx])
+		}
+	}
+
+	if v.Reference != nil {
+		walkReferences(entity, func(ref Reference, rename func(string)) {
+			v.Reference(path+"/"+ref.Field, ref, rename)
+		})
+	}
+}
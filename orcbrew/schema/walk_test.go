@@ -0,0 +1,94 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestWalk(t *testing.T) {
+	source, _ := LoadSourceFile(t, "example.json")
+
+	var paths []string
+	record := func(path string) {
+		paths = append(paths, path)
+	}
+
+	Walk(&source, Visitor{
+		Entity: func(path string, entity Entity) {
+			if strings.HasPrefix(path, "subclasses/") {
+				record(path)
+			}
+		},
+		Trait: func(path string, name *string, description *string) {
+			if strings.HasPrefix(path, "subclasses/") {
+				record(path + " " + *name)
+			}
+		},
+		Modifier: func(path string, modifier *LevelModifier) {
+			if strings.HasPrefix(path, "subclasses/") {
+				record(path + " " + string((*modifier).Type()))
+			}
+		},
+		Selection: func(path string, selection *LevelSelection) {
+			if strings.HasPrefix(path, "subclasses/") {
+				record(path + " " + selection.Type)
+			}
+		},
+		Reference: func(path string, ref Reference, rename func(string)) {
+			if strings.HasPrefix(path, "subclasses/") {
+				record(path + " " + ref.String())
+			}
+		},
+	})
+
+	expected := []string{
+		"subclasses/mysubclass",
+		"subclasses/mysubclass/traits/0 My subclass trait",
+		"subclasses/mysubclass/level-modifiers/0 armor-prof",
+		"subclasses/mysubclass/level-modifiers/1 skill-prof",
+		"subclasses/mysubclass/level-selections/0 my-selection-thingy",
+		"subclasses/mysubclass/class classes/barbarian",
+		"subclasses/mysubclass/level-selections/0/type selections/my-selection-thingy",
+	}
+	if diff := deep.Equal(paths, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestWalkMutate(t *testing.T) {
+	source, _ := LoadSourceFile(t, "example.json")
+
+	Walk(&source, Visitor{
+		Entity: func(path string, entity Entity) {
+			if spell, ok := entity.(*SpellConfig); ok {
+				spell.Level = 9
+			}
+		},
+		Trait: func(path string, name *string, description *string) {
+			*description = strings.ToUpper(*description)
+		},
+		Option: func(path string, option *SelectionOption) {
+			option.Name = "Renamed"
+		},
+		Modifier: func(path string, modifier *LevelModifier) {
+			if _, ok := (*modifier).(*ModifierArmorProficiency); ok {
+				*modifier = &ModifierArmorProficiency{Value: HeavyArmor}
+			}
+		},
+	})
+
+	if source.Spells["myspell"].Level != 9 {
+		t.Errorf("Expected the spell level to be changed")
+	}
+	if source.Subclasses["mysubclass"].Traits[0].Description != "THIS IS A SUBCLASS TRAIT" {
+		t.Errorf("Expected the trait description to be changed, got %s", source.Subclasses["mysubclass"].Traits[0].Description)
+	}
+	if source.Selections["my-selection-thingy"].Options[0].Name != "Renamed" {
+		t.Errorf("Expected the option to be renamed")
+	}
+	if modifier := source.Subclasses["mysubclass"].LevelModifiers[0].(*ModifierArmorProficiency); modifier.Value != HeavyArmor {
+		t.Errorf("Expected the modifier to be replaced, got %v", modifier)
+	}
+}
@@ -0,0 +1,70 @@
+package orcbrew
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// Pack is a single option pack split out of an .orcbrew file
+type Pack struct {
+	Name     string // the option pack name
+	Filename string // a safe file name for the pack, without extension
+	Source   schema.OrcbrewSource
+}
+
+// Split returns the option packs in the file sorted by name, each with a
+// file name that is safe to use and unique within the file
+func (f *File) Split() []Pack {
+	var names []string
+	for name := range f.Packs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	used := make(map[string]bool)
+	var packs []Pack
+	for _, name := range names {
+		filename := SafeFilename(name)
+
+		// Compare case-insensitively, for case-insensitive file systems
+		for n := 2; used[strings.ToLower(filename)]; n++ {
+			filename = fmt.Sprintf("%s-%d", SafeFilename(name), n)
+		}
+		used[strings.ToLower(filename)] = true
+
+		packs = append(packs, Pack{Name: name, Filename: filename, Source: f.Packs[name]})
+	}
+
+	return packs
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Names that can't be used as file names on Windows, regardless of extension
+var reservedFilenames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// SafeFilename converts an option pack name into a file name (without
+// extension) that is safe to use on common file systems
+func SafeFilename(name string) string {
+	filename := unsafeFilenameChars.ReplaceAllString(name, "-")
+	filename = strings.Trim(filename, "-.")
+
+	if filename == "" {
+		return "pack"
+	}
+	if reservedFilenames[strings.ToLower(filename)] {
+		return "_" + filename
+	}
+
+	return filename
+}
@@ -0,0 +1,51 @@
+package orcbrew
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+func TestSafeFilename(t *testing.T) {
+	tests := map[string]string{
+		"Test":                      "Test",
+		"Xanathar's Guide: Extras!": "Xanathar-s-Guide-Extras",
+		"../../etc/passwd":          "etc-passwd",
+		"...":                       "pack",
+		"Con":                       "_Con",
+		"Tome of Beasts v1.2":       "Tome-of-Beasts-v1.2",
+	}
+
+	for name, expected := range tests {
+		if result := SafeFilename(name); result != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, name, result)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	f := &File{
+		ExportAll: true,
+		Packs: schema.OrcbrewExportAll{
+			"My Pack": schema.OrcbrewSource{},
+			"my-pack": schema.OrcbrewSource{},
+			"Other":   schema.OrcbrewSource{},
+		},
+	}
+
+	var result []string
+	for _, pack := range f.Split() {
+		result = append(result, pack.Name+" => "+pack.Filename)
+	}
+
+	expected := []string{
+		"My Pack => My-Pack",
+		"Other => Other",
+		"my-pack => my-pack-2",
+	}
+
+	if diff := deep.Equal(result, expected); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,289 @@
+// Package sqlite writes option packs to a SQLite database for querying with
+// SQL, using a driver written in Go so that no cgo is needed.
+//
+// Each kind has a table named after it, e.g. spells, with a row for each
+// entity and a column for each of its values. Sets, maps and lists, such as
+// a spell's spell-lists or a monster's damage-resistance, have tables of
+// their own named after the kind and field, e.g. spells_spell_lists, as do
+// traits and other lists of objects, e.g. monsters_traits. The level
+// modifiers of every class and subclass are in the modifiers table.
+// README.md lists the tables and has some example queries.
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+
+	_ "modernc.org/sqlite" // registers the sqlite driver
+)
+
+// modifiersTable holds the level modifiers of classes and subclasses
+const modifiersTable = `CREATE TABLE modifiers (
+	kind TEXT NOT NULL,
+	option_pack TEXT NOT NULL,
+	key TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	level INTEGER NOT NULL,
+	type TEXT NOT NULL,
+	value,
+	ability TEXT,
+	PRIMARY KEY (kind, option_pack, key, position)
+)`
+
+// Tables returns the statements that create the tables of the database
+func Tables() []string {
+	var statements []string
+	for _, kind := range schema.Kinds {
+		for _, t := range kindTables(kind) {
+			statements = append(statements, t.create())
+		}
+	}
+	return append(statements, modifiersTable)
+}
+
+// WriteFile writes all the option packs to a new SQLite database, replacing
+// filename if it exists
+func WriteFile(all schema.OrcbrewExportAll, filename string) error {
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return err
+	}
+	if err := Write(db, all); err != nil {
+		db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+// Write creates the tables in an empty database and inserts every entity of
+// the option packs, in a single transaction
+func Write(db *sql.DB, all schema.OrcbrewExportAll) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := write(tx, all); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func write(tx *sql.Tx, all schema.OrcbrewExportAll) error {
+	for _, statement := range Tables() {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("%s: %s", statement, err)
+		}
+	}
+
+	modifiers, err := tx.Prepare("INSERT INTO modifiers VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer modifiers.Close()
+
+	var packs []string
+	for pack := range all {
+		packs = append(packs, pack)
+	}
+	sort.Strings(packs)
+
+	for _, kind := range schema.Kinds {
+		for _, t := range kindTables(kind) {
+			insert, err := tx.Prepare(t.insert())
+			if err != nil {
+				return err
+			}
+			defer insert.Close()
+
+			for _, pack := range packs {
+				entities := kindEntities(all[pack], kind)
+				for _, key := range sortedKeys(entities) {
+					entity := entities.MapIndex(reflect.ValueOf(key))
+					rows, err := t.rows(entity)
+					if err != nil {
+						return fmt.Errorf("%s/%s/%s: %s", pack, kind, key, err)
+					}
+					for _, row := range rows {
+						if _, err := insert.Exec(append([]interface{}{pack, key}, row...)...); err != nil {
+							return fmt.Errorf("%s/%s/%s in %s: %s", pack, kind, key, t.Name, err)
+						}
+					}
+				}
+			}
+		}
+
+		for _, pack := range packs {
+			entities := kindEntities(all[pack], kind)
+			for _, key := range sortedKeys(entities) {
+				list := entities.MapIndex(reflect.ValueOf(key)).FieldByName("LevelModifiers")
+				if !list.IsValid() {
+					continue
+				}
+				for idx, modifier := range list.Interface().(schema.LevelModifierList) {
+					level, value, ability := modifierValues(modifier)
+					if _, err := modifiers.Exec(kind, pack, key, idx, level, string(modifier.Type()), value, ability); err != nil {
+						return fmt.Errorf("%s/%s/%s in modifiers: %s", pack, kind, key, err)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// create returns the statement that creates a table. Every table has
+// option_pack and key columns, which are the primary key of a kind's table
+// and refer to it from the others.
+func (t *table) create() string {
+	columns := []string{"option_pack TEXT NOT NULL", "key TEXT NOT NULL"}
+	primaryKey := "option_pack, key"
+	switch t.Shape {
+	case setRows:
+		primaryKey += ", value"
+	case mapRows:
+		primaryKey += ", name"
+	case listRows, recordRows:
+		columns = append(columns, "position INTEGER NOT NULL")
+		primaryKey += ", position"
+	}
+	for _, c := range t.Columns {
+		columns = append(columns, c.Name+" "+c.Type)
+	}
+	columns = append(columns, "PRIMARY KEY ("+primaryKey+")")
+	if t.Shape != entityRows {
+		columns = append(columns, "FOREIGN KEY (option_pack, key) REFERENCES "+t.Kind+" (option_pack, key)")
+	}
+	return "CREATE TABLE " + t.Name + " (\n\t" + strings.Join(columns, ",\n\t") + "\n)"
+}
+
+// insert returns the statement that inserts a row into a table
+func (t *table) insert() string {
+	params := 2 + len(t.Columns)
+	if t.Shape == listRows || t.Shape == recordRows {
+		params++
+	}
+	return "INSERT INTO " + t.Name + " VALUES (?" + strings.Repeat(", ?", params-1) + ")"
+}
+
+// rows returns the values of the rows of a table for an entity, other than
+// its option pack and key
+func (t *table) rows(entity reflect.Value) ([][]interface{}, error) {
+	if t.Shape == entityRows {
+		var row []interface{}
+		for _, c := range t.Columns {
+			field := fieldValue(entity, c.Field)
+			if !c.JSON {
+				row = append(row, scalarValue(field))
+				continue
+			}
+			value, err := jsonValue(field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", c.Name, err)
+			}
+			row = append(row, value)
+		}
+		return [][]interface{}{row}, nil
+	}
+
+	field := fieldValue(entity, t.Field)
+	if !field.IsValid() {
+		return nil, nil
+	}
+
+	var rows [][]interface{}
+	switch t.Shape {
+	case setRows:
+		for _, key := range sortedMapKeys(field) {
+			if field.MapIndex(key).Bool() {
+				rows = append(rows, []interface{}{scalarValue(key)})
+			}
+		}
+	case mapRows:
+		for _, key := range sortedMapKeys(field) {
+			rows = append(rows, []interface{}{scalarValue(key), scalarValue(field.MapIndex(key))})
+		}
+	case listRows:
+		for idx := 0; idx < field.Len(); idx++ {
+			rows = append(rows, []interface{}{idx, scalarValue(field.Index(idx))})
+		}
+	case recordRows:
+		for idx := 0; idx < field.Len(); idx++ {
+			row := []interface{}{idx}
+			for _, c := range t.Columns {
+				row = append(row, scalarValue(fieldValue(field.Index(idx), c.Field)))
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// modifierValues returns the level and value of a level modifier, and the
+// spellcasting ability of a spell
+func modifierValues(modifier schema.LevelModifier) (int64, interface{}, interface{}) {
+	m := reflect.ValueOf(modifier).Elem()
+	level := m.FieldByName("Level").Int()
+	if spell, ok := m.FieldByName("Value").Interface().(schema.SpellWithAbility); ok {
+		var ability interface{}
+		if spell.Ability != "" {
+			ability = string(spell.Ability)
+		}
+		return level, spell.Key, ability
+	}
+	return level, scalarValue(m.FieldByName("Value")), nil
+}
+
+// jsonValue returns a field written as JSON, or nil when it's empty
+func jsonValue(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	if text := string(data); text != "null" && text != "{}" && text != "[]" {
+		return text, nil
+	}
+	return nil, nil
+}
+
+// kindEntities returns the map holding the entities of a kind
+func kindEntities(source schema.OrcbrewSource, kind string) reflect.Value {
+	v := reflect.ValueOf(source)
+	for i := 0; i < v.NumField(); i++ {
+		if strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0] == kind {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+func sortedKeys(entities reflect.Value) []string {
+	var keys []string
+	for _, key := range entities.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
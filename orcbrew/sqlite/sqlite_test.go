@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+const exampleFile = "../schema/example.orcbrew"
+
+func writeExample(t *testing.T, filename string) *sql.DB {
+	f, err := orcbrew.ReadFile(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(f.Packs, filename); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// query returns the rows of a query, with each row's columns joined by "|"
+func query(t *testing.T, db *sql.DB, q string) []string {
+	rows, err := db.Query(q)
+	if err != nil {
+		t.Fatalf("%s: %s", q, err)
+	}
+	defer rows.Close()
+
+	columns, _ := rows.Columns()
+	var results []string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for idx := range values {
+			ptrs[idx] = &values[idx]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			t.Fatal(err)
+		}
+		var row []string
+		for _, value := range values {
+			row = append(row, value.String)
+		}
+		results = append(results, strings.Join(row, "|"))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestQueries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := writeExample(t, filepath.Join(dir, "example.db"))
+	defer db.Close()
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{
+			"SELECT option_pack, name, level, ritual, components_verbal, components_material_component FROM spells WHERE school = 'necromancy' AND level < 3",
+			[]string{"Test|MySpell|0|1|1|A pinch of salt"},
+		},
+		{
+			"SELECT count(*) FROM spells_spell_lists WHERE key = 'myspell'",
+			[]string{"8"},
+		},
+		{
+			"SELECT m.name, r.value FROM monsters m JOIN monsters_damage_resistance r USING (option_pack, key)",
+			[]string{"MyMonster|traps"},
+		},
+		{
+			"SELECT name, value FROM monsters_skills ORDER BY name",
+			[]string{"history|4", "performance|2", "stealth|2", "survival|2"},
+		},
+		{
+			"SELECT hit_points_die_count, hit_points_die, challenge FROM monsters",
+			[]string{"1|8|1"},
+		},
+		{
+			"SELECT position, type, name FROM monsters_traits ORDER BY position",
+			[]string{"0|action|Multi-attack", "1|legendary-action|Dragon breath"},
+		},
+		{
+			"SELECT value FROM classes_skill_expertise_options WHERE key = 'myclass'",
+			[]string{"acrobatics", "animal-handling", "arcana"},
+		},
+		{
+			"SELECT level, name FROM classes_traits WHERE key = 'myclass'",
+			[]string{"2|MyClassTrait"},
+		},
+		{
+			"SELECT kind, key, level, type, value, ability FROM modifiers WHERE type IN ('spell', 'saving-throw-advantage') ORDER BY kind, key, position",
+			[]string{"classes|myclass|2|saving-throw-advantage|blinded|", "classes|myclass|0|spell|druidcraft|str"},
+		},
+		{
+			"SELECT position, value_key, value_level FROM races_spells ORDER BY position",
+			[]string{"0|acid-splash|0", "1|aid|2"},
+		},
+		{
+			"SELECT name, creature_num, creature_monster FROM encounters JOIN encounters_creatures USING (option_pack, key)",
+			[]string{"Goblin Ambush|3|goblin"},
+		},
+	}
+	for _, test := range tests {
+		if diff := deep.Equal(query(t, db, test.query), test.expected); diff != nil {
+			t.Errorf("%s: %v", test.query, diff)
+		}
+	}
+}
+
+func TestTables(t *testing.T) {
+	names := make(map[string]bool)
+	for _, kind := range schema.Kinds {
+		for _, table := range kindTables(kind) {
+			if names[table.Name] {
+				t.Errorf("Table %s is defined twice", table.Name)
+			}
+			names[table.Name] = true
+
+			columns := make(map[string]bool)
+			for _, c := range table.Columns {
+				if columns[c.Name] || c.Name == "key" || c.Name == "option_pack" || c.Name == "position" {
+					t.Errorf("Column %s of %s is defined twice", c.Name, table.Name)
+				}
+				columns[c.Name] = true
+			}
+		}
+	}
+
+	for _, name := range []string{"spells", "spells_spell_lists", "monsters_damage_resistance",
+		"classes_skill_options", "races_skill_options", "subclasses_traits", "selections_options"} {
+		if !names[name] {
+			t.Errorf("Expected a table %s", name)
+		}
+	}
+}
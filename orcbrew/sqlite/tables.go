@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// shape is how the rows of a table are found in an entity
+type shape int
+
+const (
+	entityRows shape = iota // a row for each entity
+	setRows                 // a row for each member of a set, e.g. map[Damage]bool
+	mapRows                 // a row for each entry of a map, e.g. map[Ability]int
+	listRows                // a row for each item of a list of values
+	recordRows              // a row for each item of a list of objects, e.g. traits
+)
+
+// table is a table of the database, holding the entities of a kind or the
+// rows of one of their sets, maps or lists
+type table struct {
+	Name    string
+	Kind    string
+	Shape   shape
+	Field   []int // the field holding the rows, for tables other than the kind's
+	Columns []column
+}
+
+// column is a column of a table, apart from the option_pack, key and
+// position columns every table has
+type column struct {
+	Name  string
+	Type  string // the SQL type
+	Field []int  // the field of the entity, or of the item for a record
+	JSON  bool   // the field is written as JSON
+}
+
+// wrapperFields are objects whose fields are named as if they were the
+// entity's own, e.g. a monster's props.damage-resistance is
+// damage_resistance
+var wrapperFields = map[string]bool{"props": true, "profs": true}
+
+// kindTables returns the tables for a kind: its own table, with a column for
+// each of its values, followed by a table for each set, map and list
+func kindTables(kind string) []*table {
+	entity := &table{Name: kind, Kind: kind}
+	tables := []*table{entity}
+
+	var add func(t reflect.Type, field []int, prefix string)
+	add = func(t reflect.Type, field []int, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Name == "Key" || f.Name == "OptionPack" || f.Type == modifierListType {
+				continue
+			}
+
+			name := joinName(prefix, jsonName(f))
+			path := append(append([]int(nil), field...), i)
+			fieldType := f.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+
+			switch {
+			case isScalar(fieldType):
+				entity.Columns = append(entity.Columns, column{Name: name, Type: sqlType(fieldType), Field: path})
+			case fieldType.Kind() == reflect.Struct:
+				if wrapperFields[jsonName(f)] {
+					add(fieldType, path, prefix)
+				} else {
+					add(fieldType, path, name+"_")
+				}
+			case fieldType.Kind() == reflect.Map && isScalar(fieldType.Key()) && fieldType.Elem().Kind() == reflect.Bool:
+				tables = append(tables, &table{Name: kind + "_" + name, Kind: kind, Shape: setRows, Field: path, Columns: []column{
+					{Name: "value", Type: sqlType(fieldType.Key())},
+				}})
+			case fieldType.Kind() == reflect.Map && isScalar(fieldType.Key()) && isScalar(fieldType.Elem()):
+				tables = append(tables, &table{Name: kind + "_" + name, Kind: kind, Shape: mapRows, Field: path, Columns: []column{
+					{Name: "name", Type: sqlType(fieldType.Key())},
+					{Name: "value", Type: sqlType(fieldType.Elem())},
+				}})
+			case fieldType.Kind() == reflect.Slice && isScalar(fieldType.Elem()):
+				tables = append(tables, &table{Name: kind + "_" + name, Kind: kind, Shape: listRows, Field: path, Columns: []column{
+					{Name: "value", Type: sqlType(fieldType.Elem())},
+				}})
+			default:
+				if columns, ok := recordColumns(fieldType); ok {
+					tables = append(tables, &table{Name: kind + "_" + name, Kind: kind, Shape: recordRows, Field: path, Columns: columns})
+				} else {
+					entity.Columns = append(entity.Columns, column{Name: name, Type: "TEXT", Field: path, JSON: true})
+				}
+			}
+		}
+	}
+	add(schema.KindType(kind), nil, "")
+
+	return tables
+}
+
+// recordColumns returns the columns for a list of objects, which are only
+// given a table when their fields are all values
+func recordColumns(t reflect.Type) ([]column, bool) {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	var columns []column
+	ok := true
+	var add func(t reflect.Type, field []int, prefix string)
+	add = func(t reflect.Type, field []int, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			path := append(append([]int(nil), field...), i)
+			switch {
+			case isScalar(f.Type):
+				columns = append(columns, column{Name: joinName(prefix, jsonName(f)), Type: sqlType(f.Type), Field: path})
+			case f.Type.Kind() == reflect.Struct:
+				add(f.Type, path, joinName(prefix, jsonName(f))+"_")
+			default:
+				ok = false
+			}
+		}
+	}
+	add(t.Elem(), nil, "")
+	return columns, ok
+}
+
+var modifierListType = reflect.TypeOf(schema.LevelModifierList{})
+
+// jsonName returns the name of a field as a column, e.g. attack_roll for
+// "attack-roll?"
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	return strings.Replace(strings.TrimSuffix(name, "?"), "-", "_", -1)
+}
+
+// joinName joins the name of a field to the names of the objects holding it,
+// leaving out options when the object is itself a set of options, e.g.
+// skill_options rather than skill_options_options
+func joinName(prefix string, name string) string {
+	if name == "options" && strings.HasSuffix(prefix, "options_") {
+		return strings.TrimSuffix(prefix, "_")
+	}
+	return prefix + name
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func sqlType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "TEXT"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
+	}
+	return "INTEGER"
+}
+
+// fieldValue returns a field of v, or an invalid value when a pointer on
+// the way is nil
+func fieldValue(v reflect.Value, field []int) reflect.Value {
+	for _, idx := range field {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// scalarValue returns a value for a column, nil being NULL
+func scalarValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Float32:
+		// Without the rounding a challenge of 0.1 would be 0.10000000149011612
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(v.Float(), 'g', -1, 32), 64)
+		return f
+	case reflect.Float64:
+		return v.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	}
+	return nil
+}
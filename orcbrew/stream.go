@@ -0,0 +1,397 @@
+package orcbrew
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cespare/goclj/parse"
+)
+
+// RawEntity is an entity read by Stream, as the JSON ToJSON converts it to
+type RawEntity struct {
+	Pack string // the option pack
+	Kind string // e.g. "spells"
+	Key  string
+	JSON string
+}
+
+// MarshalJSON writes the entity as a single object, with pack, kind and key
+// fields followed by the fields of the entity in sorted order
+func (e RawEntity) MarshalJSON() ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(e.JSON), &fields); err != nil {
+		return nil, fmt.Errorf("%s/%s: %s", e.Kind, e.Key, err)
+	}
+	fields["pack"], _ = json.Marshal(e.Pack)
+	fields["kind"], _ = json.Marshal(e.Kind)
+	fields["key"], _ = json.Marshal(e.Key)
+
+	var names []string
+	for name := range fields {
+		if name != "pack" && name != "kind" && name != "key" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for idx, name := range append([]string{"pack", "kind", "key"}, names...) {
+		if idx > 0 {
+			buf.WriteString(",")
+		}
+		nameJSON, _ := json.Marshal(name)
+		buf.Write(nameJSON)
+		buf.WriteString(":")
+		buf.Write(fields[name])
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// Stream reads an .orcbrew file an entity at a time, calling fn with each
+// entity as soon as it has been read, so that only one entity is held in
+// memory however large the file. Reading stops at the first error from fn.
+//
+// The entities of an "Export All" are given the option pack they're filed
+// under. Those of a file with a single source are given the option pack
+// they name, which is empty when they don't name one. The files are told
+// apart as ReadFile does, except that only the keys seen so far are known,
+// so a file is taken to be an "Export All" when its keys are strings, as
+// option pack names are, rather than keywords.
+func Stream(r io.Reader, fn func(e RawEntity) error) error {
+	s := &ednScanner{r: bufio.NewReader(r), line: 1}
+
+	// Skip the BOM if it's at the start of the file
+	if c, _, err := s.r.ReadRune(); err != nil {
+		return fmt.Errorf("Error parsing as Clojure: %s", err)
+	} else if c != '\uFEFF' {
+		s.r.UnreadRune()
+	}
+
+	err := s.mapEntries(func(key string) error {
+		if strings.HasPrefix(key, `"`) {
+			pack := unquoteString(key)
+			return s.mapEntries(func(kind string) error {
+				return s.entities(pack, entryKey(kind), fn)
+			})
+		}
+		return s.entities("", entryKey(key), fn)
+	})
+	if err != nil {
+		return fmt.Errorf("Error parsing as Clojure: line %d: %s", s.line, err)
+	}
+	return nil
+}
+
+// entities reads the map of the entities of a kind
+func (s *ednScanner) entities(pack string, kind string, fn func(e RawEntity) error) error {
+	if isNil, err := s.skipNil(); isNil || err != nil {
+		return err
+	}
+
+	return s.mapEntries(func(key string) error {
+		text, err := s.form()
+		if err != nil || text == "nil" {
+			return err
+		}
+
+		e := RawEntity{Pack: pack, Kind: kind, Key: entryKey(key)}
+		if e.JSON, err = formToJSON(text); err != nil {
+			return fmt.Errorf("%s/%s: %s", kind, e.Key, err)
+		}
+
+		if pack == "" {
+			var entity struct {
+				OptionPack string `json:"option-pack"`
+			}
+			if err := json.Unmarshal([]byte(e.JSON), &entity); err != nil {
+				return fmt.Errorf("%s/%s: %s", kind, e.Key, err)
+			}
+			e.Pack = entity.OptionPack
+		}
+
+		return fn(e)
+	})
+}
+
+// entryKey returns the name of a keyword or string used as a map key, e.g.
+// spells for :orcpub.dnd.e5/spells
+func entryKey(key string) string {
+	if strings.HasPrefix(key, `"`) {
+		return unquoteString(key)
+	}
+	return strings.TrimPrefix(stripNamespaces(key), ":")
+}
+
+// unquoteString returns the value of the text of an EDN string, quotes and
+// all
+func unquoteString(text string) string {
+	return ednUnquote(strings.TrimSuffix(strings.TrimPrefix(text, `"`), `"`))
+}
+
+// formToJSON converts a single form, such as an entity's map, to JSON
+func formToJSON(text string) (string, error) {
+	tree, err := parse.Reader(strings.NewReader(stripNamespaces(text)), "input.clj", 0)
+	if err != nil {
+		return "", err
+	}
+	roots := filterNonValueNodes(tree.Roots)
+	if len(roots) != 1 {
+		return "", fmt.Errorf("Expected a single value, got %d", len(roots))
+	}
+	return nodeToJSON(roots[0]), nil
+}
+
+// ednScanner reads the forms of EDN one at a time, as text
+type ednScanner struct {
+	r    *bufio.Reader
+	line int
+	last rune
+}
+
+func (s *ednScanner) read() (rune, error) {
+	c, _, err := s.r.ReadRune()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if c == '\n' {
+		s.line++
+	}
+	s.last = c
+	return c, err
+}
+
+func (s *ednScanner) unread() {
+	if s.last == '\n' {
+		s.line--
+	}
+	s.r.UnreadRune()
+}
+
+// peek returns the next rune that isn't whitespace, a comma or in a comment,
+// without reading it
+func (s *ednScanner) peek() (rune, error) {
+	for {
+		c, err := s.read()
+		if err != nil {
+			return 0, err
+		}
+
+		if c == ';' {
+			s.unread()
+			if err := s.comment(); err != nil {
+				return 0, err
+			}
+		} else if !unicode.IsSpace(c) && c != ',' {
+			s.unread()
+			return c, nil
+		}
+	}
+}
+
+// skipNil reads a nil if it's next, returning whether it was
+func (s *ednScanner) skipNil() (bool, error) {
+	if c, err := s.peek(); err != nil || c != 'n' {
+		return false, err
+	}
+	text, err := s.form()
+	if err == nil && text != "nil" {
+		err = fmt.Errorf("Expected a map, got %s", text)
+	}
+	return true, err
+}
+
+// mapEntries reads a map, calling fn with the text of each key, which must
+// read the key's value
+func (s *ednScanner) mapEntries(fn func(key string) error) error {
+	c, err := s.peek()
+	if err != nil {
+		return err
+	}
+	if c == '#' {
+		// A map with a namespace, e.g. #:orcpub.dnd.e5{...}
+		s.read()
+		if _, err := s.token(); err != nil {
+			return err
+		}
+	}
+	if c, err = s.read(); err != nil {
+		return err
+	} else if c != '{' {
+		return fmt.Errorf("Expected a map, got %q", c)
+	}
+
+	for {
+		c, err := s.peek()
+		if err != nil {
+			return err
+		}
+		if c == '}' {
+			s.read()
+			return nil
+		}
+
+		key, err := s.form()
+		if err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+}
+
+// form reads the text of the next form, e.g. a keyword, a string or a whole
+// map
+func (s *ednScanner) form() (string, error) {
+	if _, err := s.peek(); err != nil {
+		return "", err
+	}
+
+	c, err := s.read()
+	if err != nil {
+		return "", err
+	}
+
+	switch c {
+	case '"':
+		return s.str()
+	case '(', '[', '{':
+		return s.coll(c)
+	case '\\':
+		// A character, e.g. \a or \newline
+		if c, err = s.read(); err != nil {
+			return "", err
+		}
+		token, err := s.token()
+		return "\\" + string(c) + token, err
+	case '#':
+		if c, err = s.read(); err != nil {
+			return "", err
+		}
+		switch c {
+		case '{', '(':
+			coll, err := s.coll(c)
+			return "#" + coll, err
+		case '_':
+			// A discarded form
+			if _, err := s.form(); err != nil {
+				return "", err
+			}
+			return s.form()
+		}
+
+		s.unread()
+		tag, err := s.token()
+		if err != nil {
+			return "", err
+		}
+		// A namespaced map, e.g. #:orcpub.dnd.e5{...}, or a tagged value
+		if next, err := s.read(); err != nil {
+			return "", err
+		} else if next == '{' {
+			coll, err := s.coll(next)
+			return "#" + tag + coll, err
+		}
+		s.unread()
+		value, err := s.form()
+		return "#" + tag + " " + value, err
+	}
+
+	s.unread()
+	return s.token()
+}
+
+// token reads a keyword, symbol or number
+func (s *ednScanner) token() (string, error) {
+	var buf strings.Builder
+	for {
+		c, _, err := s.r.ReadRune()
+		if err == io.EOF {
+			return buf.String(), nil
+		} else if err != nil {
+			return "", err
+		}
+		if unicode.IsSpace(c) || strings.ContainsRune(`,;"()[]{}`, c) {
+			s.r.UnreadRune()
+			return buf.String(), nil
+		}
+		buf.WriteRune(c)
+	}
+}
+
+// str reads the rest of a string, after its opening quote
+func (s *ednScanner) str() (string, error) {
+	var buf strings.Builder
+	buf.WriteRune('"')
+	for {
+		c, err := s.read()
+		if err != nil {
+			return "", err
+		}
+		buf.WriteRune(c)
+		if c == '\\' {
+			if c, err = s.read(); err != nil {
+				return "", err
+			}
+			buf.WriteRune(c)
+		} else if c == '"' {
+			return buf.String(), nil
+		}
+	}
+}
+
+// comment reads a comment up to the end of its line
+func (s *ednScanner) comment() error {
+	for {
+		c, err := s.read()
+		if err != nil || c == '\n' {
+			return err
+		}
+	}
+}
+
+// coll reads the rest of a collection, after the rune that opens it
+func (s *ednScanner) coll(open rune) (string, error) {
+	var buf strings.Builder
+	buf.WriteRune(open)
+	for depth := 1; depth > 0; {
+		c, err := s.read()
+		if err != nil {
+			return "", err
+		}
+
+		switch c {
+		case '"':
+			str, err := s.str()
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(str)
+			continue
+		case ';':
+			if err := s.comment(); err != nil {
+				return "", err
+			}
+			c = '\n'
+		case '\\':
+			buf.WriteRune(c)
+			if c, err = s.read(); err != nil {
+				return "", err
+			}
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		}
+		buf.WriteRune(c)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,99 @@
+package orcbrew
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestStream(t *testing.T) {
+	file, err := os.Open(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	expectedBytes, err := ioutil.ReadFile("schema/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected map[string]map[string]interface{}
+	if err := json.Unmarshal(expectedBytes, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	err = Stream(file, func(e RawEntity) error {
+		count++
+		if e.Pack != "Test" {
+			t.Errorf("Expected %s/%s in option pack Test, got %q", e.Kind, e.Key, e.Pack)
+		}
+
+		var entity interface{}
+		if err := json.Unmarshal([]byte(e.JSON), &entity); err != nil {
+			t.Fatal(err)
+		}
+		if diff := deep.Equal(entity, expected[e.Kind][e.Key]); diff != nil {
+			t.Errorf("%s/%s: %v", e.Kind, e.Key, diff)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for _, entities := range expected {
+		total += len(entities)
+	}
+	if count != total {
+		t.Errorf("Expected %d entities, got %d", total, count)
+	}
+}
+
+func TestStreamExportAll(t *testing.T) {
+	contents := `{"Pack A" {:orcpub.dnd.e5/spells
+  {:fire-bolt {:key :fire-bolt, :name "Fire \"Bolt\"", :level 0 ; a comment }
+    :spell-lists #{:wizard}}}
+  :orcpub.dnd.e5/feats nil}
+ "My \"Pack\"" #:orcpub.dnd.e5{:monsters {"goblin" {:key :goblin, :name "Goblin"}
+  :nothing nil}}}`
+
+	var lines []string
+	err := Stream(strings.NewReader(contents), func(e RawEntity) error {
+		line, err := json.Marshal(e)
+		lines = append(lines, string(line))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`{"pack":"Pack A","kind":"spells","key":"fire-bolt","level":0,"name":"Fire \"Bolt\"","spell-lists":["wizard"]}`,
+		`{"pack":"My \"Pack\"","kind":"monsters","key":"goblin","name":"Goblin"}`,
+	}
+	if diff := deep.Equal(lines, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	contents := "{:spells\n {:fire-bolt {:key :fire-bolt}\n  :ray"
+
+	count := 0
+	err := Stream(strings.NewReader(contents), func(e RawEntity) error {
+		count++
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected an error on line 3, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected the entity before the error, got %d", count)
+	}
+}
@@ -0,0 +1,448 @@
+package orcbrew
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cespare/goclj/parse"
+)
+
+// typedTags are the keys of the objects typed JSON uses for tagged values
+var typedTags = map[string]bool{
+	"~num": true, "~kw": true, "~sym": true, "~list": true, "~set": true, "~map": true, "~edn": true,
+}
+
+// ToTypedJSON converts the EDN contents of an .orcbrew file into typed JSON,
+// which FromTypedJSON converts back.
+//
+// Typed JSON is a JSON encoding of EDN that keeps the types JSON doesn't
+// have, so that it can be converted back into the same EDN values. Values
+// are written as:
+//
+//	nil, true, false   null, true, false
+//	"string"           "string"
+//	42, -1.5, 2e10     42, -1.5, 2e10 (as written)
+//	1/2, 10N, 1.5M     {"~num": "1/2"}
+//	:ns/keyword        {"~kw": "ns/keyword"}
+//	symbol             {"~sym": "symbol"}
+//	[1 2]              [1, 2]
+//	(1 2)              {"~list": [1, 2]}
+//	#{1 2}             {"~set": [1, 2]}
+//	{:a 1, "b" 2}      {":a": 1, "b": 2}
+//	{1 :a, [2] :b}     {"~map": [[1, {"~kw": "a"}], [[2], {"~kw": "b"}]]}
+//	anything else      {"~edn": "\\c"}
+//
+// A map is an object when each key is a keyword, written with its colon, a
+// string, which is written as is unless it starts with : or ~, or another
+// value that isn't a collection, written as ~ followed by its EDN, e.g.
+// "~nil", "~1" or "~\":not-a-keyword\"". Other maps, and maps whose first key
+// would look like one of the tags above, are written with ~map as a list of
+// key and value pairs. An object whose first key is one of the tags is
+// always a tagged value.
+//
+// Nothing else is changed: keywords keep their namespaces, map entries
+// whose value is nil are kept, and entries, sets and lists keep their
+// order. The keys of a map written with a namespace, e.g. #:orcpub.dnd.e5{},
+// are given the namespace.
+func ToTypedJSON(r io.Reader) (string, error) {
+	contentsBytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	contentsBytes = bytes.TrimLeft(contentsBytes, "\xef\xbb\xbf")
+
+	tt, err := parse.Reader(bytes.NewReader(contentsBytes), "input.clj", 0)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing as Clojure: %s", err)
+	}
+
+	roots := filterNonValueNodes(tt.Roots)
+	if len(roots) != 1 {
+		return "", fmt.Errorf("Expected a single value, got %d", len(roots))
+	}
+	var buf bytes.Buffer
+	if err := writeTyped(&buf, roots[0], ""); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// writeTyped writes a node as typed JSON, with namespace given to the
+// keywords without one when it's a key of a map with a namespace
+func writeTyped(buf *bytes.Buffer, node parse.Node, namespace string) error {
+	switch v := node.(type) {
+	case *parse.NilNode:
+		buf.WriteString("null")
+	case *parse.BoolNode:
+		buf.WriteString(strconv.FormatBool(v.Val))
+	case *parse.StringNode:
+		buf.WriteString(jsonString(ednUnquote(v.Val)))
+	case *parse.NumberNode:
+		if jsonNumber.MatchString(v.Val) {
+			buf.WriteString(v.Val)
+		} else {
+			writeTag(buf, "~num", jsonString(v.Val))
+		}
+	case *parse.KeywordNode:
+		writeTag(buf, "~kw", jsonString(keywordName(v.Val, namespace)))
+	case *parse.SymbolNode:
+		writeTag(buf, "~sym", jsonString(v.Val))
+	case *parse.VectorNode:
+		return writeTypedList(buf, filterNonValueNodes(v.Children()))
+	case *parse.ListNode:
+		return writeTypedTag(buf, "~list", func() error { return writeTypedList(buf, filterNonValueNodes(v.Children())) })
+	case *parse.SetNode:
+		return writeTypedTag(buf, "~set", func() error { return writeTypedList(buf, filterNonValueNodes(v.Children())) })
+	case *parse.MapNode:
+		return writeTypedMap(buf, v)
+	default:
+		writeTag(buf, "~edn", jsonString(node.String()))
+	}
+	return nil
+}
+
+func writeTag(buf *bytes.Buffer, tag string, value string) {
+	buf.WriteString(`{"` + tag + `": ` + value + `}`)
+}
+
+func writeTypedTag(buf *bytes.Buffer, tag string, value func() error) error {
+	buf.WriteString(`{"` + tag + `": `)
+	if err := value(); err != nil {
+		return err
+	}
+	buf.WriteString("}")
+	return nil
+}
+
+func writeTypedList(buf *bytes.Buffer, nodes []parse.Node) error {
+	buf.WriteString("[")
+	for idx, node := range nodes {
+		if idx > 0 {
+			buf.WriteString(", ")
+		}
+		if err := writeTyped(buf, node, ""); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("]")
+	return nil
+}
+
+func writeTypedMap(buf *bytes.Buffer, m *parse.MapNode) error {
+	children := filterNonValueNodes(m.Children())
+	if len(children)%2 != 0 {
+		return fmt.Errorf("Map with an odd number of forms at %v", m.Position())
+	}
+	namespace := strings.TrimPrefix(m.Namespace, ":")
+
+	// Use an object when every key can be written as a string
+	var keys []string
+	for idx := 0; idx < len(children); idx += 2 {
+		key, ok := typedKey(children[idx], namespace)
+		if !ok {
+			keys = nil
+			break
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 && typedTags[keys[0]] {
+		keys = nil
+	}
+
+	if keys != nil || len(children) == 0 {
+		buf.WriteString("{")
+		for idx, key := range keys {
+			if idx > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(jsonString(key) + ": ")
+			if err := writeTyped(buf, children[idx*2+1], ""); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}")
+		return nil
+	}
+
+	return writeTypedTag(buf, "~map", func() error {
+		buf.WriteString("[")
+		for idx := 0; idx < len(children); idx += 2 {
+			if idx > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString("[")
+			if err := writeTyped(buf, children[idx], namespace); err != nil {
+				return err
+			}
+			buf.WriteString(", ")
+			if err := writeTyped(buf, children[idx+1], ""); err != nil {
+				return err
+			}
+			buf.WriteString("]")
+		}
+		buf.WriteString("]")
+		return nil
+	})
+}
+
+// typedKey returns a map key as the key of an object, if it can be
+func typedKey(node parse.Node, namespace string) (string, bool) {
+	switch v := node.(type) {
+	case *parse.KeywordNode:
+		return ":" + keywordName(v.Val, namespace), true
+	case *parse.StringNode:
+		s := ednUnquote(v.Val)
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "~") {
+			return "~" + quote(s), true
+		}
+		return s, true
+	case *parse.NilNode, *parse.BoolNode, *parse.NumberNode, *parse.SymbolNode:
+		return "~" + node.String(), true
+	}
+	return "", false
+}
+
+// keywordName returns the name of a keyword without its colon, giving it
+// namespace when it has none
+func keywordName(keyword string, namespace string) string {
+	name := strings.TrimPrefix(keyword, ":")
+	if namespace == "" || strings.Contains(name, "/") {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// jsonString returns s as a JSON string, leaving HTML characters alone
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// ednUnquote returns the value of the contents of an EDN string
+func ednUnquote(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var buf strings.Builder
+	for idx := 0; idx < len(s); idx++ {
+		if s[idx] != '\\' || idx+1 == len(s) {
+			buf.WriteByte(s[idx])
+			continue
+		}
+
+		idx++
+		switch s[idx] {
+		case 'n':
+			buf.WriteByte('\n')
+		case 't':
+			buf.WriteByte('\t')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'b':
+			buf.WriteByte('\b')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'u':
+			if idx+5 <= len(s) {
+				if r, err := strconv.ParseUint(s[idx+1:idx+5], 16, 32); err == nil {
+					buf.WriteRune(rune(r))
+					idx += 4
+					continue
+				}
+			}
+			buf.WriteByte(s[idx])
+		default:
+			buf.WriteByte(s[idx])
+		}
+	}
+	return buf.String()
+}
+
+// FromTypedJSON converts typed JSON, as written by ToTypedJSON, back into
+// EDN. Maps are written with an entry on each line.
+func FromTypedJSON(r io.Reader) (string, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	if err := readTyped(dec, &buf); err != nil {
+		return "", fmt.Errorf("Error reading typed JSON: %s", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return "", fmt.Errorf("Error reading typed JSON: expected a single value")
+	}
+	buf.WriteString("\n")
+	return buf.String(), nil
+}
+
+// readTyped reads a typed JSON value and writes it as EDN
+func readTyped(dec *json.Decoder, buf *bytes.Buffer) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch v := token.(type) {
+	case nil:
+		buf.WriteString("nil")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		buf.WriteString(v.String())
+	case string:
+		buf.WriteString(quote(v))
+	case json.Delim:
+		if v == '[' {
+			return readTypedList(dec, buf, "[", "]")
+		}
+		return readTypedObject(dec, buf)
+	}
+	return nil
+}
+
+// readTypedList reads the rest of an array, after its [
+func readTypedList(dec *json.Decoder, buf *bytes.Buffer, open string, close string) error {
+	buf.WriteString(open)
+	for idx := 0; dec.More(); idx++ {
+		if idx > 0 {
+			buf.WriteString(" ")
+		}
+		if err := readTyped(dec, buf); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	buf.WriteString(close)
+	return nil
+}
+
+// readTypedObject reads the rest of an object, after its {
+func readTypedObject(dec *json.Decoder, buf *bytes.Buffer) error {
+	start := buf.Len()
+	separator := entrySeparator(buf)
+	buf.WriteString("{")
+	for idx := 0; dec.More(); idx++ {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+
+		if idx == 0 && typedTags[key] {
+			buf.Truncate(start)
+			if err := readTag(dec, buf, key); err != nil {
+				return err
+			}
+			if dec.More() {
+				return fmt.Errorf("Expected only %s in its object", key)
+			}
+			_, err := dec.Token()
+			return err
+		}
+
+		if idx > 0 {
+			buf.WriteString(separator)
+		}
+		switch {
+		case strings.HasPrefix(key, ":"):
+			buf.WriteString(key)
+		case strings.HasPrefix(key, "~"):
+			buf.WriteString(key[1:])
+		default:
+			buf.WriteString(quote(key))
+		}
+		buf.WriteString(" ")
+		if err := readTyped(dec, buf); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	buf.WriteString("}")
+	return nil
+}
+
+// entrySeparator returns the separator between the entries of a map about
+// to be written to buf, which puts each entry on a line of its own
+func entrySeparator(buf *bytes.Buffer) string {
+	column := utf8.RuneCount(buf.Bytes()[lineStart(buf):])
+	return "\n" + strings.Repeat(" ", column+1)
+}
+
+// readTag reads the value of a tagged value and writes it as EDN
+func readTag(dec *json.Decoder, buf *bytes.Buffer, tag string) error {
+	switch tag {
+	case "~list", "~set", "~map":
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if token != json.Delim('[') {
+			return fmt.Errorf("Expected an array for %s, got %v", tag, token)
+		}
+		switch tag {
+		case "~list":
+			return readTypedList(dec, buf, "(", ")")
+		case "~set":
+			return readTypedList(dec, buf, "#{", "}")
+		}
+
+		separator := entrySeparator(buf)
+		buf.WriteString("{")
+		for idx := 0; dec.More(); idx++ {
+			if idx > 0 {
+				buf.WriteString(separator)
+			}
+			if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+				return fmt.Errorf("Expected a key and value pair for ~map")
+			}
+			if err := readTyped(dec, buf); err != nil {
+				return err
+			}
+			buf.WriteString(" ")
+			if err := readTyped(dec, buf); err != nil {
+				return err
+			}
+			if token, err := dec.Token(); err != nil || token != json.Delim(']') {
+				return fmt.Errorf("Expected a key and value pair for ~map")
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		buf.WriteString("}")
+		return nil
+	}
+
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	s, ok := token.(string)
+	if !ok {
+		return fmt.Errorf("Expected a string for %s, got %v", tag, token)
+	}
+	switch tag {
+	case "~kw":
+		buf.WriteString(":" + s)
+	default:
+		buf.WriteString(s)
+	}
+	return nil
+}
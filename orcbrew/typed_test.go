@@ -0,0 +1,95 @@
+package orcbrew
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestTypedJSON(t *testing.T) {
+	file, err := os.Open(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	typed, err := ToTypedJSON(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(typed), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	classes, ok := parsed[":orcpub.dnd.e5/classes"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected :orcpub.dnd.e5/classes, got keys %v", parsed)
+	}
+	myclass := classes[":myclass"].(map[string]interface{})
+	if v, ok := myclass["~nil"]; !ok || v != nil {
+		t.Errorf("Expected the nil entry of myclass to be kept, got %v", myclass)
+	}
+	if diff := deep.Equal(myclass[":key"], map[string]interface{}{"~kw": "myclass"}); diff != nil {
+		t.Error(diff)
+	}
+
+	edn, err := FromTypedJSON(strings.NewReader(typed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ToTypedJSON(strings.NewReader(edn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != typed {
+		t.Errorf("Expected the same typed JSON after a round trip, got:\n%s", again)
+	}
+}
+
+func TestTypedJSONValues(t *testing.T) {
+	tests := []struct {
+		edn   string
+		typed string
+	}{
+		{`nil`, `null`},
+		{`[1 -1.5 2e10 1/2 10N 1.5M]`, `[1, -1.5, 2e10, {"~num": "1/2"}, {"~num": "10N"}, {"~num": "1.5M"}]`},
+		{`"a \"quoted\" <b>\n"`, `"a \"quoted\" <b>\n"`},
+		{`[:a :ns/b sym true]`, `[{"~kw": "a"}, {"~kw": "ns/b"}, {"~sym": "sym"}, true]`},
+		{`(1 #{:a})`, `{"~list": [1, {"~set": [{"~kw": "a"}]}]}`},
+		{`{:a 1, "b" 2, ":c" 3, nil 4, 5 6, sym 7}`, `{":a": 1, "b": 2, "~\":c\"": 3, "~nil": 4, "~5": 6, "~sym": 7}`},
+		{`{[1] 2}`, `{"~map": [[[1], 2]]}`},
+		{`{kw :a, :b 1}`, `{"~map": [[{"~sym": "kw"}, {"~kw": "a"}], [{"~kw": "b"}, 1]]}`},
+		{`{}`, `{}`},
+	}
+	for _, test := range tests {
+		typed, err := ToTypedJSON(strings.NewReader(test.edn))
+		if err != nil {
+			t.Errorf("%s: %s", test.edn, err)
+			continue
+		}
+		if typed != test.typed {
+			t.Errorf("%s: expected %s, got %s", test.edn, test.typed, typed)
+		}
+
+		edn, err := FromTypedJSON(strings.NewReader(typed))
+		if err != nil {
+			t.Errorf("%s: %s", typed, err)
+			continue
+		}
+		if again, err := ToTypedJSON(strings.NewReader(edn)); err != nil || again != typed {
+			t.Errorf("%s: expected %s after a round trip through %s, got %s (%v)", test.edn, typed, edn, again, err)
+		}
+	}
+}
+
+func TestFromTypedJSONError(t *testing.T) {
+	for _, typed := range []string{`{"~kw": 1}`, `{"~set": {}}`, `{"~map": [[1]]}`, `[1] [2]`, `{"~kw": "a", "b": 1}`} {
+		if edn, err := FromTypedJSON(strings.NewReader(typed)); err == nil {
+			t.Errorf("%s: expected an error, got %s", typed, edn)
+		}
+	}
+}
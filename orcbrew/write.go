@@ -0,0 +1,402 @@
+package orcbrew
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+// The JSON produced by the schema package doesn't say whether a string was a
+// keyword or a string in the original EDN, or whether an array was a set or a
+// vector, so the writer decides based on the field the value belongs to.
+var (
+	// Fields whose values are strings rather than keywords
+	textFields = map[string]bool{
+		"alignment":          true,
+		"casting-time":       true,
+		"description":        true,
+		"duration":           true,
+		"languages":          true,
+		"material-component": true,
+		"name":               true,
+		"option-pack":        true,
+		"range":              true,
+		"school":             true,
+		"speed":              true,
+		"subclass-title":     true,
+	}
+
+	// Fields whose values are sets rather than vectors
+	setFields = map[string]bool{
+		"ability-increases": true,
+		"languages":         true,
+		"prereqs":           true,
+	}
+
+	// Fields whose values are maps keyed by numbers (e.g. levels)
+	numberKeyedFields = map[string]bool{
+		"spell-list":   true,
+		"spells-known": true,
+	}
+
+	// Fields whose values are maps keyed by abilities
+	abilityKeyedFields = map[string]bool{
+		"abilities": true,
+		"save":      true,
+	}
+
+	// Fields whose values may contain abilities, alongside other keywords
+	abilityFields = map[string]bool{
+		"ability":           true,
+		"ability-increases": true,
+		"prereqs":           true,
+	}
+)
+
+// Write encodes the file as EDN, in the same layout as OrcPub exports
+func (f *File) Write(w io.Writer) error {
+	return f.write(w, nil)
+}
+
+// WriteConflicts encodes the file like Write, except that each entity in
+// conflicts is written twice, with our and their side of the conflict
+// between git-style conflict markers
+func (f *File) WriteConflicts(w io.Writer, conflicts []schema.Merge3Conflict) error {
+	return f.write(w, conflicts)
+}
+
+func (f *File) write(w io.Writer, conflicts []schema.Merge3Conflict) error {
+	var buf bytes.Buffer
+
+	packConflicts := make(map[string][]schema.Merge3Conflict)
+	for _, conflict := range conflicts {
+		packConflicts[conflict.Pack] = append(packConflicts[conflict.Pack], conflict)
+	}
+
+	if f.ExportAll {
+		var packs []string
+		for pack := range f.Packs {
+			packs = append(packs, pack)
+		}
+		for pack := range packConflicts {
+			if _, ok := f.Packs[pack]; !ok {
+				packs = append(packs, pack)
+			}
+		}
+		sort.Strings(packs)
+
+		buf.WriteString("{")
+		for idx, pack := range packs {
+			if idx > 0 {
+				buf.WriteString("\n ")
+			}
+			buf.WriteString(quote(pack))
+			buf.WriteString("\n ")
+			err := writeSource(&buf, f.Packs[pack], 1, packConflicts[pack])
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}\n")
+	} else {
+		source, err := f.Source()
+		if err != nil {
+			return err
+		}
+		err = writeSource(&buf, source, 0, conflicts)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteFile writes the file as EDN to the given filename, see Write
+func (f *File) WriteFile(filename string) error {
+	var buf bytes.Buffer
+	err := f.Write(&buf)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// WriteSource encodes a single source as EDN
+func WriteSource(w io.Writer, source schema.OrcbrewSource) error {
+	f := &File{Packs: schema.OrcbrewExportAll{"": source}}
+	return f.Write(w)
+}
+
+func writeSource(buf *bytes.Buffer, source schema.OrcbrewSource, indent int, conflicts []schema.Merge3Conflict) error {
+	decoded, err := decode(source)
+	if err != nil {
+		return err
+	}
+	value := decoded.(map[string]interface{})
+
+	e := &encoder{buf: buf, conflicts: make(map[string][2]interface{})}
+	for _, conflict := range conflicts {
+		var sides [2]interface{}
+		for idx, side := range []interface{}{conflict.Ours, conflict.Theirs} {
+			sides[idx], err = decode(side)
+			if err != nil {
+				return err
+			}
+		}
+		e.conflicts[conflict.Kind+"/"+conflict.Key] = sides
+
+		if value[conflict.Kind] == nil {
+			value[conflict.Kind] = map[string]interface{}{}
+		}
+	}
+
+	e.writeMap(value, nil, indent)
+	return nil
+}
+
+// decode converts a value to the JSON representation used by the encoder
+func decode(v interface{}) (interface{}, error) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+
+	var value interface{}
+	err = decoder.Decode(&value)
+	return value, err
+}
+
+type encoder struct {
+	buf *bytes.Buffer
+
+	// Our and their side of conflicting entities, by kind/key
+	conflicts map[string][2]interface{}
+}
+
+// conflicted returns whether the value at path is, or contains, a
+// conflicting entity, which can't be written inline
+func (e *encoder) conflicted(path []string) bool {
+	switch len(path) {
+	case 1:
+		for name := range e.conflicts {
+			if strings.HasPrefix(name, path[0]+"/") {
+				return true
+			}
+		}
+	case 2:
+		_, ok := e.conflicts[path[0]+"/"+path[1]]
+		return ok
+	}
+	return false
+}
+
+// lineWidth is the width within which collections of scalars are written on
+// a single line
+const lineWidth = 80
+
+// inline returns the value written on a single line
+func (e *encoder) inline(value interface{}, path []string) string {
+	inline := &encoder{buf: &bytes.Buffer{}}
+	inline.writeValue(value, path, -1)
+	return inline.buf.String()
+}
+
+// writeValue writes a value found at the given path of JSON keys
+func (e *encoder) writeValue(value interface{}, path []string, indent int) {
+	field := ""
+	if len(path) > 0 {
+		field = path[len(path)-1]
+	}
+
+	if indent >= 0 && !e.conflicted(path) {
+		if inline := e.inline(value, path); indent+len(inline) <= lineWidth {
+			e.buf.WriteString(inline)
+			return
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		e.writeMap(v, path, indent)
+	case []interface{}:
+		open, close := "[", "]"
+		if setFields[field] || (len(path) > 1 && path[len(path)-2] == "spell-list") {
+			open, close = "#{", "}"
+		}
+
+		e.buf.WriteString(open)
+		for idx, elem := range v {
+			if idx > 0 {
+				if indent < 0 {
+					e.buf.WriteString(" ")
+				} else {
+					e.buf.WriteString("\n" + strings.Repeat(" ", indent+len(open)))
+				}
+			}
+			if indent < 0 {
+				e.writeValue(elem, path, indent)
+			} else {
+				e.writeValue(elem, path, indent+len(open))
+			}
+		}
+		e.buf.WriteString(close)
+	case string:
+		switch {
+		case textFields[field]:
+			e.buf.WriteString(quote(v))
+		case abilityFields[field] && isAbility(v):
+			e.buf.WriteString(keyword(NamespaceCharacter, v))
+		default:
+			e.buf.WriteString(keyword("", v))
+		}
+	case json.Number:
+		e.buf.WriteString(v.String())
+	case bool:
+		e.buf.WriteString(strconv.FormatBool(v))
+	case nil:
+		e.buf.WriteString("nil")
+	}
+}
+
+func (e *encoder) writeMap(m map[string]interface{}, path []string, indent int) {
+	field := ""
+	if len(path) > 0 {
+		field = path[len(path)-1]
+	}
+
+	var keys []string
+	for key, value := range m {
+		if value != nil && (len(path) != 1 || !e.conflicted([]string{path[0], key})) {
+			keys = append(keys, key)
+		}
+	}
+	if len(path) == 1 {
+		for name := range e.conflicts {
+			if strings.HasPrefix(name, path[0]+"/") {
+				keys = append(keys, strings.TrimPrefix(name, path[0]+"/"))
+			}
+		}
+	}
+	sortKeys(keys, numberKeyedFields[field])
+
+	e.buf.WriteString("{")
+	for idx, key := range keys {
+		if idx > 0 {
+			e.buf.WriteString(separator(indent))
+		}
+
+		switch {
+		case path == nil:
+			e.buf.WriteString(keyword(NamespaceE5, key))
+		case numberKeyedFields[field]:
+			e.buf.WriteString(key)
+		case abilityKeyedFields[field]:
+			e.buf.WriteString(keyword(NamespaceCharacter, key))
+		default:
+			e.buf.WriteString(keyword("", key))
+		}
+
+		value := m[key]
+		valuePath := append(path[:len(path):len(path)], key)
+
+		if sides, ok := e.conflicts[strings.Join(valuePath, "/")]; ok && len(valuePath) == 2 {
+			e.writeConflict(sides, valuePath, indent+1)
+			if idx == len(keys)-1 {
+				e.buf.WriteString("\n" + strings.Repeat(" ", indent))
+			}
+			continue
+		}
+
+		// Values are written after their key when they fit on the line
+		if indent < 0 || (!e.conflicted(valuePath) && e.buf.Len()-lineStart(e.buf)+1+len(e.inline(value, valuePath)) <= lineWidth) {
+			e.buf.WriteString(" ")
+			e.writeValue(value, valuePath, -1)
+			continue
+		}
+
+		e.buf.WriteString("\n" + strings.Repeat(" ", indent+1))
+		e.writeValue(value, valuePath, indent+1)
+	}
+	e.buf.WriteString("}")
+}
+
+// writeConflict writes both sides of a conflicting entity between conflict
+// markers, with nil standing for a side that deleted the entity
+func (e *encoder) writeConflict(sides [2]interface{}, path []string, indent int) {
+	side := &encoder{buf: e.buf}
+	for idx, marker := range []string{"<<<<<<< ours", "=======", ">>>>>>> theirs"} {
+		e.buf.WriteString("\n" + marker)
+		if idx < len(sides) {
+			e.buf.WriteString("\n" + strings.Repeat(" ", indent))
+			side.writeValue(sides[idx], path, indent)
+		}
+	}
+}
+
+// lineStart returns the offset of the start of the last line in buf
+func lineStart(buf *bytes.Buffer) int {
+	return bytes.LastIndexByte(buf.Bytes(), '\n') + 1
+}
+
+// separator returns the separator between the entries of a map starting at
+// indent, which are written on separate lines unless writing inline
+func separator(indent int) string {
+	if indent < 0 {
+		return ", "
+	}
+	return "\n" + strings.Repeat(" ", indent+1)
+}
+
+func isAbility(value string) bool {
+	for _, ability := range schema.Ability("").Values() {
+		if ability == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sortKeys sorts map keys, numerically when they are numbers
+func sortKeys(keys []string, numeric bool) {
+	sort.Slice(keys, func(i, j int) bool {
+		if numeric {
+			a, errA := strconv.Atoi(keys[i])
+			b, errB := strconv.Atoi(keys[j])
+			if errA == nil && errB == nil {
+				return a < b
+			}
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+// keyword returns the EDN keyword for name, falling back to a string when
+// name can't be represented as a keyword
+func keyword(namespace string, name string) string {
+	if name == "" || strings.ContainsAny(name, " \t\n\r,;\"'`~^@#()[]{}\\") {
+		return quote(name)
+	}
+
+	if namespace != "" {
+		return ":" + namespace + "/" + name
+	}
+	return ":" + name
+}
+
+func quote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(s) + `"`
+}
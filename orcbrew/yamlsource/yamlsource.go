@@ -0,0 +1,801 @@
+// Package yamlsource reads and writes option packs as trees of YAML files,
+// a format meant for writing content by hand. Entities are written like the
+// JSON produced by orcbrew2json, with a few short forms:
+//
+// Sets may be written as lists, so that
+//
+//	skills: [acrobatics, arcana]
+//
+// means the same as {acrobatics: true, arcana: true}. Level modifiers may be
+// written as their type followed by their value and level, as in
+//
+//	level-modifiers:
+//	  - skill-prof: athletics @3
+//	  - spell: {key: druidcraft, ability: wis}
+//	    level: 2
+//
+// Multi-line text can use YAML block scalars, and comments are allowed
+// anywhere.
+//
+// A source directory holds one directory per option pack, or is itself the
+// only option pack. An option pack directory holds an optional pack.yaml
+// with the name of the pack (by default the name of the directory), along
+// with entities either as one file per entity in a directory named after
+// their kind (spells/fireball.yaml) or as a list of entities in a file named
+// after their kind (spells.yaml).
+package yamlsource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// PackFile is the file holding the settings of an option pack
+const PackFile = "pack.yaml"
+
+// Layout chooses how Unbuild writes the entities of an option pack
+type Layout int
+
+// Symbolic constants for layouts
+const (
+	PerEntity Layout = iota // one file per entity, e.g. spells/fireball.yaml
+	PerKind                 // one file per kind, e.g. spells.yaml
+)
+
+// packSettings is the contents of pack.yaml
+type packSettings struct {
+	Name string `yaml:"name"`
+}
+
+var levelModifierListType = reflect.TypeOf(schema.LevelModifierList{})
+
+// Build reads the option packs in a source directory
+func Build(dir string) (schema.OrcbrewExportAll, error) {
+	all := make(schema.OrcbrewExportAll)
+
+	isPack, err := isPackDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if isPack {
+		return all, readPack(all, dir)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			if err := readPack(all, filepath.Join(dir, entry.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(all) == 0 {
+		return nil, fmt.Errorf("No option packs found in %s", dir)
+	}
+	return all, nil
+}
+
+// isPackDir returns whether a directory holds an option pack, rather than
+// directories of option packs
+func isPackDir(dir string) (bool, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if entry.Name() == PackFile || schema.IsKind(name) && (entry.IsDir() || isYAML(entry.Name())) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isYAML(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func readPack(all schema.OrcbrewExportAll, dir string) error {
+	settings := packSettings{Name: filepath.Base(dir)}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, PackFile)); err == nil {
+		if err := yaml.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("%s: %s", filepath.Join(dir, PackFile), err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if _, ok := all[settings.Name]; ok {
+		return fmt.Errorf("%s: option pack %s is defined more than once", dir, settings.Name)
+	}
+	source := schema.OrcbrewSource{}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		kind := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+
+		switch {
+		case strings.HasPrefix(entry.Name(), ".") || entry.Name() == PackFile:
+			continue
+		case entry.IsDir() && schema.IsKind(kind):
+			files, err := ioutil.ReadDir(path)
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				if file.IsDir() || !isYAML(file.Name()) {
+					continue
+				}
+				filename := filepath.Join(path, file.Name())
+				key := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+				if err := readEntities(&source, settings.Name, kind, filename, key); err != nil {
+					return err
+				}
+			}
+		case !entry.IsDir() && isYAML(entry.Name()):
+			if !schema.IsKind(kind) {
+				return fmt.Errorf("%s: %s isn't a kind of entity", path, kind)
+			}
+			if err := readEntities(&source, settings.Name, kind, path, ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	all[settings.Name] = source
+	return nil
+}
+
+// readEntities reads a file holding a single entity, when key is the default
+// key for the entity, or a list of entities
+func readEntities(source *schema.OrcbrewSource, pack string, kind string, filename string, key string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	nodes := []*yaml.Node{doc.Content[0]}
+	if key == "" {
+		if doc.Content[0].Kind != yaml.SequenceNode {
+			return fmt.Errorf("%s:%d: expected a list of %s", filename, doc.Content[0].Line, kind)
+		}
+		nodes = doc.Content[0].Content
+	}
+
+	field, _ := fieldByJSONName(reflect.TypeOf(*source), kind)
+	entities := reflect.ValueOf(source).Elem().FieldByIndex(field.Index)
+	for _, node := range nodes {
+		entity, err := decodeEntity(node, kind)
+		if err != nil {
+			return fmt.Errorf("%s:%s", filename, err)
+		}
+
+		v := reflect.ValueOf(entity).Elem()
+		if v.FieldByName("Key").String() == "" {
+			if key == "" {
+				return fmt.Errorf("%s:%d: the entity has no key", filename, node.Line)
+			}
+			v.FieldByName("Key").SetString(key)
+		}
+		if v.FieldByName("OptionPack").String() == "" {
+			v.FieldByName("OptionPack").SetString(pack)
+		}
+
+		entityKey := reflect.ValueOf(v.FieldByName("Key").String())
+		if entities.IsNil() {
+			entities.Set(reflect.MakeMap(entities.Type()))
+		}
+		if entities.MapIndex(entityKey).IsValid() {
+			return fmt.Errorf("%s:%d: %s/%s is defined more than once", filename, node.Line, kind, entityKey)
+		}
+		entities.SetMapIndex(entityKey, v)
+	}
+
+	return nil
+}
+
+// UnmarshalEntity decodes a single entity of the given kind from YAML,
+// returning a pointer to it, e.g. a *schema.SpellConfig
+func UnmarshalEntity(data []byte, kind string) (interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("No entity found")
+	}
+
+	entity, err := decodeEntity(doc.Content[0], kind)
+	if err != nil {
+		return nil, fmt.Errorf("line %s", err)
+	}
+	return entity, nil
+}
+
+func decodeEntity(node *yaml.Node, kind string) (interface{}, error) {
+	entityType := schema.KindType(kind)
+	if entityType == nil {
+		return nil, fmt.Errorf("%d: unknown kind %s", node.Line, kind)
+	}
+
+	value, err := fromNode(node, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("%d: %s", node.Line, err)
+	}
+	entity := reflect.New(entityType)
+	if err := json.Unmarshal(jsonBytes, entity.Interface()); err != nil {
+		return nil, fmt.Errorf("%d: %s", node.Line, err)
+	}
+	return entity.Interface(), nil
+}
+
+// fromNode converts a YAML node into the JSON value for a field of type t,
+// expanding short forms. t is nil when the type isn't known.
+func fromNode(node *yaml.Node, t reflect.Type) (interface{}, error) {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.DocumentNode {
+		return fromNode(node.Content[0], t)
+	}
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil, nil
+	}
+
+	if t == levelModifierListType {
+		return modifiersFromNode(node)
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		m := make(map[string]interface{})
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			keyNode, valueNode := node.Content[idx], node.Content[idx+1]
+			key := keyNode.Value
+
+			var fieldType reflect.Type
+			switch {
+			case t == nil || t.Kind() == reflect.Interface:
+			case t.Kind() == reflect.Struct:
+				field, ok := fieldByJSONName(t, key)
+				if !ok {
+					return nil, fmt.Errorf("%d: unknown field %s", keyNode.Line, key)
+				}
+				fieldType = field.Type
+			case t.Kind() == reflect.Map:
+				fieldType = t.Elem()
+			default:
+				return nil, fmt.Errorf("%d: expected %s, got a mapping", node.Line, describeType(t))
+			}
+
+			value, err := fromNode(valueNode, fieldType)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+
+	case yaml.SequenceNode:
+		// A list of the members of a set
+		if t != nil && t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Bool {
+			m := make(map[string]interface{})
+			for _, item := range node.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("%d: expected the members of a set", item.Line)
+				}
+				m[item.Value] = true
+			}
+			return m, nil
+		}
+
+		var elemType reflect.Type
+		if t != nil && t.Kind() != reflect.Interface {
+			if t.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("%d: expected %s, got a list", node.Line, describeType(t))
+			}
+			elemType = t.Elem()
+		}
+
+		list := []interface{}{}
+		for _, item := range node.Content {
+			value, err := fromNode(item, elemType)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+
+	case yaml.ScalarNode:
+		if t != nil && t.Kind() == reflect.String {
+			return node.Value, nil
+		}
+
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, fmt.Errorf("%d: %s", node.Line, err)
+		}
+		if t != nil {
+			switch t.Kind() {
+			case reflect.Int, reflect.Int64, reflect.Float32, reflect.Float64:
+				switch value.(type) {
+				case int, float64:
+				default:
+					return nil, fmt.Errorf("%d: expected a number, got %q", node.Line, node.Value)
+				}
+			case reflect.Bool:
+				if _, ok := value.(bool); !ok {
+					return nil, fmt.Errorf("%d: expected true or false, got %q", node.Line, node.Value)
+				}
+			case reflect.Struct, reflect.Map, reflect.Slice:
+				return nil, fmt.Errorf("%d: expected %s, got %q", node.Line, describeType(t), node.Value)
+			}
+		}
+		return value, nil
+	}
+
+	return nil, fmt.Errorf("%d: unexpected YAML", node.Line)
+}
+
+// modifiersFromNode converts a list of level modifiers, which may use the
+// short form "type: value @level"
+func modifiersFromNode(node *yaml.Node) (interface{}, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%d: expected a list of level modifiers", node.Line)
+	}
+
+	list := []interface{}{}
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%d: expected a level modifier such as skill-prof: athletics", item.Line)
+		}
+
+		fields := make(map[string]*yaml.Node)
+		var modifierType string
+		for idx := 0; idx+1 < len(item.Content); idx += 2 {
+			key := item.Content[idx].Value
+			fields[key] = item.Content[idx+1]
+			if key != "level" && key != "type" && key != "value" {
+				if modifierType != "" {
+					return nil, fmt.Errorf("%d: a level modifier can only have one type", item.Line)
+				}
+				modifierType = key
+			}
+		}
+
+		var valueNode *yaml.Node
+		if modifierType != "" {
+			if fields["type"] != nil || fields["value"] != nil {
+				return nil, fmt.Errorf("%d: unknown field %s", item.Line, modifierType)
+			}
+			valueNode = fields[modifierType]
+		} else if fields["type"] != nil {
+			modifierType, valueNode = fields["type"].Value, fields["value"]
+		}
+
+		modifier, err := schema.NewLevelModifier(modifierType)
+		if err != nil {
+			return nil, fmt.Errorf("%d: unknown level modifier %q", item.Line, modifierType)
+		}
+		valueType := reflect.TypeOf(modifier).Elem().Field(1).Type
+
+		m := map[string]interface{}{"type": modifierType}
+		if levelNode := fields["level"]; levelNode != nil {
+			level, err := fromNode(levelNode, reflect.TypeOf(0))
+			if err != nil {
+				return nil, err
+			}
+			m["level"] = level
+		}
+
+		if valueNode != nil {
+			// The short form of a scalar value can end with @level
+			if valueNode.Kind == yaml.ScalarNode && fields["type"] == nil {
+				text := valueNode.Value
+				if idx := strings.LastIndex(text, " @"); idx >= 0 {
+					level, err := strconv.Atoi(strings.TrimSpace(text[idx+2:]))
+					if err != nil {
+						return nil, fmt.Errorf("%d: invalid level in %q", valueNode.Line, text)
+					}
+					m["level"] = level
+					text = strings.TrimSpace(text[:idx])
+				}
+				valueNode = &yaml.Node{Kind: yaml.ScalarNode, Value: text, Line: valueNode.Line}
+				if valueType.Kind() == reflect.String {
+					valueNode.Tag = "!!str"
+				}
+			}
+
+			value, err := fromNode(valueNode, valueType)
+			if err != nil {
+				return nil, err
+			}
+			m["value"] = value
+		}
+		list = append(list, m)
+	}
+	return list, nil
+}
+
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if jsonName(t.Field(i)) == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func jsonName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct:
+		return "a mapping"
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Bool {
+			return "a list"
+		}
+		return "a mapping"
+	case reflect.Slice:
+		return "a list"
+	case reflect.String:
+		return "text"
+	case reflect.Bool:
+		return "true or false"
+	}
+	return "a number"
+}
+
+// Unbuild writes the option packs as a source directory, with a directory for
+// each option pack. Files for entities that no longer exist aren't removed.
+func Unbuild(all schema.OrcbrewExportAll, dir string, layout Layout) error {
+	var packs []string
+	for pack := range all {
+		packs = append(packs, pack)
+	}
+	sort.Strings(packs)
+
+	used := make(map[string]bool)
+	for _, pack := range packs {
+		packDir := orcbrew.SafeFilename(pack)
+		for n := 2; used[strings.ToLower(packDir)]; n++ {
+			packDir = fmt.Sprintf("%s-%d", orcbrew.SafeFilename(pack), n)
+		}
+		used[strings.ToLower(packDir)] = true
+
+		if err := writePack(all[pack], pack, filepath.Join(dir, packDir), layout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePack(source schema.OrcbrewSource, pack string, dir string, layout Layout) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	settings, err := yaml.Marshal(packSettings{Name: pack})
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, PackFile), settings, 0644); err != nil {
+		return err
+	}
+
+	sourceValue := reflect.ValueOf(source)
+	for i := 0; i < sourceValue.NumField(); i++ {
+		kind := jsonName(sourceValue.Type().Field(i))
+		entities := sourceValue.Field(i)
+		if entities.Len() == 0 {
+			continue
+		}
+
+		var keys []string
+		for _, key := range entities.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+
+		if layout == PerKind {
+			list := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, key := range keys {
+				node, err := entityNode(entities.MapIndex(reflect.ValueOf(key)).Interface(), pack)
+				if err != nil {
+					return err
+				}
+				list.Content = append(list.Content, node)
+			}
+			if err := writeNode(filepath.Join(dir, kind+".yaml"), list); err != nil {
+				return err
+			}
+			continue
+		}
+
+		kindDir := filepath.Join(dir, kind)
+		if err := os.MkdirAll(kindDir, 0755); err != nil {
+			return err
+		}
+		used := make(map[string]bool)
+		for _, key := range keys {
+			filename := orcbrew.SafeFilename(key)
+			for n := 2; used[strings.ToLower(filename)]; n++ {
+				filename = fmt.Sprintf("%s-%d", orcbrew.SafeFilename(key), n)
+			}
+			used[strings.ToLower(filename)] = true
+
+			node, err := entityNode(entities.MapIndex(reflect.ValueOf(key)).Interface(), pack)
+			if err != nil {
+				return err
+			}
+			if err := writeNode(filepath.Join(kindDir, filename+".yaml"), node); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeNode(filename string, node *yaml.Node) error {
+	data, err := encodeNode(node)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+func encodeNode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalEntity encodes an entity as YAML, using the short forms
+func MarshalEntity(entity interface{}) ([]byte, error) {
+	node, err := entityNode(entity, "")
+	if err != nil {
+		return nil, err
+	}
+	return encodeNode(node)
+}
+
+// entityNode returns the YAML for an entity, leaving out its option pack
+// when it's the pack it's written to
+func entityNode(entity interface{}, pack string) (*yaml.Node, error) {
+	jsonBytes, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(jsonBytes, &value); err != nil {
+		return nil, err
+	}
+
+	if value["option-pack"] == pack {
+		delete(value, "option-pack")
+	}
+	return toNode(value, reflect.Indirect(reflect.ValueOf(entity)).Type()), nil
+}
+
+// toNode converts a JSON value for a field of type t into YAML, using the
+// short forms. t is nil when the type isn't known. Fields that are null,
+// false, zero or empty text are left out.
+func toNode(value interface{}, t reflect.Type) *yaml.Node {
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Interface {
+		t = nil
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		if t != nil && t.Kind() == reflect.Struct {
+			for i := 0; i < t.NumField(); i++ {
+				name := jsonName(t.Field(i))
+				if fieldValue, ok := v[name]; ok && !isOmitted(fieldValue) {
+					node.Content = append(node.Content, stringNode(name), toNode(fieldValue, t.Field(i).Type))
+				}
+			}
+		} else if t != nil && t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Bool && allTrue(v) {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, key := range sortedKeys(v) {
+				node.Content = append(node.Content, stringNode(key))
+			}
+		} else {
+			var elemType reflect.Type
+			if t != nil && t.Kind() == reflect.Map {
+				elemType = t.Elem()
+			}
+			for _, key := range sortedKeys(v) {
+				keyNode := stringNode(key)
+				if t != nil && t.Kind() == reflect.Map && t.Key().Kind() == reflect.Int {
+					keyNode.Tag = "!!int"
+				}
+				node.Content = append(node.Content, keyNode, toNode(v[key], elemType))
+			}
+		}
+		if len(node.Content) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		return node
+
+	case []interface{}:
+		if t == levelModifierListType {
+			return modifiersNode(v)
+		}
+
+		var elemType reflect.Type
+		if t != nil && t.Kind() == reflect.Slice {
+			elemType = t.Elem()
+		}
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		flow := true
+		for _, item := range v {
+			child := toNode(item, elemType)
+			if child.Kind != yaml.ScalarNode || strings.Contains(child.Value, "\n") {
+				flow = false
+			}
+			node.Content = append(node.Content, child)
+		}
+		if flow {
+			node.Style = yaml.FlowStyle
+		}
+		return node
+
+	case string:
+		node := stringNode(v)
+		if strings.Contains(v, "\n") {
+			node.Style = yaml.LiteralStyle
+		}
+		return node
+
+	case float64:
+		if v == float64(int64(v)) {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(int64(v), 10)}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(v, 'f', -1, 64)}
+
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	}
+
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+// modifiersNode writes level modifiers in the short form
+func modifiersNode(modifiers []interface{}) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, item := range modifiers {
+		m, _ := item.(map[string]interface{})
+		modifierType, _ := m["type"].(string)
+		level, _ := m["level"].(float64)
+
+		var valueType reflect.Type
+		if modifier, err := schema.NewLevelModifier(modifierType); err == nil {
+			valueType = reflect.TypeOf(modifier).Elem().Field(1).Type
+		}
+
+		modifierNode := &yaml.Node{Kind: yaml.MappingNode}
+		value := toNode(m["value"], valueType)
+		// A value containing " @" would be read as having a level, so it
+		// has to be written in the long form
+		long := value.Kind == yaml.ScalarNode && strings.Contains(value.Value, " @")
+		if value.Kind == yaml.ScalarNode && level != 0 && !long {
+			value = stringNode(fmt.Sprintf("%s @%d", value.Value, int(level)))
+		}
+		if long {
+			modifierNode.Content = append(modifierNode.Content, stringNode("type"), stringNode(modifierType), stringNode("value"), value)
+		} else {
+			modifierNode.Content = append(modifierNode.Content, stringNode(modifierType), value)
+		}
+		if (value.Kind != yaml.ScalarNode || long) && level != 0 {
+			modifierNode.Content = append(modifierNode.Content, stringNode("level"), toNode(level, nil))
+		}
+		if value.Kind == yaml.MappingNode {
+			value.Style = yaml.FlowStyle
+		}
+
+		node.Content = append(node.Content, modifierNode)
+	}
+	if len(node.Content) == 0 {
+		node.Style = yaml.FlowStyle
+	}
+	return node
+}
+
+func stringNode(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+func isOmitted(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	}
+	return false
+}
+
+func allTrue(m map[string]interface{}) bool {
+	for _, value := range m {
+		if value != true {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKeys returns the keys of a map, sorting numeric keys by value
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
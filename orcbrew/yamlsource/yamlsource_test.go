@@ -0,0 +1,202 @@
+package yamlsource
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew"
+	"github.com/jnwhiteh/orcbrew-utils/orcbrew/schema"
+)
+
+const exampleFile = "../schema/example.orcbrew"
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "yamlsource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	f, err := orcbrew.ReadFile(exampleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Values containing " @" can't be written in the short form
+	source := f.Packs["Test"]
+	class := source.Classes["myclass"]
+	class.LevelModifiers = append(class.LevelModifiers[:len(class.LevelModifiers):len(class.LevelModifiers)],
+		&schema.ModifierToolProficiency{Value: "at @ night"},
+		&schema.ModifierWeaponProficiency{Value: "dusk @ dawn", Level: 2})
+	source.Classes["myclass"] = class
+	f.Packs["Test"] = source
+
+	for _, layout := range []Layout{PerEntity, PerKind} {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		if err := Unbuild(f.Packs, dir, layout); err != nil {
+			t.Fatal(err)
+		}
+		all, err := Build(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := deep.Equal(all, f.Packs); diff != nil {
+			t.Errorf("Layout %d: %v", layout, diff)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"pack.yaml": "name: My Pack\n",
+		"spells/fireball.yaml": `# A classic
+name: Fireball
+level: 3
+school: evocation
+spell-lists: [sorcerer, wizard]
+description: |
+  A bright streak flashes.
+
+  Each creature takes 8d6 fire damage.
+`,
+		"races.yaml": `
+- key: dwarf
+  name: Dwarf
+  speed: 25
+  abilities: {con: 2}
+`,
+		"subclasses.yaml": `
+- key: forge
+  name: Forge Domain
+  class: cleric
+  level-modifiers:
+    - tool-prof: smiths-tools
+    - flying-speed: 30 @5
+    - spell: {key: light, ability: wis}
+      level: 3
+`,
+	})
+
+	all, err := Build(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, ok := all["My Pack"]
+	if !ok {
+		t.Fatalf("Expected My Pack, got %v", all)
+	}
+
+	spell := source.Spells["fireball"]
+	if spell.Key != "fireball" || spell.OptionPack != "My Pack" || spell.Level != 3 {
+		t.Errorf("Unexpected spell %+v", spell)
+	}
+	if spell.Description != "A bright streak flashes.\n\nEach creature takes 8d6 fire damage.\n" {
+		t.Errorf("Unexpected description %q", spell.Description)
+	}
+	if diff := deep.Equal(spell.SpellLists, map[string]bool{"sorcerer": true, "wizard": true}); diff != nil {
+		t.Error(diff)
+	}
+
+	subclass := source.Subclasses["forge"]
+	expected := schema.LevelModifierList{
+		&schema.ModifierToolProficiency{Value: "smiths-tools"},
+		&schema.ModifierFlyingSpeed{Level: 5, Value: 30},
+		&schema.ModifierSpell{Level: 3, Value: schema.SpellWithAbility{Key: "light", Ability: schema.Wisdom}},
+	}
+	if diff := deep.Equal(subclass.LevelModifiers, expected); diff != nil {
+		t.Error(diff)
+	}
+	if race := source.Races["dwarf"]; race.Speed != 25 || race.Abilities[schema.Constitution] != 2 {
+		t.Errorf("Unexpected race %+v", race)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		files    map[string]string
+		expected string
+	}{
+		{map[string]string{"spells/a.yaml": "name: A\nlevle: 1\n"}, "a.yaml:2: unknown field levle"},
+		{map[string]string{"spells/a.yaml": "level: one\n"}, "a.yaml:1: expected a number"},
+		{map[string]string{"spells.yaml": "name: A\n"}, "expected a list of spells"},
+		{map[string]string{"spells.yaml": "- name: A\n"}, "has no key"},
+		{map[string]string{"pack.yaml": "name: P\n", "widgets.yaml": "[]\n"}, "widgets isn't a kind"},
+		{map[string]string{"spells/a.yaml": "name: A\n", "spells.yaml": "- key: a\n"}, "spells/a is defined more than once"},
+		{map[string]string{"subclasses.yaml": "- key: a\n  level-modifiers: [{skil-prof: arcana}]\n"}, "unknown level modifier"},
+		{map[string]string{"subclasses.yaml": "- key: a\n  level-modifiers: [{skill-prof: arcana @x}]\n"}, "invalid level"},
+	}
+
+	for _, test := range tests {
+		dir := tempDir(t)
+		_, err := Build(dir)
+		if err == nil {
+			t.Errorf("Expected an error for an empty directory")
+		}
+
+		writeFiles(t, dir, test.files)
+		_, err = Build(dir)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%v: expected an error containing %q, got %v", test.files, test.expected, err)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestMarshalEntity(t *testing.T) {
+	subclass := &schema.SubclassConfig{Key: "forge", Name: "Forge Domain", OptionPack: "Test", Class: "cleric",
+		Traits: []schema.LevelTrait{{Name: "Blessing", Level: 1, Description: "Short.\nAnd sweet."}},
+		Profs:  &schema.ClassProficiencies{Save: map[schema.Ability]bool{schema.Wisdom: true, schema.Charisma: true}},
+		LevelModifiers: schema.LevelModifierList{
+			&schema.ModifierSkillProficiency{Value: "athletics", Level: 3},
+			&schema.ModifierSpell{Level: 3, Value: schema.SpellWithAbility{Key: "light", Ability: schema.Wisdom}},
+		},
+	}
+
+	data, err := MarshalEntity(subclass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"save: [cha, wis]\n",
+		"description: |-\n      Short.\n      And sweet.\n",
+		"  - skill-prof: athletics @3\n",
+		"  - spell: {ability: wis, key: light}\n    level: 3\n",
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, data)
+		}
+	}
+
+	entity, err := UnmarshalEntity(data, "subclasses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(entity, subclass); diff != nil {
+		t.Errorf("%v\n%s", diff, data)
+	}
+}